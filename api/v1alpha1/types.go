@@ -23,6 +23,7 @@ import (
 
 // +genclient
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:subresource:status
 
 // GatewayRouter is a specification for a GatewayRouter resource.
 type GatewayRouter struct {
@@ -68,6 +69,37 @@ type GatewayRouterSpec struct {
 	// If the Protocol is bgp, this property must be empty.
 	// +optional
 	Static StaticSpec `json:"static,omitempty"`
+
+	// Parameters to set up the OSPF adjacency with the Gateway Router over the Interface.
+	// If the Protocol is not OSPF, this property must be empty.
+	// +optional
+	Ospf OspfSpec `json:"ospf,omitempty"`
+
+	// Routes imported from the Gateway Router.
+	// When left empty, the default routes and all the routes received over BGP are imported.
+	// With OSPF, only the default routes are imported when left empty.
+	// +optional
+	Import *ImportPolicy `json:"import,omitempty"`
+}
+
+// ImportPolicy defines the routes imported from a Gateway Router.
+type ImportPolicy struct {
+	// Prefixes (CIDRs) imported from the Gateway Router. A prefix matches the CIDR
+	// and all the more specific prefixes within it (e.g. 10.0.0.0/8 matches 10.1.0.0/16).
+	// The routes not matching any prefix are rejected.
+	// +optional
+	Prefixes []string `json:"prefixes,omitempty"`
+
+	// Import the default routes (0.0.0.0/0 and ::/0) from the Gateway Router.
+	// Default is true.
+	// +optional
+	DefaultRoute *bool `json:"defaultRoute,omitempty"`
+
+	// Maximum number of prefixes imported from the Gateway Router.
+	// The prefixes received above the limit are rejected.
+	// No limit is set when left empty.
+	// +optional
+	MaxPrefixes *uint32 `json:"maxPrefixes,omitempty"`
 }
 
 // RoutingProtocol represents the routing protocol used in a gateway router.
@@ -79,6 +111,8 @@ const (
 	BGP RoutingProtocol = "BGP"
 	// Static Routing.
 	Static RoutingProtocol = "Static"
+	// OSPF, Open Shortest Path First (OSPFv2 for IPv4 and OSPFv3 for IPv6).
+	OSPF RoutingProtocol = "OSPF"
 )
 
 // BgpSpec defines the parameters to set up a BGP session.
@@ -98,7 +132,9 @@ type BgpSpec struct {
 	// Hold timer of the BGP session. Please refere to BGP material to understand what this implies.
 	// The value must be a valid duration format. For example, 90s, 1m, 1h.
 	// The duration will be rounded by second
-	// Minimum duration is 3s.
+	// Minimum duration is 3s, except 0s which turns off the hold timer and the keepalives
+	// (the session is then never considered down by the hold timer).
+	// +kubebuilder:validation:XValidation:rule="self == '0s' || duration(self) >= duration('3s')",message="0s or >= 3s"
 	// +optional
 	HoldTime string `json:"holdTime,omitempty"`
 
@@ -113,8 +149,115 @@ type BgpSpec struct {
 	// BGP authentication (RFC2385).
 	// +optional
 	Auth *BgpAuth `json:"auth,omitempty"`
+
+	// Maximum number of hops (TTL) to reach the Gateway Router (multihop eBGP).
+	// When left empty, the Gateway Router must be directly connected via the Interface.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=255
+	Multihop *uint8 `json:"multihop,omitempty"`
+
+	// Local address used as source of the BGP session (e.g. a loopback address).
+	// The address is added to the loopback interface of the router if not already existing.
+	// When left empty, the address of the Interface is used.
+	// +optional
+	SourceAddress string `json:"sourceAddress,omitempty"`
+
+	// Next hop announced with the VIPs to the Gateway Router.
+	// When left empty, the address of the router is announced (next hop self).
+	// +optional
+	NextHop string `json:"nextHop,omitempty"`
+
+	// Graceful restart of the BGP session.
+	// When left empty, graceful restart is turned off.
+	// +optional
+	GracefulRestart *BgpGracefulRestart `json:"gracefulRestart,omitempty"`
+}
+
+// BgpGracefulRestart defines the graceful restart parameters of a BGP session.
+type BgpGracefulRestart struct {
+	// Graceful restart (RFC4724). The Gateway Router keeps forwarding to the VIPs while
+	// the BGP session is re-established after a restart of the router.
+	// Valid values are:
+	// - false: graceful restart is turned off;
+	// - true: graceful restart is turned on.
+	// When left empty, graceful restart is turned off.
+	// +optional
+	Switch *bool `json:"switch,omitempty"`
+
+	// Restart time advertised to the Gateway Router. Please refere to BGP material to understand what this implies.
+	// The value must be a valid duration format. For example, 90s, 1m, 1h.
+	// The duration will be rounded by second.
+	// Maximum duration is 4095s. When left empty, the bird default (120s) is used.
+	// +optional
+	RestartTime string `json:"restartTime,omitempty"`
+
+	// Long-lived graceful restart (RFC9494) stale time. The routes are kept as stale by the Gateway
+	// Router during this time once the restart time has expired.
+	// The value must be a valid duration format. For example, 90s, 1m, 1h.
+	// The duration will be rounded by second.
+	// When left empty, long-lived graceful restart is turned off.
+	// +optional
+	LongLivedStaleTime string `json:"longLivedStaleTime,omitempty"`
 }
 
+// OspfSpec defines the parameters to set up an OSPF adjacency.
+// Only one OSPF Gateway Router is supported per Interface.
+type OspfSpec struct {
+	// OSPF area of the Interface, as a number or in the dotted format (e.g. 0, 10 or 0.0.0.10).
+	// When left empty, the backbone area (0) is used.
+	// +optional
+	Area string `json:"area,omitempty"`
+
+	// Cost of the Interface.
+	// When left empty, the bird default (10) is used.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	Cost *uint16 `json:"cost,omitempty"`
+
+	// Interval between the hello packets sent over the Interface.
+	// The value must be a valid duration format. For example, 10s, 1m.
+	// The duration will be rounded by second.
+	// When left empty, the bird default (10s) is used.
+	// +optional
+	HelloInterval string `json:"helloInterval,omitempty"`
+
+	// Time after which the Gateway Router is considered down if no hello packet has been received.
+	// The value must be a valid duration format. For example, 40s, 1m.
+	// The duration will be rounded by second.
+	// When left empty, the bird default (4 times the hello interval) is used.
+	// +optional
+	DeadInterval string `json:"deadInterval,omitempty"`
+
+	// OSPF cryptographic authentication. The key is referenced the same way as the BGP authentication.
+	// +optional
+	Auth *BgpAuth `json:"auth,omitempty"`
+
+	// BFD monitoring of the OSPF adjacency.
+	// +optional
+	BFD BfdSpec `json:"bfd,omitempty"`
+
+	// How the VIPs are advertised to the Gateway Router.
+	// Valid values are:
+	// - Stub: the VIPs are advertised as stub networks of the area;
+	// - External: the VIPs are advertised as external routes (type 2).
+	// When left empty, the VIPs are advertised as external routes.
+	// +optional
+	// +kubebuilder:validation:Enum=Stub;External
+	VIPAdvertisement OspfVIPAdvertisement `json:"vipAdvertisement,omitempty"`
+}
+
+// OspfVIPAdvertisement represents how the VIPs are advertised over OSPF.
+// +enum
+type OspfVIPAdvertisement string
+
+const (
+	// OspfStub advertises the VIPs as stub networks.
+	OspfStub OspfVIPAdvertisement = "Stub"
+	// OspfExternal advertises the VIPs as external routes.
+	OspfExternal OspfVIPAdvertisement = "External"
+)
+
 // StaticSpec defines the parameters to set up static routes.
 type StaticSpec struct {
 	// BFD monitoring of Static session.
@@ -167,7 +310,29 @@ type BgpAuth struct {
 }
 
 // GatewayRouterStatus is the status for a GatewayRouter resource.
-type GatewayRouterStatus struct{}
+type GatewayRouterStatus struct {
+	// Status of the routers connected to the Gateway Router.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Routers []RouterStatus `json:"routers,omitempty"`
+}
+
+// RouterStatus is the status of a router connected to a Gateway Router.
+type RouterStatus struct {
+	// Name of the router (name of the pod running it).
+	Name string `json:"name"`
+
+	// Number of prefixes currently imported from the Gateway Router.
+	ImportedPrefixes int32 `json:"importedPrefixes"`
+
+	// Number of prefixes received from the Gateway Router and rejected by the
+	// import policy (prefixes not matching or above the maximum number of prefixes).
+	RejectedPrefixes int32 `json:"rejectedPrefixes"`
+
+	// Last time the status has been updated by the router.
+	LastUpdateTime metav1.Time `json:"lastUpdateTime"`
+}
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
@@ -203,6 +368,7 @@ type NetworkAttachementAnnotation struct {
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:subresource:status
 
 // L34Route is a specification for a L34Route resource.
 type L34Route struct {
@@ -262,6 +428,9 @@ type L34RouteSpec struct {
 
 	// Destination CIDRs that this L34Route will send traffic to.
 	// The destination CIDRs should not have overlaps.
+	// A CIDR which is not a single IP (e.g. 40.0.0.0/24) is announced as a whole
+	// prefix (e.g. an anycast pool) and is added as is to the Gateway status addresses
+	// (CIDRAddressType).
 	//nolint:tagliatelle
 	DestinationCIDRs []string `json:"destinationCIDRs"`
 
@@ -296,10 +465,82 @@ type L34RouteSpec struct {
 	// ByteMatches matches bytes in the L4 header in the L34Route.
 	// +optional
 	ByteMatches []string `json:"byteMatches,omitempty"`
+
+	// BGP attributes set on the destination CIDRs announced to the BGP peers.
+	// They complete the BGP attributes of the Gateway (BgpAttributesAnnotation):
+	// the communities are added and the other attributes are overridden.
+	// +optional
+	BgpAttributes *BgpAttributes `json:"bgpAttributes,omitempty"`
+
+	// Gateway Routers the destination CIDRs are exported to.
+	// It overrides the export of the Gateway (VIPExportAnnotation). A destination CIDR
+	// exported by several L34Routes is exported to the Gateway Routers of all of them.
+	// When left empty, the export of the Gateway is used.
+	// +optional
+	Export *VIPExport `json:"export,omitempty"`
+}
+
+// CIDRAddressType is the type of the Gateway status addresses containing a prefix
+// (e.g. 40.0.0.0/24) of the destination CIDRs of the L34Routes. The single IPs
+// (/32 and /128) use the IPAddress type.
+const CIDRAddressType gatewayapiv1.AddressType = "l34.gateway.api.poc/CIDR"
+
+// VIPExportAnnotation is the annotation of a Gateway containing, in JSON format,
+// the VIPExport of all its addresses.
+const VIPExportAnnotation = "l34.gateway.api.poc/vip-export"
+
+// VIPExport defines the Gateway Routers the VIPs are exported to. A Gateway Router
+// must match all the fields to receive the VIPs.
+type VIPExport struct {
+	// Interfaces (networks) the VIPs are exported over: only the Gateway Routers
+	// reached over one of these interfaces receive the VIPs.
+	// When left empty, the Gateway Routers of all the interfaces receive the VIPs.
+	// +optional
+	Interfaces []string `json:"interfaces,omitempty"`
+
+	// Label selector of the Gateway Routers receiving the VIPs.
+	// When left empty, the Gateway Routers are not filtered on their labels.
+	// +optional
+	GatewayRouterSelector *metav1.LabelSelector `json:"gatewayRouterSelector,omitempty"`
+}
+
+// BgpAttributesAnnotation is the annotation of a Gateway containing, in JSON format,
+// the BgpAttributes set on all its addresses announced to the BGP peers.
+const BgpAttributesAnnotation = "l34.gateway.api.poc/bgp-attributes"
+
+// BgpAttributes defines the BGP path attributes set on the VIPs announced to the BGP peers.
+type BgpAttributes struct {
+	// BGP communities (RFC1997) added to the VIPs.
+	// The format is <ASN>:<value> (e.g. 65000:100), each part being a 16 bits number.
+	// +optional
+	// +kubebuilder:validation:items:Pattern=`^[0-9]+:[0-9]+$`
+	Communities []string `json:"communities,omitempty"`
+
+	// BGP large communities (RFC8092) added to the VIPs.
+	// The format is <ASN>:<value>:<value> (e.g. 65000:1:2), each part being a 32 bits number.
+	// +optional
+	// +kubebuilder:validation:items:Pattern=`^[0-9]+:[0-9]+:[0-9]+$`
+	LargeCommunities []string `json:"largeCommunities,omitempty"`
+
+	// Multi Exit Discriminator of the VIPs.
+	// +optional
+	//nolint:tagliatelle
+	MED *uint32 `json:"med,omitempty"`
+
+	// Local preference of the VIPs (only sent to internal BGP peers).
+	// +optional
+	LocalPreference *uint32 `json:"localPreference,omitempty"`
+
+	// Number of times the local ASN is prepended to the AS path of the VIPs.
+	// +optional
+	// +kubebuilder:validation:Maximum=16
+	ASPathPrepend *uint32 `json:"asPathPrepend,omitempty"`
 }
 
 // L34RouteStatus is the status for a L34Route resource.
-type L34RouteStatus struct{}
+type L34RouteStatus struct {
+	gatewayapiv1.RouteStatus `json:",inline"`
+}
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
@@ -31,4 +31,10 @@ const (
 
 	// LabelKPNGInject indicates a pod must receive the KPNG and router containers.
 	LabelKPNGInject = "l-3-4-gateway-api-poc/kpng-inject"
+
+	// LabelGatewayName is the name of the gateway an EndpointSlice has been generated for.
+	LabelGatewayName = "l-3-4-gateway-api-poc/gateway-name"
+
+	// LabelGatewayNamespace is the namespace of the gateway an EndpointSlice has been generated for.
+	LabelGatewayNamespace = "l-3-4-gateway-api-poc/gateway-namespace"
 )
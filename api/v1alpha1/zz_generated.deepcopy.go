@@ -21,6 +21,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/gateway-api/apis/v1"
 )
@@ -50,6 +51,46 @@ func (in *BfdSpec) DeepCopy() *BfdSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BgpAttributes) DeepCopyInto(out *BgpAttributes) {
+	*out = *in
+	if in.Communities != nil {
+		in, out := &in.Communities, &out.Communities
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LargeCommunities != nil {
+		in, out := &in.LargeCommunities, &out.LargeCommunities
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MED != nil {
+		in, out := &in.MED, &out.MED
+		*out = new(uint32)
+		**out = **in
+	}
+	if in.LocalPreference != nil {
+		in, out := &in.LocalPreference, &out.LocalPreference
+		*out = new(uint32)
+		**out = **in
+	}
+	if in.ASPathPrepend != nil {
+		in, out := &in.ASPathPrepend, &out.ASPathPrepend
+		*out = new(uint32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BgpAttributes.
+func (in *BgpAttributes) DeepCopy() *BgpAttributes {
+	if in == nil {
+		return nil
+	}
+	out := new(BgpAttributes)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BgpAuth) DeepCopyInto(out *BgpAuth) {
 	*out = *in
@@ -65,6 +106,26 @@ func (in *BgpAuth) DeepCopy() *BgpAuth {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BgpGracefulRestart) DeepCopyInto(out *BgpGracefulRestart) {
+	*out = *in
+	if in.Switch != nil {
+		in, out := &in.Switch, &out.Switch
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BgpGracefulRestart.
+func (in *BgpGracefulRestart) DeepCopy() *BgpGracefulRestart {
+	if in == nil {
+		return nil
+	}
+	out := new(BgpGracefulRestart)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BgpSpec) DeepCopyInto(out *BgpSpec) {
 	*out = *in
@@ -94,6 +155,16 @@ func (in *BgpSpec) DeepCopyInto(out *BgpSpec) {
 		*out = new(BgpAuth)
 		**out = **in
 	}
+	if in.Multihop != nil {
+		in, out := &in.Multihop, &out.Multihop
+		*out = new(uint8)
+		**out = **in
+	}
+	if in.GracefulRestart != nil {
+		in, out := &in.GracefulRestart, &out.GracefulRestart
+		*out = new(BgpGracefulRestart)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BgpSpec.
@@ -112,7 +183,7 @@ func (in *GatewayRouter) DeepCopyInto(out *GatewayRouter) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GatewayRouter.
@@ -170,6 +241,12 @@ func (in *GatewayRouterSpec) DeepCopyInto(out *GatewayRouterSpec) {
 	*out = *in
 	in.Bgp.DeepCopyInto(&out.Bgp)
 	in.Static.DeepCopyInto(&out.Static)
+	in.Ospf.DeepCopyInto(&out.Ospf)
+	if in.Import != nil {
+		in, out := &in.Import, &out.Import
+		*out = new(ImportPolicy)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GatewayRouterSpec.
@@ -185,6 +262,13 @@ func (in *GatewayRouterSpec) DeepCopy() *GatewayRouterSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GatewayRouterStatus) DeepCopyInto(out *GatewayRouterStatus) {
 	*out = *in
+	if in.Routers != nil {
+		in, out := &in.Routers, &out.Routers
+		*out = make([]RouterStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GatewayRouterStatus.
@@ -197,13 +281,43 @@ func (in *GatewayRouterStatus) DeepCopy() *GatewayRouterStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImportPolicy) DeepCopyInto(out *ImportPolicy) {
+	*out = *in
+	if in.Prefixes != nil {
+		in, out := &in.Prefixes, &out.Prefixes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DefaultRoute != nil {
+		in, out := &in.DefaultRoute, &out.DefaultRoute
+		*out = new(bool)
+		**out = **in
+	}
+	if in.MaxPrefixes != nil {
+		in, out := &in.MaxPrefixes, &out.MaxPrefixes
+		*out = new(uint32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImportPolicy.
+func (in *ImportPolicy) DeepCopy() *ImportPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ImportPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *L34Route) DeepCopyInto(out *L34Route) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new L34Route.
@@ -297,6 +411,16 @@ func (in *L34RouteSpec) DeepCopyInto(out *L34RouteSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.BgpAttributes != nil {
+		in, out := &in.BgpAttributes, &out.BgpAttributes
+		*out = new(BgpAttributes)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Export != nil {
+		in, out := &in.Export, &out.Export
+		*out = new(VIPExport)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new L34RouteSpec.
@@ -312,6 +436,7 @@ func (in *L34RouteSpec) DeepCopy() *L34RouteSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *L34RouteStatus) DeepCopyInto(out *L34RouteStatus) {
 	*out = *in
+	in.RouteStatus.DeepCopyInto(&out.RouteStatus)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new L34RouteStatus.
@@ -359,6 +484,48 @@ func (in *NetworkAttachementAnnotation) DeepCopy() *NetworkAttachementAnnotation
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OspfSpec) DeepCopyInto(out *OspfSpec) {
+	*out = *in
+	if in.Cost != nil {
+		in, out := &in.Cost, &out.Cost
+		*out = new(uint16)
+		**out = **in
+	}
+	if in.Auth != nil {
+		in, out := &in.Auth, &out.Auth
+		*out = new(BgpAuth)
+		**out = **in
+	}
+	in.BFD.DeepCopyInto(&out.BFD)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OspfSpec.
+func (in *OspfSpec) DeepCopy() *OspfSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OspfSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RouterStatus) DeepCopyInto(out *RouterStatus) {
+	*out = *in
+	in.LastUpdateTime.DeepCopyInto(&out.LastUpdateTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RouterStatus.
+func (in *RouterStatus) DeepCopy() *RouterStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RouterStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StaticSpec) DeepCopyInto(out *StaticSpec) {
 	*out = *in
@@ -374,3 +541,28 @@ func (in *StaticSpec) DeepCopy() *StaticSpec {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VIPExport) DeepCopyInto(out *VIPExport) {
+	*out = *in
+	if in.Interfaces != nil {
+		in, out := &in.Interfaces, &out.Interfaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.GatewayRouterSelector != nil {
+		in, out := &in.GatewayRouterSelector, &out.GatewayRouterSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VIPExport.
+func (in *VIPExport) DeepCopy() *VIPExport {
+	if in == nil {
+		return nil
+	}
+	out := new(VIPExport)
+	in.DeepCopyInto(out)
+	return out
+}
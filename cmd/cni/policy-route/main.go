@@ -18,9 +18,11 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"runtime"
+	"slices"
 
 	"github.com/containernetworking/cni/pkg/skel"
 	"github.com/containernetworking/cni/pkg/types"
@@ -29,9 +31,12 @@ import (
 	"github.com/containernetworking/plugins/pkg/ns"
 	bv "github.com/containernetworking/plugins/pkg/utils/buildversion"
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/cni"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/networking"
 	"github.com/vishvananda/netlink"
 )
 
+var errTableInUse = errors.New("the table is used by another component")
+
 // NetConf represents the cni config of the policy-route plugin.
 type NetConf struct {
 	types.NetConf
@@ -186,7 +191,12 @@ func configureNetNs(
 	nexthopsV4 []*netlink.NexthopInfo,
 	nexthopsV6 []*netlink.NexthopInfo,
 ) error {
-	err := flushRoutingPolicies(tableID)
+	err := checkRoutingConflicts(tableID, policyRoutes)
+	if err != nil {
+		return err
+	}
+
+	err = flushRoutingPolicies(tableID)
 	if err != nil {
 		return err
 	}
@@ -250,6 +260,33 @@ func configureNetNs(
 	return nil
 }
 
+// checkRoutingConflicts returns an error if the table is used by rules or routes of another
+// component, they would be flushed otherwise. The default routes and the rules of the policy
+// routes are ignored since they might remain from a previous ADD.
+func checkRoutingConflicts(tableID int, policyRoutes []cni.PolicyRoute) error {
+	conflicts, err := networking.RoutingConflicts(
+		[]networking.TableRange{{First: tableID, Last: tableID}},
+		nil,
+		func(rule *netlink.Rule) bool {
+			return rule.Src != nil && slices.ContainsFunc(policyRoutes, func(policyRoute cni.PolicyRoute) bool {
+				return policyRoute.SrcPrefix == rule.Src.String()
+			})
+		},
+		func(route *netlink.Route) bool {
+			return route.Dst == nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to check the table %d: %w", tableID, err)
+	}
+
+	if len(conflicts) > 0 {
+		return fmt.Errorf("%w: table %d: %v", errTableInUse, tableID, conflicts)
+	}
+
+	return nil
+}
+
 func getNextHops(gateways []string) ([]*netlink.NexthopInfo, []*netlink.NexthopInfo) {
 	nexthopsV4 := []*netlink.NexthopInfo{}
 	nexthopsV6 := []*netlink.NexthopInfo{}
@@ -41,6 +41,8 @@ import (
 
 type runOptions struct {
 	cli.CommonOptions
+	cli.LeaderElectionOptions
+	cli.CacheOptions
 	gatewayClassName string
 	registry         string
 	version          string
@@ -78,6 +80,8 @@ func newCmdRun() *cobra.Command {
 		"version of the image to use.",
 	)
 
+	runOpts.SetLeaderElectionFlags(cmd, "istio-controller-manager.l34.gateway.api.poc")
+	runOpts.SetCacheFlags(cmd)
 	runOpts.SetCommonFlags(cmd)
 
 	return cmd
@@ -95,10 +99,9 @@ func (ro *runOptions) run(ctx context.Context) {
 
 	crlog.SetLogger(logger)
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme:         scheme,
-		LeaderElection: false,
-		Cache:          cache.Options{},
+	options := ctrl.Options{
+		Scheme: scheme,
+		Cache:  cache.Options{},
 		Metrics: server.Options{
 			BindAddress: "0",
 		},
@@ -108,7 +111,12 @@ func (ro *runOptions) run(ctx context.Context) {
 				Port: 9443,
 			},
 		},
-	})
+	}
+
+	ro.SetManagerOptions(&options)
+	ro.SetManagerCacheOptions(&options)
+
+	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), options)
 	if err != nil {
 		log.Fatal(setupLog, "failed to create manager for controllers", "err", err)
 	}
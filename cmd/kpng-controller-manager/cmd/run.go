@@ -21,6 +21,7 @@ import (
 
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/api/v1alpha1"
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/cli"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/controllers/gatewayclass"
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/controllers/kpng"
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/log"
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/networkattachment"
@@ -38,6 +39,8 @@ import (
 
 type runOptions struct {
 	cli.CommonOptions
+	cli.LeaderElectionOptions
+	cli.CacheOptions
 	gatewayClassName string
 }
 
@@ -60,6 +63,8 @@ func newCmdRun() *cobra.Command {
 		"Name of the Gateway Class handled by this controller manager.",
 	)
 
+	runOpts.SetLeaderElectionFlags(cmd, "kpng-controller-manager.l34.gateway.api.poc")
+	runOpts.SetCacheFlags(cmd)
 	runOpts.SetCommonFlags(cmd)
 
 	return cmd
@@ -77,15 +82,19 @@ func (ro *runOptions) run(ctx context.Context) {
 
 	crlog.SetLogger(logger)
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme:         scheme,
-		LeaderElection: false,
-		Cache:          cache.Options{},
+	options := ctrl.Options{
+		Scheme: scheme,
+		Cache:  cache.Options{},
 		Metrics: server.Options{
 			BindAddress: "0",
 		},
 		HealthProbeBindAddress: ":8081",
-	})
+	}
+
+	ro.SetManagerOptions(&options)
+	ro.SetManagerCacheOptions(&options)
+
+	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), options)
 	if err != nil {
 		log.Fatal(setupLog, "failed to create manager for controllers", "err", err)
 	}
@@ -101,6 +110,14 @@ func (ro *runOptions) run(ctx context.Context) {
 		log.Fatal(setupLog, "failed to create controller", "err", err, "controller", "Gateway")
 	}
 
+	if err = (&gatewayclass.Controller{
+		Client:            mgr.GetClient(),
+		ControllerName:    ro.gatewayClassName,
+		SupportedFeatures: kpng.SupportedFeatures,
+	}).SetupWithManager(mgr); err != nil {
+		log.Fatal(setupLog, "failed to create controller", "err", err, "controller", "GatewayClass")
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		log.Fatal(setupLog, "unable to set up health check", "err", err)
 	}
@@ -18,13 +18,21 @@ package cmd
 
 import (
 	"context"
+	"net/http"
+	"os"
+	"time"
 
+	"github.com/go-logr/logr"
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/api/v1alpha1"
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/bird"
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/cli"
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/controllers/router"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/frr"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/gobgp"
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/log"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/shutdown"
 	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
@@ -34,16 +42,40 @@ import (
 	crlog "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	gatewayapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayapiv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
 )
 
 type runOptions struct {
 	cli.CommonOptions
-	name      string
-	namespace string
+	cli.CacheOptions
+	name             string
+	namespace        string
+	readinessFile    string
+	gracefulRestart  bool
+	routingSuite     string
+	drainPeriod      time.Duration
+	gracefulShutdown bool
+	shutdownSocket   string
+	tables           bird.RoutingTables
+}
+
+// routingSuite is a routing suite run by the router.
+type routingSuite interface {
+	router.RoutingSuite
+	router.ImportStatisticsGetter
+	Run(ctx context.Context) error
+}
+
+// healthChecker is implemented by the routing suites reporting their own health.
+type healthChecker interface {
+	Healthz(req *http.Request) error
+	Readyz(req *http.Request) error
 }
 
 func newCmdRun() *cobra.Command {
-	runOpts := &runOptions{}
+	runOpts := &runOptions{
+		tables: bird.DefaultRoutingTables(),
+	}
 
 	cmd := &cobra.Command{
 		Use:   "run",
@@ -68,64 +100,298 @@ func newCmdRun() *cobra.Command {
 		"namespace of the gateway in which the router is running.",
 	)
 
+	cmd.Flags().StringVar(
+		&runOpts.readinessFile,
+		"readiness-file",
+		"",
+		"file shared with the stateless-load-balancer listing the VIPs ready to be announced (all if empty).",
+	)
+
+	cmd.Flags().BoolVar(
+		&runOpts.gracefulRestart,
+		"graceful-restart",
+		false,
+		"keep the routes in the kernel when bird restarts and recover them once the BGP sessions are re-established (bird only).",
+	)
+
+	cmd.Flags().StringVar(
+		&runOpts.routingSuite,
+		"routing-suite",
+		"bird",
+		"routing suite announcing the VIPs to the gateway routers: bird (external bird binary), gobgp (embedded, "+
+			"the gateways with BFD are rejected) or frr (external FRR daemons).",
+	)
+
+	cmd.Flags().DurationVar(
+		&runOpts.drainPeriod,
+		"drain-period",
+		0,
+		"time waited after the VIPs have been withdrawn when the router is stopped, before the stateless-load-balancer removes its data plane.",
+	)
+
+	cmd.Flags().BoolVar(
+		&runOpts.gracefulShutdown,
+		"graceful-shutdown",
+		false,
+		"announce the VIPs with the graceful shutdown community (65535:0) during the drain period instead of withdrawing them.",
+	)
+
+	cmd.Flags().StringVar(
+		&runOpts.shutdownSocket,
+		"shutdown-socket",
+		"",
+		"socket shared with the stateless-load-balancer on which it is notified once the traffic has been drained (disabled if empty).",
+	)
+
+	runOpts.setTableFlags(cmd)
+
 	runOpts.SetCommonFlags(cmd)
+	runOpts.SetCacheFlags(cmd)
 
 	return cmd
 }
 
+func (ro *runOptions) setTableFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVar(
+		&ro.tables.TableID,
+		"table-id",
+		ro.tables.TableID,
+		"kernel table the routes received from the gateway routers are written to.",
+	)
+
+	cmd.Flags().IntVar(
+		&ro.tables.BlackholeTableID,
+		"blackhole-table-id",
+		ro.tables.BlackholeTableID,
+		"kernel table of the blackhole routes dropping the traffic of the VIPs when no gateway router is available (bird only).",
+	)
+
+	cmd.Flags().IntVar(
+		&ro.tables.RulePriority,
+		"rule-priority",
+		ro.tables.RulePriority,
+		"priority of the policy rules routing the traffic of the VIPs via the table-id kernel table.",
+	)
+
+	cmd.Flags().IntVar(
+		&ro.tables.NetworkTableID,
+		"network-table-id",
+		ro.tables.NetworkTableID,
+		"first kernel table of the routing instances, one per network of the gateway routers (bird only).",
+	)
+
+	cmd.Flags().IntVar(
+		&ro.tables.NetworkRulePriority,
+		"network-rule-priority",
+		ro.tables.NetworkRulePriority,
+		"priority of the policy rules of the routing instances (lower than rule-priority, bird only).",
+	)
+}
+
+// newRoutingSuite returns the routing suite selected by the routing-suite flag.
+func (ro *runOptions) newRoutingSuite(setupLog logr.Logger) routingSuite {
+	switch ro.routingSuite {
+	case "bird":
+		birdInstance := bird.New()
+		birdInstance.GracefulRestart = ro.gracefulRestart
+		birdInstance.Tables = ro.tables
+
+		return birdInstance
+	case "gobgp":
+		gobgpInstance := gobgp.New()
+		gobgpInstance.TableID = ro.tables.TableID
+		gobgpInstance.RulePriority = ro.tables.RulePriority
+
+		return gobgpInstance
+	case "frr":
+		frrInstance := frr.New()
+		frrInstance.TableID = ro.tables.TableID
+		frrInstance.RulePriority = ro.tables.RulePriority
+
+		return frrInstance
+	default:
+		log.Fatal(setupLog, "unknown routing suite", "routing-suite", ro.routingSuite)
+	}
+
+	return nil
+}
+
 func (ro *runOptions) run(ctx context.Context) {
+	// The routing suite and the controllers keep running while the traffic is drained,
+	// they are stopped once the drain is over.
+	runCtx, stop := context.WithCancel(context.WithoutCancel(ctx))
+	defer stop()
+
 	scheme := runtime.NewScheme()
 	setupLog := ctrl.Log.WithName("setup")
 
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 	utilruntime.Must(v1alpha1.AddToScheme(scheme))
 	utilruntime.Must(gatewayapiv1.Install(scheme))
+	utilruntime.Must(gatewayapiv1beta1.Install(scheme))
 
 	logger := log.New("Router", ro.LogLevel)
 
 	crlog.SetLogger(logger)
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+	options := ctrl.Options{
 		Scheme:         scheme,
 		LeaderElection: false,
-		Cache:          cache.Options{},
 		Metrics: server.Options{
 			BindAddress: "0",
 		},
 		HealthProbeBindAddress: ":8082",
-	})
+	}
+
+	// the resources of the gateway are in its namespace, only the routes attached to the
+	// gateway can be in other namespaces.
+	ro.SetGatewayCacheOptions(&options, ro.namespace, &v1alpha1.L34Route{})
+
+	// the BGP passwords are only read from the secrets of the namespace of the gateway.
+	options.Cache.ByObject[&v1.Secret{}] = cache.ByObject{
+		Namespaces: map[string]cache.Config{
+			ro.namespace: {},
+		},
+	}
+
+	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), options)
 	if err != nil {
 		log.Fatal(setupLog, "failed to create manager for controllers", "err", err)
 	}
 
-	birdInstance := bird.New()
+	// the forwarding state is kept over a restart only by bird, the other routing suites
+	// would silently withdraw the routes the gateway relies on during the restart.
+	if ro.gracefulRestart && ro.routingSuite != "bird" {
+		log.Fatal(setupLog, "graceful restart is only supported by the bird routing suite",
+			"routing-suite", ro.routingSuite)
+	}
+
+	err = ro.tables.Validate()
+	if err != nil {
+		log.Fatal(setupLog, "invalid kernel tables", "err", err)
+	}
+
+	// the rules and routes of another component in the kernel tables or with the rule priorities
+	// of the router would silently break the routing of the VIPs.
+	// only bird uses the blackhole table and the routing instances.
+	tableRanges := ro.tables.Ranges()
+	conflictsFunc := ro.tables.Conflicts
+
+	if ro.routingSuite != "bird" {
+		tableRanges = ro.tables.PolicyRouteRanges()
+		conflictsFunc = ro.tables.PolicyRouteConflicts
+	}
+
+	conflicts, err := conflictsFunc()
+	if err != nil {
+		log.Fatal(setupLog, "failed to check the kernel tables", "err", err)
+	}
+
+	if len(conflicts) > 0 {
+		log.Fatal(setupLog, "kernel tables or rule priorities used by another component",
+			"tables", tableRanges, "conflicts", conflicts)
+	}
+
+	routingSuiteInstance := ro.newRoutingSuite(setupLog)
 
 	go func() {
-		err := birdInstance.Run(ctx)
+		err := routingSuiteInstance.Run(runCtx)
 		if err != nil {
-			setupLog.Error(err, "failed to start bird")
+			setupLog.Error(err, "failed to start the routing suite", "routing-suite", ro.routingSuite)
 		}
 	}()
 
-	if err = (&router.Controller{
+	routerController := &router.Controller{
 		Client:               mgr.GetClient(),
 		Scheme:               mgr.GetScheme(),
-		RoutingSuiteInstance: birdInstance,
+		RoutingSuiteInstance: routingSuiteInstance,
 		Name:                 ro.name,
 		Namespace:            ro.namespace,
-	}).SetupWithManager(mgr); err != nil {
+		ReadinessFile:        ro.readinessFile,
+		Recorder:             mgr.GetEventRecorderFor("router"),
+		GracefulShutdown:     ro.gracefulShutdown,
+	}
+
+	if err = routerController.SetupWithManager(mgr); err != nil {
 		log.Fatal(setupLog, "failed to create controller", "err", err, "controller", "Gateway")
 	}
 
-	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
+	routerName, err := os.Hostname()
+	if err != nil {
+		log.Fatal(setupLog, "failed to get the hostname", "err", err)
+	}
+
+	if err = (&router.StatusUpdater{
+		Client:     mgr.GetClient(),
+		Name:       ro.name,
+		Namespace:  ro.namespace,
+		RouterName: routerName,
+		Statistics: routingSuiteInstance,
+	}).SetupWithManager(mgr); err != nil {
+		log.Fatal(setupLog, "failed to create status updater", "err", err)
+	}
+
+	healthzCheck, readyzCheck := healthz.Ping, healthz.Ping
+
+	if checker, ok := routingSuiteInstance.(healthChecker); ok {
+		healthzCheck, readyzCheck = checker.Healthz, checker.Readyz
+	}
+
+	if err := mgr.AddHealthzCheck("healthz", healthzCheck); err != nil {
 		log.Fatal(setupLog, "unable to set up health check", "err", err)
 	}
 
-	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
+	if err := mgr.AddReadyzCheck("readyz", readyzCheck); err != nil {
 		log.Fatal(setupLog, "unable to set up ready check", "err", err)
 	}
 
-	if err := mgr.Start(ctx); err != nil {
+	go ro.shutdown(ctx, runCtx, stop, routerController)
+
+	if err := mgr.Start(runCtx); err != nil {
 		log.Fatal(setupLog, "failed to start manager", "err", err)
 	}
 }
+
+// shutdown waits for the router to be stopped (ctx), drains the traffic and notifies the
+// stateless-load-balancer over the shutdown socket before stopping the routing suite and
+// the controllers (runCtx).
+func (ro *runOptions) shutdown(
+	ctx context.Context,
+	runCtx context.Context,
+	stop context.CancelFunc,
+	routerController *router.Controller,
+) {
+	logger := ctrl.Log.WithName("shutdown")
+
+	var shutdownServer *shutdown.Server
+
+	if ro.shutdownSocket != "" {
+		shutdownServer = shutdown.NewServer(ro.shutdownSocket)
+
+		go func() {
+			err := shutdownServer.Run(runCtx)
+			if err != nil {
+				logger.Error(err, "failed to run the shutdown server")
+			}
+		}()
+	}
+
+	<-ctx.Done()
+
+	if ro.drainPeriod > 0 {
+		logger.Info("draining the traffic", "drain-period", ro.drainPeriod)
+
+		err := routerController.Drain(runCtx)
+		if err != nil {
+			logger.Error(err, "failed to withdraw the VIPs")
+		}
+
+		time.Sleep(ro.drainPeriod)
+	}
+
+	if shutdownServer != nil {
+		shutdownServer.Drained()
+	}
+
+	stop()
+}
@@ -21,8 +21,10 @@ import (
 
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/api/v1alpha1"
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/cli"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/controllers/gatewayclass"
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/controllers/statelessloadbalancer/controllermanager"
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/controllers/statelessloadbalancer/podinjector"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/controllers/statelessloadbalancer/podinjector/network"
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/log"
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/networkattachment"
 	"github.com/spf13/cobra"
@@ -35,11 +37,15 @@ import (
 	crlog "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	gatewayapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayapiv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
 )
 
 type runOptions struct {
 	cli.CommonOptions
+	cli.LeaderElectionOptions
+	cli.CacheOptions
 	gatewayClassName string
+	firstTableID     int
 }
 
 func newCmdRun() *cobra.Command {
@@ -61,6 +67,15 @@ func newCmdRun() *cobra.Command {
 		"Name of the Gateway Class handled by this controller manager.",
 	)
 
+	cmd.Flags().IntVar(
+		&runOpts.firstTableID,
+		"first-table-id",
+		network.DefaultFirstTableID,
+		"first kernel table used by the policy routes of the VIPs in the pods.",
+	)
+
+	runOpts.SetLeaderElectionFlags(cmd, "stateless-load-balancer-controller-manager.l34.gateway.api.poc")
+	runOpts.SetCacheFlags(cmd)
 	runOpts.SetCommonFlags(cmd)
 
 	return cmd
@@ -73,20 +88,25 @@ func (ro *runOptions) run(ctx context.Context) {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 	utilruntime.Must(v1alpha1.AddToScheme(scheme))
 	utilruntime.Must(gatewayapiv1.Install(scheme))
+	utilruntime.Must(gatewayapiv1beta1.Install(scheme))
 
 	logger := log.New("stateless-load-balancer-controller-manager", ro.LogLevel)
 
 	crlog.SetLogger(logger)
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme:         scheme,
-		LeaderElection: false,
-		Cache:          cache.Options{},
+	options := ctrl.Options{
+		Scheme: scheme,
+		Cache:  cache.Options{},
 		Metrics: server.Options{
 			BindAddress: "0",
 		},
 		HealthProbeBindAddress: ":8081",
-	})
+	}
+
+	ro.SetManagerOptions(&options)
+	ro.SetManagerCacheOptions(&options)
+
+	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), options)
 	if err != nil {
 		log.Fatal(setupLog, "failed to create manager for controllers", "err", err)
 	}
@@ -105,10 +125,19 @@ func (ro *runOptions) run(ctx context.Context) {
 		Scheme:           mgr.GetScheme(),
 		GatewayClassName: ro.gatewayClassName,
 		GetIPsFunc:       networkattachment.GetIPs,
+		FirstTableID:     ro.firstTableID,
 	}).SetupWithManager(mgr); err != nil {
 		log.Fatal(setupLog, "failed to create controller", "err", err, "controller", "Pod")
 	}
 
+	if err = (&gatewayclass.Controller{
+		Client:            mgr.GetClient(),
+		ControllerName:    ro.gatewayClassName,
+		SupportedFeatures: controllermanager.SupportedFeatures,
+	}).SetupWithManager(mgr); err != nil {
+		log.Fatal(setupLog, "failed to create controller", "err", err, "controller", "GatewayClass")
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		log.Fatal(setupLog, "unable to set up health check", "err", err)
 	}
@@ -18,29 +18,41 @@ package cmd
 
 import (
 	"context"
+	"math"
+	"time"
 
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/api/v1alpha1"
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/cli"
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/controllers/statelessloadbalancer"
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/log"
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/nfqlb"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/readiness"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/shutdown"
 	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	v1discovery "k8s.io/api/discovery/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
-	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	crlog "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	gatewayapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayapiv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
 )
 
 type runOptions struct {
 	cli.CommonOptions
+	cli.CacheOptions
 	name             string
 	namespace        string
 	gatewayClassName string
+	readinessFile    string
+	shutdownSocket   string
+	drainTimeout     time.Duration
+	firstFwMark      uint32
+	lastFwMark       uint32
 }
 
 func newCmdRun() *cobra.Command {
@@ -76,43 +88,122 @@ func newCmdRun() *cobra.Command {
 		"Name of the Gateway Class handled by this controller manager.",
 	)
 
+	cmd.Flags().StringVar(
+		&runOpts.readinessFile,
+		"readiness-file",
+		"",
+		"file shared with the router in which the VIPs ready to be announced are written (disabled if empty).",
+	)
+
+	cmd.Flags().StringVar(
+		&runOpts.shutdownSocket,
+		"shutdown-socket",
+		"",
+		"socket shared with the router on which the data plane waits for the traffic to be drained before being removed (disabled if empty).",
+	)
+
+	cmd.Flags().DurationVar(
+		&runOpts.drainTimeout,
+		"drain-timeout",
+		time.Minute,
+		"maximum time waited for the router to drain the traffic when the stateless-load-balancer is stopped.",
+	)
+
+	cmd.Flags().Uint32Var(
+		&runOpts.firstFwMark,
+		"first-fwmark",
+		5000,
+		"first forwarding mark (and kernel table) used to route the traffic to the targets.",
+	)
+
+	cmd.Flags().Uint32Var(
+		&runOpts.lastFwMark,
+		"last-fwmark",
+		math.MaxUint32,
+		"last forwarding mark (and kernel table) used to route the traffic to the targets.",
+	)
+
 	runOpts.SetCommonFlags(cmd)
+	runOpts.SetCacheFlags(cmd)
 
 	return cmd
 }
 
 func (ro *runOptions) run(ctx context.Context) {
+	// The data plane (nfqlb) and the controllers keep running until the router has
+	// drained the traffic.
+	runCtx, stop := context.WithCancel(context.WithoutCancel(ctx))
+	defer stop()
+
 	scheme := runtime.NewScheme()
 	setupLog := ctrl.Log.WithName("setup")
 
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 	utilruntime.Must(v1alpha1.AddToScheme(scheme))
 	utilruntime.Must(gatewayapiv1.Install(scheme))
+	utilruntime.Must(gatewayapiv1beta1.Install(scheme))
 
 	logger := log.New("stateless-load-balancer", ro.LogLevel)
 
 	crlog.SetLogger(logger)
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+	options := ctrl.Options{
 		Scheme:         scheme,
 		LeaderElection: false,
-		Cache:          cache.Options{},
 		Metrics: server.Options{
 			BindAddress: "0",
 		},
 		HealthProbeBindAddress: ":8081",
-	})
+	}
+
+	// the resources of the gateway are in its namespace, only the routes attached to the
+	// gateway, their services and the reference grants can be in other namespaces.
+	ro.SetGatewayCacheOptions(&options, ro.namespace,
+		&v1alpha1.L34Route{},
+		&v1.Service{},
+		&v1discovery.EndpointSlice{},
+		&gatewayapiv1beta1.ReferenceGrant{},
+	)
+
+	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), options)
 	if err != nil {
 		log.Fatal(setupLog, "failed to create manager for controllers", "err", err)
 	}
 
-	lb, err := nfqlb.New()
+	lb, err := nfqlb.New(
+		nfqlb.WithStartingOffset(ro.firstFwMark),
+		nfqlb.WithLastOffset(ro.lastFwMark),
+	)
 	if err != nil {
 		log.Fatal(setupLog, "failed to instantiate nfqlb", "err", err)
 	}
 
+	// the rules and routes of another component using the forwarding marks of nfqlb would
+	// silently break the routing of the traffic to the targets.
+	conflicts, err := lb.Conflicts()
+	if err != nil {
+		log.Fatal(setupLog, "failed to check the kernel tables", "err", err)
+	}
+
+	if len(conflicts) > 0 {
+		log.Fatal(setupLog, "forwarding marks or kernel tables used by another component",
+			"first-fwmark", ro.firstFwMark, "last-fwmark", ro.lastFwMark, "conflicts", conflicts)
+	}
+
+	if ro.readinessFile != "" {
+		// Nothing is ready until the first reconciliation (e.g. after a restart of the container).
+		err = readiness.Write(ro.readinessFile, []string{})
+		if err != nil {
+			log.Fatal(setupLog, "failed to reset the readiness file", "err", err)
+		}
+	}
+
+	lbStopped := make(chan struct{})
+
 	go func() {
-		err := lb.Start(ctx)
+		defer close(lbStopped)
+
+		err := lb.Start(runCtx)
 		if err != nil {
 			setupLog.Error(err, "failed to start nfqlb")
 		}
@@ -125,6 +216,7 @@ func (ro *runOptions) run(ctx context.Context) {
 		Namespace:        ro.namespace,
 		GatewayClassName: ro.gatewayClassName,
 		ServiceManager:   statelessloadbalancer.NewManager(statelessloadbalancer.NewNFQLB(lb)),
+		ReadinessFile:    ro.readinessFile,
 	}).SetupWithManager(mgr); err != nil {
 		log.Fatal(setupLog, "failed to create controller", "err", err, "controller", "Gateway")
 	}
@@ -137,7 +229,34 @@ func (ro *runOptions) run(ctx context.Context) {
 		log.Fatal(setupLog, "unable to set up ready check", "err", err)
 	}
 
-	if err := mgr.Start(ctx); err != nil {
+	go ro.shutdown(ctx, stop)
+
+	if err := mgr.Start(runCtx); err != nil {
 		log.Fatal(setupLog, "failed to start manager", "err", err)
 	}
+
+	// the nftables state of nfqlb is removed before exiting.
+	<-lbStopped
+}
+
+// shutdown waits for the stateless-load-balancer to be stopped (ctx) and for the router
+// to drain the traffic before stopping the data plane and the controllers.
+func (ro *runOptions) shutdown(ctx context.Context, stop context.CancelFunc) {
+	<-ctx.Done()
+
+	if ro.shutdownSocket != "" {
+		logger := ctrl.Log.WithName("shutdown")
+		logger.Info("waiting for the router to drain the traffic", "drain-timeout", ro.drainTimeout)
+
+		waitCtx, cancel := context.WithTimeout(context.Background(), ro.drainTimeout)
+
+		err := shutdown.Wait(waitCtx, ro.shutdownSocket)
+		if err != nil {
+			logger.Error(err, "the traffic has not been drained")
+		}
+
+		cancel()
+	}
+
+	stop()
 }
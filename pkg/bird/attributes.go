@@ -0,0 +1,201 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bird
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	communityParts      = 2
+	largeCommunityParts = 3
+	communityPartSize   = 16
+	largeCommunityPart  = 32
+)
+
+// vipRouteConfig returns the static route of the VIP with the BGP attributes
+// (communities, large communities, MED and local preference) set on it.
+func vipRouteConfig(vip VIP) string {
+	statements := bgpAttributesStatements(vip.GetBgpAttributes())
+	if len(statements) == 0 {
+		return fmt.Sprintf(vipRouteTemplate, vip.GetCIDR())
+	}
+
+	conf := ""
+
+	for _, statement := range statements {
+		conf += fmt.Sprintf("\t\t%s;\n", statement)
+	}
+
+	return fmt.Sprintf(vipRouteAttributesTemplate, vip.GetCIDR(), conf)
+}
+
+func bgpAttributesStatements(attributes BgpAttributes) []string {
+	statements := []string{}
+
+	if attributes == nil {
+		return statements
+	}
+
+	for _, community := range attributes.GetCommunities() {
+		value, ok := parseCommunity(community, communityParts, communityPartSize)
+		if ok {
+			statements = append(statements, fmt.Sprintf("bgp_community.add(%s)", value))
+		}
+	}
+
+	for _, community := range attributes.GetLargeCommunities() {
+		value, ok := parseCommunity(community, largeCommunityParts, largeCommunityPart)
+		if ok {
+			statements = append(statements, fmt.Sprintf("bgp_large_community.add(%s)", value))
+		}
+	}
+
+	if attributes.GetMED() != nil {
+		statements = append(statements, fmt.Sprintf("bgp_med = %d", *attributes.GetMED()))
+	}
+
+	if attributes.GetLocalPreference() != nil {
+		statements = append(statements, fmt.Sprintf("bgp_local_pref = %d", *attributes.GetLocalPreference()))
+	}
+
+	return statements
+}
+
+// parseCommunity converts a community (e.g. 65000:100) into its bird format (e.g. (65000,100)).
+// false is returned if the community is invalid.
+func parseCommunity(community string, parts int, bitSize int) (string, bool) {
+	values := strings.Split(community, ":")
+	if len(values) != parts {
+		return "", false
+	}
+
+	for _, value := range values {
+		_, err := strconv.ParseUint(value, 10, bitSize)
+		if err != nil {
+			return "", false
+		}
+	}
+
+	return fmt.Sprintf("(%s)", strings.Join(values, ",")), true
+}
+
+// bgpExportConfig returns the export filter of a BGP protocol. Only the VIPs exported
+// to the gateway are announced and the local ASN is prepended to the AS path of the
+// VIPs requesting it, otherwise the default filter (announced_routes) is used.
+func bgpExportConfig(gateway Gateway, vips []VIP, localASN uint32, ipFamily string) string {
+	prepend := ""
+
+	for _, vip := range ExportedVIPs(vips, gateway) {
+		if vip.GetBgpAttributes() == nil || vip.GetBgpAttributes().GetASPathPrepend() == nil ||
+			*vip.GetBgpAttributes().GetASPathPrepend() == 0 {
+			continue
+		}
+
+		if !sameIPFamily(vip.GetCIDR(), ipFamily) {
+			continue
+		}
+
+		statements := strings.Repeat(fmt.Sprintf(" bgp_path.prepend(%d);", localASN),
+			int(*vip.GetBgpAttributes().GetASPathPrepend()))
+
+		prepend += fmt.Sprintf(bgpExportPrependTemplate, vip.GetCIDR(), statements)
+	}
+
+	return exportConfig(gateway, vips, ipFamily, prepend)
+}
+
+// exportConfig returns the export filter of a protocol announcing the VIPs exported
+// to the gateway, with the AS path prepend statements in parameter. The default filter
+// (announced_routes) is used if all the VIPs are exported without prepend, and none is
+// exported if no VIP of the IP family is exported to the gateway.
+func exportConfig(gateway Gateway, vips []VIP, ipFamily string, prepend string) string {
+	all, exported := []string{}, []string{}
+
+	for _, vip := range vips {
+		if !sameIPFamily(vip.GetCIDR(), ipFamily) {
+			continue
+		}
+
+		all = append(all, vip.GetCIDR())
+
+		if exportedTo(vip, gateway) {
+			exported = append(exported, vip.GetCIDR())
+		}
+	}
+
+	scope := ""
+
+	if len(exported) < len(all) {
+		if len(exported) == 0 {
+			return "none"
+		}
+
+		scope = fmt.Sprintf(bgpExportScopeTemplate, strings.Join(exported, ", "))
+	}
+
+	if scope == "" && prepend == "" {
+		return bgpExportFilter
+	}
+
+	return fmt.Sprintf(bgpExportCustomFilterTemplate, scope, prepend)
+}
+
+// ExportedVIPs returns the VIPs exported to the gateway.
+func ExportedVIPs(vips []VIP, gateway Gateway) []VIP {
+	exported := []VIP{}
+
+	for _, vip := range vips {
+		if exportedTo(vip, gateway) {
+			exported = append(exported, vip)
+		}
+	}
+
+	return exported
+}
+
+// exportedTo returns true if the VIP is exported to the gateway.
+func exportedTo(vip VIP, gateway Gateway) bool {
+	if vip.GetExportedTo() == nil {
+		return true
+	}
+
+	for _, name := range vip.GetExportedTo() {
+		if name == gateway.GetName() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sameIPFamily returns true if the CIDR belongs to the IP family (ipv4 or ipv6).
+func sameIPFamily(cidr string, ipFamily string) bool {
+	return (ipFamily == "ipv4" && isIPv4CIDR(cidr)) || (ipFamily == "ipv6" && isIPv6CIDR(cidr))
+}
+
+func vipCIDRs(vips []VIP) []string {
+	cidrs := []string{}
+
+	for _, vip := range vips {
+		cidrs = append(cidrs, vip.GetCIDR())
+	}
+
+	return cidrs
+}
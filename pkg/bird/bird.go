@@ -18,18 +18,47 @@ package bird
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"sync"
+	"syscall"
+	"time"
+
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/log"
+)
+
+var (
+	errBirdRunning    = errors.New("bird is already running")
+	errBirdExited     = errors.New("bird exited")
+	errBirdNotRunning = errors.New("bird is not running")
+	errBirdCrashing   = errors.New("bird keeps exiting")
 )
 
-var errBirdRunning = errors.New("bird is already running")
+const (
+	// birdStopTimeout is the time given to bird to stop before being killed.
+	birdStopTimeout = 5 * time.Second
+	// birdRestartMinBackoff and birdRestartMaxBackoff bound the time waited before
+	// restarting bird after it has exited.
+	birdRestartMinBackoff = 1 * time.Second
+	birdRestartMaxBackoff = 1 * time.Minute
+	// birdStableDuration is the time bird has to run for the restart backoff to be reset.
+	birdStableDuration = 1 * time.Minute
+	// birdMaxRestarts is the number of consecutive restarts after which bird is
+	// reported as unhealthy.
+	birdMaxRestarts = 5
+)
 
 // Bird represents the bird configuration.
 type Bird struct {
-	// SocketPath is the full path with filename to communicate with birdc
+	// Binary is the bird executable (path or name looked up in PATH).
+	Binary string
+	// SocketPath is the full path with filename of the bird control socket
 	SocketPath string
 	// configuration file (with path)
 	ConfigFile string
@@ -39,114 +68,493 @@ type Bird struct {
 	LogFileSize   int
 	LogFile       string
 	LogFileBackup string
+	// Directory where the BGP passwords are written. The passwords are
+	// included by the bird configuration, so they are never written in
+	// the configuration file.
+	PasswordDirectory string
+	// Keep the forwarding state during a restart of bird: the routes are kept
+	// in the kernel tables when bird stops and bird is started in graceful
+	// restart recovery mode with the first configuration received, so the
+	// routes are only flushed once the BGP sessions have been re-established.
+	GracefulRestart bool
+	// Tables are the kernel tables and the priorities of the policy rules used
+	// to route the traffic of the VIPs.
+	Tables RoutingTables
 
-	running bool
-	mu      sync.Mutex
+	// supervised is set while Run is supervising bird.
+	supervised bool
+	running    bool
+	// number of consecutive restarts of bird without it running for birdStableDuration.
+	restarts int
+	// configured is closed when the first configuration has been written.
+	configured chan struct{}
+	// fingerprint of the configuration currently applied in bird.
+	appliedFingerprint string
+	// last configuration accepted by bird (or parsed by bird while it was not
+	// running), replayed when bird is restarted.
+	lastGood *lastGoodConfig
+	// source addresses of the BGP sessions added to the loopback interface.
+	sourceAddresses []string
+	mu              sync.Mutex
+}
+
+type lastGoodConfig struct {
+	vips     []VIP
+	gateways []Gateway
 }
 
 // New is the bird constructor.
 func New() *Bird {
 	return &Bird{
-		SocketPath:    "/var/run/bird/bird.ctl",
-		ConfigFile:    "/etc/bird/bird.conf",
-		LogEnabled:    true,
-		LogFileSize:   defaultLogFileSize,
-		LogFile:       "/var/log/bird.log",
-		LogFileBackup: "/var/log/bird.log.backup",
+		Binary:            "bird",
+		SocketPath:        "/var/run/bird/bird.ctl",
+		ConfigFile:        "/etc/bird/bird.conf",
+		LogEnabled:        true,
+		LogFileSize:       defaultLogFileSize,
+		LogFile:           "/var/log/bird.log",
+		LogFileBackup:     "/var/log/bird.log.backup",
+		PasswordDirectory: "/var/run/bird/passwords",
+		Tables:            DefaultRoutingTables(),
 	}
 }
 
-// Run starts bird with the current bird configuration. Bird will be stopped
-// when the context in parameter will be cancelled.
+// Run starts bird with the current bird configuration and supervises it: if bird
+// exits unexpectedly, it is restarted with an exponential backoff and the last
+// configuration is replayed. Bird will be stopped when the context in parameter
+// will be cancelled.
 func (b *Bird) Run(ctx context.Context) error {
+	if b.GracefulRestart {
+		// bird must start with the BGP sessions configured, otherwise the graceful
+		// restart recovery would end immediately and flush the kernel routes.
+		select {
+		case <-b.configuredChannel():
+		case <-ctx.Done():
+			return nil
+		}
+	}
+
 	b.mu.Lock()
 
-	if b.running {
+	if b.supervised {
+		b.mu.Unlock()
+
 		return errBirdRunning
 	}
 
-	// Write empty config if config file does not exist
-	if _, err := os.Stat(b.ConfigFile); errors.Is(err, os.ErrNotExist) {
-		err := b.writeConfig([]string{}, []Gateway{})
-		if err != nil {
-			return err
+	b.supervised = true
+
+	b.mu.Unlock()
+
+	defer func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		b.supervised = false
+	}()
+
+	logger := log.FromContextOrGlobal(ctx)
+	backoff := birdRestartMinBackoff
+
+	for {
+		started := time.Now()
+
+		err := b.run(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		stable := time.Since(started) >= birdStableDuration
+		if stable {
+			backoff = birdRestartMinBackoff
+		}
+
+		b.mu.Lock()
+
+		if stable {
+			b.restarts = 0
 		}
+
+		b.restarts++
+
+		b.mu.Unlock()
+
+		logger.Error(err, "bird exited, restarting it", "backoff", backoff)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+
+		backoff = min(2*backoff, birdRestartMaxBackoff)
 	}
+}
 
-	b.running = true
+// run starts bird with the last configuration and waits for it to exit.
+func (b *Bird) run(ctx context.Context) error {
+	err := b.start()
+	if err != nil {
+		return err
+	}
 
-	b.mu.Unlock()
+	defer b.stopped()
 
-	//nolint:gosec
-	cmd := exec.CommandContext(
-		ctx,
-		"bird",
+	args := []string{
 		"-d",
 		"-c",
 		b.ConfigFile,
 		"-s",
 		b.SocketPath,
-	)
+	}
 
-	var errFinal error
+	if b.GracefulRestart {
+		args = append(args, "-R")
+	}
+
+	cmd := exec.CommandContext(ctx, b.Binary, args...)
+	// bird is terminated gracefully when the context is cancelled (e.g. the pod is
+	// being deleted), so the BGP peers are notified and withdraw the routes instead
+	// of keeping them as stale. The graceful restart applies only if bird or the
+	// router stops unexpectedly.
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(syscall.SIGTERM) //nolint:wrapcheck
+	}
+	cmd.WaitDelay = birdStopTimeout
 
 	stdoutStderr, err := cmd.CombinedOutput()
-	if err != nil && !errors.Is(err, context.Cause(ctx)) {
-		errFinal = fmt.Errorf("failed starting bird ; %w; %s", err, stdoutStderr)
+	if err != nil {
+		return fmt.Errorf("failed running bird ; %w; %s", err, stdoutStderr)
+	}
+
+	return fmt.Errorf("%w; %s", errBirdExited, stdoutStderr)
+}
+
+// start replays the last configuration and marks bird as running. If no configuration
+// has been received yet, the existing configuration file is used (an empty configuration
+// is written if it does not exist).
+func (b *Bird) start() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch _, err := os.Stat(b.ConfigFile); {
+	case b.lastGood != nil:
+		err := b.writeConfig(b.lastGood.vips, b.lastGood.gateways)
+		if err != nil {
+			return err
+		}
+
+		b.appliedFingerprint = configFingerprint(b.getConfig(b.lastGood.vips, b.lastGood.gateways), b.lastGood.gateways)
+	case errors.Is(err, os.ErrNotExist):
+		err := b.writeConfig([]VIP{}, []Gateway{})
+		if err != nil {
+			return err
+		}
+	}
+
+	b.running = true
+
+	return nil
+}
+
+// stopped marks bird as not running, so the configurations are written until it is
+// restarted, and the next one is fully applied.
+func (b *Bird) stopped() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.running = false
+	b.appliedFingerprint = ""
+}
+
+// Healthz reports bird as unhealthy when it keeps exiting right after being
+// restarted.
+func (b *Bird) Healthz(_ *http.Request) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.restarts >= birdMaxRestarts {
+		return fmt.Errorf("%w: %d consecutive restarts", errBirdCrashing, b.restarts)
+	}
+
+	return nil
+}
+
+// Readyz reports bird as ready when it is running and answers on its control socket.
+func (b *Bird) Readyz(req *http.Request) error {
+	b.mu.Lock()
+	running := b.running
+	b.mu.Unlock()
+
+	if !running {
+		return errBirdNotRunning
+	}
+
+	_, err := NewClient(b.SocketPath).Command(req.Context(), "show status")
+	if err != nil {
+		return fmt.Errorf("bird does not answer on its control socket: %w", err)
 	}
 
-	return errFinal
+	return nil
 }
 
-// Configure writes the bird configuration file, sets the policy routes and configures bird if it is running.
-func (b *Bird) Configure(ctx context.Context, vips []string, gateways []Gateway) error {
+// Configure writes the bird configuration file, adds the source addresses of the BGP sessions to the
+// loopback interface, sets the policy routes (and those of the routing instances of the networks) and
+// configures bird if it is running. An error is returned if the gateways are reachable over more
+// interfaces than the number of routing instances.
+//
+// When bird is running, the new configuration is validated by bird ("configure check") before being
+// applied. If it is rejected, the last configuration accepted by bird is restored and an error is returned.
+// When bird is not running, the new configuration is parsed by bird ("bird -p") before being written, so
+// an invalid configuration is neither written nor replayed when bird is (re)started.
+// Bird is not reconfigured if the configuration has not changed since the last one applied.
+func (b *Bird) Configure(ctx context.Context, vips []VIP, gateways []Gateway) error {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	err := b.writeConfig(vips, gateways)
+	err := checkNetworks(gateways)
 	if err != nil {
 		return err
 	}
 
 	if b.running {
-		//nolint:gosec
-		cmd := exec.CommandContext(
-			ctx,
-			"birdc",
-			"-s",
-			b.SocketPath,
-			"configure",
-			`"`+b.ConfigFile+`"`,
-		)
-
-		stdoutStderr, err := cmd.CombinedOutput()
-		if err != nil && !errors.Is(err, context.Cause(ctx)) {
-			return fmt.Errorf("failed configuring bird ; %w; %s", err, stdoutStderr)
-		}
+		err = b.apply(ctx, vips, gateways)
+	} else {
+		err = b.write(ctx, vips, gateways)
 	}
 
-	err = setPolicyRoutes(vips)
 	if err != nil {
-		return fmt.Errorf("failed to set the policy routes %v: %w", vips, err)
+		return err
+	}
+
+	configured := b.configuredChannelLocked()
+
+	select {
+	case <-configured:
+	default:
+		close(configured)
+	}
+
+	sourceAddresses := SourceAddressCIDRs(gateways)
+
+	b.sourceAddresses, err = SetSourceAddresses(sourceAddresses, b.sourceAddresses)
+	if err != nil {
+		return fmt.Errorf("failed to set the source addresses %v: %w", sourceAddresses, err)
+	}
+
+	policyRoutes := vipCIDRs(vips)
+	policyRoutes = append(policyRoutes, sourceAddresses...)
+
+	err = SetPolicyRoutes(policyRoutes, b.Tables.TableID, b.Tables.RulePriority)
+	if err != nil {
+		return fmt.Errorf("failed to set the policy routes %v: %w", policyRoutes, err)
+	}
+
+	err = setRoutingInstancePolicyRoutes(policyRoutes, routingInstances(gateways, b.Tables.NetworkTableID), b.Tables)
+	if err != nil {
+		return fmt.Errorf("failed to set the routing instances: %w", err)
 	}
 
 	return nil
 }
 
-func (b *Bird) writeConfig(vips []string, gateways []Gateway) error {
-	file, err := os.Create(b.ConfigFile)
-	if err != nil {
-		return fmt.Errorf("failed to create %v, err: %w", b.ConfigFile, err)
+// configuredChannel returns the channel closed when the first configuration has been written.
+func (b *Bird) configuredChannel() <-chan struct{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.configuredChannelLocked()
+}
+
+func (b *Bird) configuredChannelLocked() chan struct{} {
+	if b.configured == nil {
+		b.configured = make(chan struct{})
 	}
 
-	defer file.Close()
+	return b.configured
+}
 
+// apply validates and applies the configuration to the running bird. The last configuration
+// accepted by bird is restored if the new one is rejected.
+func (b *Bird) apply(ctx context.Context, vips []VIP, gateways []Gateway) error {
 	conf := b.getConfig(vips, gateways)
+	fingerprint := configFingerprint(conf, gateways)
 
-	_, err = file.WriteString(conf)
+	if fingerprint == b.appliedFingerprint {
+		return nil
+	}
+
+	err := writePasswords(b.PasswordDirectory, gateways)
+	if err != nil {
+		return b.rollback(ctx, err)
+	}
+
+	candidate, err := writeTempFile(b.ConfigFile, conf)
+	if err != nil {
+		return b.rollback(ctx, err)
+	}
+
+	defer os.Remove(candidate)
+
+	client := NewClient(b.SocketPath)
+
+	err = client.ConfigureCheck(ctx, candidate)
+	if err != nil {
+		return b.rollback(ctx, fmt.Errorf("the configuration is invalid: %w", err))
+	}
+
+	err = os.Rename(candidate, b.ConfigFile)
+	if err != nil {
+		return b.rollback(ctx, fmt.Errorf("failed to rename %v to %v, err: %w", candidate, b.ConfigFile, err))
+	}
+
+	err = client.Configure(ctx, b.ConfigFile)
+	if err != nil {
+		return b.rollback(ctx, fmt.Errorf("failed configuring bird ; %w", err))
+	}
+
+	b.appliedFingerprint = fingerprint
+	b.lastGood = &lastGoodConfig{
+		vips:     vips,
+		gateways: gateways,
+	}
+
+	return nil
+}
+
+// write validates the configuration with bird in parse-only mode and writes it while bird
+// is not running. The configuration is kept as the one to replay when bird is (re)started
+// only if bird accepts it.
+func (b *Bird) write(ctx context.Context, vips []VIP, gateways []Gateway) error {
+	err := writePasswords(b.PasswordDirectory, gateways)
+	if err != nil {
+		return err
+	}
+
+	candidate, err := writeTempFile(b.ConfigFile, b.getConfig(vips, gateways))
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(candidate)
+
+	err = b.parse(ctx, candidate)
+	if err != nil {
+		return fmt.Errorf("the configuration is invalid: %w", err)
+	}
+
+	err = os.Rename(candidate, b.ConfigFile)
+	if err != nil {
+		return fmt.Errorf("failed to rename %v to %v, err: %w", candidate, b.ConfigFile, err)
+	}
+
+	b.lastGood = &lastGoodConfig{
+		vips:     vips,
+		gateways: gateways,
+	}
+
+	return nil
+}
+
+// parse runs bird in parse-only mode on the configuration file in parameter.
+func (b *Bird) parse(ctx context.Context, configFile string) error {
+	stdoutStderr, err := exec.CommandContext(ctx, b.Binary, "-p", "-c", configFile).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed parsing %v with bird ; %w; %s", configFile, err, stdoutStderr)
+	}
+
+	return nil
+}
+
+// rollback restores the last configuration accepted by bird and returns the error in parameter
+// with the result of the rollback.
+func (b *Bird) rollback(ctx context.Context, errApply error) error {
+	if ctx.Err() != nil {
+		// bird might have not received the configuration, it will be retried.
+		return fmt.Errorf("failed to apply the configuration: %w", errApply)
+	}
+
+	b.appliedFingerprint = ""
+
+	lastGood := b.lastGood
+	if lastGood == nil {
+		lastGood = &lastGoodConfig{}
+	}
+
+	err := b.writeConfig(lastGood.vips, lastGood.gateways)
+	if err != nil {
+		return fmt.Errorf("failed to apply the configuration: %w; failed to restore the last valid configuration: %w",
+			errApply, err)
+	}
+
+	err = NewClient(b.SocketPath).Configure(ctx, b.ConfigFile)
+	if err != nil {
+		return fmt.Errorf("failed to apply the configuration: %w; failed to restore the last valid configuration: %w",
+			errApply, err)
+	}
+
+	b.appliedFingerprint = configFingerprint(b.getConfig(lastGood.vips, lastGood.gateways), lastGood.gateways)
+
+	return fmt.Errorf("failed to apply the configuration, the last valid configuration has been restored: %w", errApply)
+}
+
+func (b *Bird) writeConfig(vips []VIP, gateways []Gateway) error {
+	err := writePasswords(b.PasswordDirectory, gateways)
+	if err != nil {
+		return err
+	}
+
+	// write into a temporary file and rename it so bird never reads a partial file.
+	file, err := writeTempFile(b.ConfigFile, b.getConfig(vips, gateways))
 	if err != nil {
-		return fmt.Errorf("failed to write config to %v, err: %w", b.ConfigFile, err)
+		return err
+	}
+
+	err = os.Rename(file, b.ConfigFile)
+	if err != nil {
+		_ = os.Remove(file)
+
+		return fmt.Errorf("failed to rename %v to %v, err: %w", file, b.ConfigFile, err)
 	}
 
 	return nil
 }
+
+// writeTempFile writes the configuration into a temporary file next to the
+// configuration file and returns its path.
+func writeTempFile(configFile string, conf string) (string, error) {
+	file, err := os.CreateTemp(filepath.Dir(configFile), filepath.Base(configFile)+".tmp")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file for %v, err: %w", configFile, err)
+	}
+
+	_, err = file.WriteString(conf)
+	if err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+
+		return "", fmt.Errorf("failed to write config to %v, err: %w", file.Name(), err)
+	}
+
+	err = file.Close()
+	if err != nil {
+		_ = os.Remove(file.Name())
+
+		return "", fmt.Errorf("failed to close %v, err: %w", file.Name(), err)
+	}
+
+	return file.Name(), nil
+}
+
+// configFingerprint returns a fingerprint of the configuration and the passwords
+// it includes, so changes can be detected without keeping the passwords.
+func configFingerprint(conf string, gateways []Gateway) string {
+	hash := sha256.New()
+
+	hash.Write([]byte(conf))
+
+	for _, gateway := range gateways {
+		hash.Write([]byte("\x00" + gateway.GetName() + "\x00" + gatewayPassword(gateway)))
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
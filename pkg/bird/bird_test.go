@@ -19,10 +19,15 @@ package bird_test
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"reflect"
 	"sort"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/api/v1alpha1"
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/bird"
@@ -104,10 +109,10 @@ func TestBird_Configure(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			b := bird.New()
+			b := newBird(t)
 			b.ConfigFile = "./bird.conf"
 
-			if err := b.Configure(context.TODO(), tt.args.vips, tt.args.gateways); (err != nil) != tt.wantErr {
+			if err := b.Configure(context.TODO(), newVIPs(tt.args.vips...), tt.args.gateways); (err != nil) != tt.wantErr {
 				t.Errorf("Bird.Configure() error = %v, wantErr %v", err, tt.wantErr)
 			}
 
@@ -140,6 +145,1284 @@ func TestBird_Configure(t *testing.T) {
 	}
 }
 
+func TestBird_ConfigureBGP(t *testing.T) {
+	tests := []struct {
+		name         string
+		gateway      *gateway
+		wantProtocol string
+	}{
+		{
+			name: "defaults",
+			gateway: &gateway{
+				name:     "gateway",
+				address:  "169.254.100.150",
+				protocol: v1alpha1.BGP,
+				bgp:      &bgpSpec{bfd: &bfdSpec{}},
+				intf:     "eth0",
+			},
+			wantProtocol: bgpProtocolConfig("gateway", "10179 as 8103", "169.254.100.150 port 10179 as 4248829953",
+				"\tbfd off;", 3, 1, "ipv4"),
+		},
+		{
+			name: "local ASN without local port",
+			gateway: &gateway{
+				name:     "gateway",
+				address:  "169.254.100.150",
+				protocol: v1alpha1.BGP,
+				bgp:      &bgpSpec{localASN: newUint32(65001), bfd: &bfdSpec{}},
+				intf:     "eth0",
+			},
+			wantProtocol: bgpProtocolConfig("gateway", "10179 as 65001", "169.254.100.150 port 10179 as 4248829953",
+				"\tbfd off;", 3, 1, "ipv4"),
+		},
+		{
+			name: "remote ASN without remote port",
+			gateway: &gateway{
+				name:     "gateway",
+				address:  "169.254.100.150",
+				protocol: v1alpha1.BGP,
+				bgp:      &bgpSpec{remoteASN: newUint32(65002), bfd: &bfdSpec{}},
+				intf:     "eth0",
+			},
+			wantProtocol: bgpProtocolConfig("gateway", "10179 as 8103", "169.254.100.150 port 10179 as 65002",
+				"\tbfd off;", 3, 1, "ipv4"),
+		},
+		{
+			name: "ports without ASNs",
+			gateway: &gateway{
+				name:     "gateway",
+				address:  "169.254.100.150",
+				protocol: v1alpha1.BGP,
+				bgp:      &bgpSpec{localPort: newUint16(179), remotePort: newUint16(1179), bfd: &bfdSpec{}},
+				intf:     "eth0",
+			},
+			wantProtocol: bgpProtocolConfig("gateway", "179 as 8103", "169.254.100.150 port 1179 as 4248829953",
+				"\tbfd off;", 3, 1, "ipv4"),
+		},
+		{
+			name: "hold time rounded by second",
+			gateway: &gateway{
+				name:     "gateway",
+				address:  "169.254.100.150",
+				protocol: v1alpha1.BGP,
+				bgp:      &bgpSpec{holdTime: "89600ms", bfd: &bfdSpec{}},
+				intf:     "eth0",
+			},
+			wantProtocol: bgpProtocolConfig("gateway", "10179 as 8103", "169.254.100.150 port 10179 as 4248829953",
+				"\tbfd off;", 90, 30, "ipv4"),
+		},
+		{
+			name: "hold time in minutes",
+			gateway: &gateway{
+				name:     "gateway",
+				address:  "169.254.100.150",
+				protocol: v1alpha1.BGP,
+				bgp:      &bgpSpec{holdTime: "1m", bfd: &bfdSpec{}},
+				intf:     "eth0",
+			},
+			wantProtocol: bgpProtocolConfig("gateway", "10179 as 8103", "169.254.100.150 port 10179 as 4248829953",
+				"\tbfd off;", 60, 20, "ipv4"),
+		},
+		{
+			name: "hold time below minimum",
+			gateway: &gateway{
+				name:     "gateway",
+				address:  "169.254.100.150",
+				protocol: v1alpha1.BGP,
+				bgp:      &bgpSpec{holdTime: "1s", bfd: &bfdSpec{}},
+				intf:     "eth0",
+			},
+			wantProtocol: bgpProtocolConfig("gateway", "10179 as 8103", "169.254.100.150 port 10179 as 4248829953",
+				"\tbfd off;", 3, 1, "ipv4"),
+		},
+		{
+			name: "hold time zero",
+			gateway: &gateway{
+				name:     "gateway",
+				address:  "169.254.100.150",
+				protocol: v1alpha1.BGP,
+				bgp:      &bgpSpec{holdTime: "0s", bfd: &bfdSpec{}},
+				intf:     "eth0",
+			},
+			wantProtocol: bgpProtocolConfig("gateway", "10179 as 8103", "169.254.100.150 port 10179 as 4248829953",
+				"\tbfd off;", 0, 0, "ipv4"),
+		},
+		{
+			name: "invalid hold time",
+			gateway: &gateway{
+				name:     "gateway",
+				address:  "169.254.100.150",
+				protocol: v1alpha1.BGP,
+				bgp:      &bgpSpec{holdTime: "24", bfd: &bfdSpec{}},
+				intf:     "eth0",
+			},
+			wantProtocol: bgpProtocolConfig("gateway", "10179 as 8103", "169.254.100.150 port 10179 as 4248829953",
+				"\tbfd off;", 3, 1, "ipv4"),
+		},
+		{
+			name: "bfd switched off with timers",
+			gateway: &gateway{
+				name:     "gateway",
+				address:  "169.254.100.150",
+				protocol: v1alpha1.BGP,
+				bgp: &bgpSpec{bfd: &bfdSpec{
+					sw:         newBool(false),
+					minTx:      "300ms",
+					minRx:      "300ms",
+					multiplier: newUint16(5),
+				}},
+				intf: "eth0",
+			},
+			wantProtocol: bgpProtocolConfig("gateway", "10179 as 8103", "169.254.100.150 port 10179 as 4248829953",
+				"\tbfd off;", 3, 1, "ipv4"),
+		},
+		{
+			name: "bfd without timers",
+			gateway: &gateway{
+				name:     "gateway",
+				address:  "169.254.100.150",
+				protocol: v1alpha1.BGP,
+				bgp:      &bgpSpec{bfd: &bfdSpec{sw: newBool(true)}},
+				intf:     "eth0",
+			},
+			wantProtocol: bgpProtocolConfig("gateway", "10179 as 8103", "169.254.100.150 port 10179 as 4248829953",
+				"bfd {};", 3, 1, "ipv4"),
+		},
+		{
+			name: "bfd timers rounded by millisecond",
+			gateway: &gateway{
+				name:     "gateway",
+				address:  "169.254.100.150",
+				protocol: v1alpha1.BGP,
+				bgp: &bgpSpec{bfd: &bfdSpec{
+					sw:    newBool(true),
+					minTx: "1s",
+					minRx: "250400us",
+				}},
+				intf: "eth0",
+			},
+			wantProtocol: bgpProtocolConfig("gateway", "10179 as 8103", "169.254.100.150 port 10179 as 4248829953",
+				"bfd {\n\t\tmin rx interval 250ms;\n\t\tmin tx interval 1000ms;\n\t};", 3, 1, "ipv4"),
+		},
+		{
+			name: "bfd invalid timers and multiplier only",
+			gateway: &gateway{
+				name:     "gateway",
+				address:  "169.254.100.150",
+				protocol: v1alpha1.BGP,
+				bgp: &bgpSpec{bfd: &bfdSpec{
+					sw:         newBool(true),
+					minTx:      "fast",
+					minRx:      "0s",
+					multiplier: newUint16(3),
+				}},
+				intf: "eth0",
+			},
+			wantProtocol: bgpProtocolConfig("gateway", "10179 as 8103", "169.254.100.150 port 10179 as 4248829953",
+				"bfd {\n\t\tmultiplier 3;\n\t};", 3, 1, "ipv4"),
+		},
+		{
+			name: "all fields ipv6",
+			gateway: &gateway{
+				name:     "gateway-v6",
+				address:  "100:100::150",
+				protocol: v1alpha1.BGP,
+				bgp: &bgpSpec{
+					remotePort: newUint16(179),
+					localPort:  newUint16(1179),
+					remoteASN:  newUint32(65002),
+					localASN:   newUint32(65001),
+					holdTime:   "9s",
+					bfd:        bfd,
+				},
+				intf: "eth0",
+			},
+			wantProtocol: bgpProtocolConfig("gateway-v6", "1179 as 65001", "100:100::150 port 179 as 65002",
+				"bfd {\n\t\tmin rx interval 300ms;\n\t\tmin tx interval 300ms;\n\t\tmultiplier 5;\n\t};", 9, 3, "ipv6"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBird(t)
+			b.ConfigFile = "./bird.conf"
+
+			if err := b.Configure(context.TODO(), []bird.VIP{}, []bird.Gateway{tt.gateway}); err != nil {
+				t.Errorf("Bird.Configure() error = %v", err)
+			}
+
+			config, err := os.ReadFile(b.ConfigFile)
+			if err != nil {
+				t.Errorf("error reading bird config file = %v", err)
+			}
+
+			wantConfig := strings.Replace(emptyConfig, bfdProtocolConfig,
+				networkConfig+"\n\n"+tt.wantProtocol+"\n\n"+bfdProtocolConfig, 1)
+			if string(config) != wantConfig {
+				t.Errorf("Bird.Configure() config = %v, want %v", string(config), wantConfig)
+			}
+
+			err = os.Remove(b.ConfigFile)
+			if err != nil {
+				t.Errorf("error deleting bird config file = %v", err)
+			}
+		})
+	}
+}
+
+func TestBird_ConfigurePassword(t *testing.T) {
+	b := newBird(t)
+	b.ConfigFile = "./bird.conf"
+	b.PasswordDirectory = t.TempDir()
+
+	gw := &gateway{
+		name:     "gateway",
+		address:  "169.254.100.150",
+		protocol: v1alpha1.BGP,
+		bgp:      &bgpSpec{password: "my-secret-password", bfd: &bfdSpec{}},
+		intf:     "eth0",
+	}
+
+	err := b.Configure(context.TODO(), []bird.VIP{}, []bird.Gateway{gw})
+	if err != nil {
+		t.Fatalf("Bird.Configure() error = %v", err)
+	}
+
+	config, err := os.ReadFile(b.ConfigFile)
+	if err != nil {
+		t.Fatalf("error reading bird config file = %v", err)
+	}
+
+	passwordFile := filepath.Join(b.PasswordDirectory, "gateway.conf")
+
+	if strings.Contains(string(config), gw.bgp.password) {
+		t.Errorf("Bird.Configure() config contains the password")
+	}
+
+	if !strings.Contains(string(config), fmt.Sprintf("\tinclude \"%s\";\n", passwordFile)) {
+		t.Errorf("Bird.Configure() config = %v, want include of %v", string(config), passwordFile)
+	}
+
+	password, err := os.ReadFile(passwordFile)
+	if err != nil {
+		t.Fatalf("error reading password file = %v", err)
+	}
+
+	if string(password) != "password \"my-secret-password\";\n" {
+		t.Errorf("Bird.Configure() password file = %v", string(password))
+	}
+
+	fileInfo, err := os.Stat(passwordFile)
+	if err != nil {
+		t.Fatalf("error reading password file info = %v", err)
+	}
+
+	if fileInfo.Mode().Perm() != 0o600 {
+		t.Errorf("Bird.Configure() password file mode = %v, want %v", fileInfo.Mode().Perm(), os.FileMode(0o600))
+	}
+
+	// the password file is removed with the authentication
+	gw.bgp.password = ""
+
+	err = b.Configure(context.TODO(), []bird.VIP{}, []bird.Gateway{gw})
+	if err != nil {
+		t.Fatalf("Bird.Configure() error = %v", err)
+	}
+
+	if _, err := os.Stat(passwordFile); !os.IsNotExist(err) {
+		t.Errorf("Bird.Configure() password file still exists, err = %v", err)
+	}
+
+	// passwords that cannot be written as bird strings are refused
+	gw.bgp.password = "my\"password"
+
+	err = b.Configure(context.TODO(), []bird.VIP{}, []bird.Gateway{gw})
+	if err == nil || strings.Contains(err.Error(), gw.bgp.password) {
+		t.Errorf("Bird.Configure() error = %v, want error without the password", err)
+	}
+
+	err = os.Remove(b.ConfigFile)
+	if err != nil {
+		t.Errorf("error deleting bird config file = %v", err)
+	}
+}
+
+// bgpProtocolConfig returns the expected BGP protocol of a gateway in the bird configuration.
+func TestBird_ConfigureBgpAttributes(t *testing.T) {
+	vips := []bird.VIP{
+		&vip{
+			cidr: "20.0.0.1/32",
+			bgpAttributes: &bgpAttributes{
+				communities:      []string{"65000:100", "65000:70000", "invalid"},
+				largeCommunities: []string{"4200000000:1:2"},
+				med:              newUint32(10),
+				localPreference:  newUint32(200),
+			},
+		},
+		&vip{
+			cidr: "20.0.0.2/32",
+			bgpAttributes: &bgpAttributes{
+				asPathPrepend: newUint32(2),
+			},
+		},
+		&vip{
+			cidr: "2000::1/128",
+			bgpAttributes: &bgpAttributes{
+				asPathPrepend: newUint32(1),
+			},
+		},
+		&vip{
+			cidr: "2000::2/128",
+		},
+	}
+
+	b := newBird(t)
+	b.ConfigFile = filepath.Join(t.TempDir(), "bird.conf")
+
+	err := b.Configure(context.TODO(), vips, []bird.Gateway{gatewayIPv4BGPBFD, gatewayIPv6BGPBFD})
+	if err != nil {
+		t.Fatalf("Bird.Configure() error = %v", err)
+	}
+
+	config, err := os.ReadFile(b.ConfigFile)
+	if err != nil {
+		t.Fatalf("error reading bird config file = %v", err)
+	}
+
+	want := []string{
+		`	route 20.0.0.1/32 via "lo" {
+		bgp_community.add((65000,100));
+		bgp_large_community.add((4200000000,1,2));
+		bgp_med = 10;
+		bgp_local_pref = 200;
+	};
+`,
+		"\troute 20.0.0.2/32 via \"lo\";\n",
+		"\troute 2000::2/128 via \"lo\";\n",
+		`	ipv4 {
+		import filter gateway_routes;
+		export filter {
+			if ( net ~ [ 20.0.0.2/32 ] ) then { bgp_path.prepend(8103); bgp_path.prepend(8103); }
+			if ( net ~ [ 0.0.0.0/0 ] ) then reject;
+			if ( net ~ [ 0::/0 ] ) then reject;
+			if source = RTS_STATIC && dest != RTD_BLACKHOLE then accept;
+			else reject;
+		};
+		table network4_0;
+	};`,
+		`	ipv6 {
+		import filter gateway_routes;
+		export filter {
+			if ( net ~ [ 2000::1/128 ] ) then { bgp_path.prepend(8103); }
+			if ( net ~ [ 0.0.0.0/0 ] ) then reject;`,
+	}
+
+	for _, snippet := range want {
+		if !strings.Contains(string(config), snippet) {
+			t.Errorf("Bird.Configure() config = %v, want %v", string(config), snippet)
+		}
+	}
+
+	if strings.Contains(string(config), "65000,70000") {
+		t.Errorf("Bird.Configure() config contains an invalid community")
+	}
+
+	err = os.Remove(b.ConfigFile)
+	if err != nil {
+		t.Errorf("error deleting bird config file = %v", err)
+	}
+
+	err = b.Configure(context.TODO(), []bird.VIP{}, []bird.Gateway{})
+	if err != nil {
+		t.Errorf("Bird.Configure() error = %v", err)
+	}
+}
+
+func TestBird_ConfigureImportPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		gateway *gateway
+		want    string
+	}{
+		{
+			name: "prefixes, default route and max prefixes",
+			gateway: &gateway{
+				name:     "gateway-v4-a-1",
+				address:  "169.254.100.150",
+				protocol: v1alpha1.BGP,
+				bgp:      bgp,
+				intf:     "eth0",
+				imports: &importPolicy{
+					prefixes:    []string{"10.0.0.0/8", "192.168.1.1/24", "2001::/32", "invalid"},
+					maxPrefixes: newUint32(100),
+				},
+			},
+			want: `	ipv4 {
+		import filter {
+			if ( net ~ [ 0.0.0.0/0 ] ) then accept;
+			if ( net ~ [ 10.0.0.0/8+, 192.168.1.0/24+ ] ) then accept;
+			reject;
+		};
+		import limit 100 action block;
+		export filter announced_routes;
+		table network4_0;
+	};`,
+		},
+		{
+			name: "ipv6 without default route",
+			gateway: &gateway{
+				name:     "gateway-v6-a-1",
+				address:  "100:100::150",
+				protocol: v1alpha1.BGP,
+				bgp:      bgp,
+				intf:     "eth0",
+				imports: &importPolicy{
+					prefixes:     []string{"10.0.0.0/8", "2001::/32"},
+					defaultRoute: newBool(false),
+				},
+			},
+			want: `	ipv6 {
+		import filter {
+			if ( net ~ [ 2001::/32+ ] ) then accept;
+			reject;
+		};
+		export filter announced_routes;
+		table network6_0;
+	};`,
+		},
+		{
+			name: "no import policy",
+			gateway: &gateway{
+				name:     "gateway-v4-a-1",
+				address:  "169.254.100.150",
+				protocol: v1alpha1.BGP,
+				bgp:      bgp,
+				intf:     "eth0",
+			},
+			want: `	ipv4 {
+		import filter gateway_routes;
+		export filter announced_routes;
+		table network4_0;
+	};`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBird(t)
+			b.ConfigFile = filepath.Join(t.TempDir(), "bird.conf")
+
+			if err := b.Configure(context.TODO(), []bird.VIP{}, []bird.Gateway{tt.gateway}); err != nil {
+				t.Fatalf("Bird.Configure() error = %v", err)
+			}
+
+			config, err := os.ReadFile(b.ConfigFile)
+			if err != nil {
+				t.Fatalf("error reading bird config file = %v", err)
+			}
+
+			if !strings.Contains(string(config), tt.want) {
+				t.Errorf("Bird.Configure() config = %v, want %v", string(config), tt.want)
+			}
+		})
+	}
+}
+
+func TestBird_ConfigureMultihop(t *testing.T) {
+	tests := []struct {
+		name    string
+		gateway *gateway
+		want    []string
+	}{
+		{
+			name: "multihop with source address and next hop",
+			gateway: &gateway{
+				name:     "gateway-v4-a-1",
+				address:  "169.254.200.150",
+				protocol: v1alpha1.BGP,
+				bgp: &bgpSpec{
+					holdTime:      "24s",
+					bfd:           bfd,
+					multihop:      newUint8(2),
+					sourceAddress: "127.0.0.1",
+					nextHop:       "169.254.100.1",
+				},
+				intf: "eth0",
+			},
+			want: []string{
+				"ipv4 table igp4;",
+				`protocol bgp 'gateway-v4-a-1' from BGP_TEMPLATE {
+	multihop 2;
+	local 127.0.0.1 port 10179 as 8103;
+	neighbor 169.254.200.150 port 10179 as 4248829953;`,
+				`	ipv4 {
+		import filter gateway_routes;
+		export filter announced_routes;
+		table network4_0;
+		igp table igp4;
+		next hop address 169.254.100.1;
+	};`,
+			},
+		},
+		{
+			name: "next hop of another family",
+			gateway: &gateway{
+				name:     "gateway-v6-a-1",
+				address:  "100:100::150",
+				protocol: v1alpha1.BGP,
+				bgp: &bgpSpec{
+					bfd:     bfd,
+					nextHop: "169.254.100.1",
+				},
+				intf: "eth0",
+			},
+			want: []string{
+				`protocol bgp 'gateway-v6-a-1' from BGP_TEMPLATE {
+	interface "eth0";
+	local port 10179 as 8103;`,
+				`	ipv6 {
+		import filter gateway_routes;
+		export filter announced_routes;
+		table network6_0;
+	};`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBird(t)
+			b.ConfigFile = filepath.Join(t.TempDir(), "bird.conf")
+
+			if err := b.Configure(context.TODO(), []bird.VIP{}, []bird.Gateway{tt.gateway}); err != nil {
+				t.Fatalf("Bird.Configure() error = %v", err)
+			}
+
+			t.Cleanup(func() {
+				if err := b.Configure(context.TODO(), []bird.VIP{}, []bird.Gateway{}); err != nil {
+					t.Errorf("Bird.Configure() cleanup error = %v", err)
+				}
+			})
+
+			config, err := os.ReadFile(b.ConfigFile)
+			if err != nil {
+				t.Fatalf("error reading bird config file = %v", err)
+			}
+
+			for _, want := range tt.want {
+				if !strings.Contains(string(config), want) {
+					t.Errorf("Bird.Configure() config = %v, want %v", string(config), want)
+				}
+			}
+		})
+	}
+}
+
+func TestBird_ConfigureExport(t *testing.T) {
+	vips := []bird.VIP{
+		&vip{cidr: "20.0.0.1/32", exportedTo: []string{"gateway-v4-a-1"}},
+		&vip{cidr: "20.0.0.2/32"},
+		&vip{cidr: "2000::1/128", exportedTo: []string{}},
+	}
+
+	tests := []struct {
+		name    string
+		gateway *gateway
+		want    string
+	}{
+		{
+			name: "all the VIPs exported",
+			gateway: &gateway{
+				name:     "gateway-v4-a-1",
+				address:  "169.254.100.150",
+				protocol: v1alpha1.BGP,
+				bgp:      bgp,
+				intf:     "eth0",
+			},
+			want: `	ipv4 {
+		import filter gateway_routes;
+		export filter announced_routes;
+		table network4_0;
+	};`,
+		},
+		{
+			name: "some VIPs exported",
+			gateway: &gateway{
+				name:     "gateway-v4-b-1",
+				address:  "169.254.101.150",
+				protocol: v1alpha1.BGP,
+				bgp:      bgp,
+				intf:     "eth1",
+			},
+			want: `	ipv4 {
+		import filter gateway_routes;
+		export filter {
+			if ( net !~ [ 20.0.0.2/32 ] ) then reject;
+			if ( net ~ [ 0.0.0.0/0 ] ) then reject;
+			if ( net ~ [ 0::/0 ] ) then reject;
+			if source = RTS_STATIC && dest != RTD_BLACKHOLE then accept;
+			else reject;
+		};
+		table network4_0;
+	};`,
+		},
+		{
+			name: "no VIP exported",
+			gateway: &gateway{
+				name:     "gateway-v6-a-1",
+				address:  "100:100::150",
+				protocol: v1alpha1.BGP,
+				bgp:      bgp,
+				intf:     "eth0",
+			},
+			want: `	ipv6 {
+		import filter gateway_routes;
+		export none;
+		table network6_0;
+	};`,
+		},
+		{
+			name: "OSPF stub networks",
+			gateway: &gateway{
+				name:     "gateway-v4-b-1",
+				address:  "169.254.101.150",
+				protocol: v1alpha1.OSPF,
+				ospf:     &ospfSpec{vipAdvertisement: v1alpha1.OspfStub},
+				intf:     "eth1",
+			},
+			want: `	area 0 {
+		stubnet 20.0.0.2/32;
+		interface "eth1" {`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBird(t)
+			b.ConfigFile = filepath.Join(t.TempDir(), "bird.conf")
+
+			if err := b.Configure(context.TODO(), vips, []bird.Gateway{tt.gateway}); err != nil {
+				t.Fatalf("Bird.Configure() error = %v", err)
+			}
+
+			t.Cleanup(func() {
+				if err := b.Configure(context.TODO(), []bird.VIP{}, []bird.Gateway{}); err != nil {
+					t.Errorf("Bird.Configure() cleanup error = %v", err)
+				}
+			})
+
+			config, err := os.ReadFile(b.ConfigFile)
+			if err != nil {
+				t.Fatalf("error reading bird config file = %v", err)
+			}
+
+			if !strings.Contains(string(config), tt.want) {
+				t.Errorf("Bird.Configure() config = %v, want %v", string(config), tt.want)
+			}
+		})
+	}
+}
+
+func TestBird_ConfigureOSPF(t *testing.T) {
+	tests := []struct {
+		name    string
+		vips    []string
+		gateway *gateway
+		want    []string
+	}{
+		{
+			name: "external VIPs with interface options",
+			vips: []string{"20.0.0.1/32", "2000::1/128"},
+			gateway: &gateway{
+				name:     "gateway-v4-a-1",
+				address:  "169.254.100.150",
+				protocol: v1alpha1.OSPF,
+				intf:     "eth0",
+				ospf: &ospfSpec{
+					cost:          newUint16(20),
+					helloInterval: "5s",
+					deadInterval:  "20s",
+					password:      "secret",
+					bfd:           bfd,
+				},
+			},
+			want: []string{
+				`protocol ospf v2 'gateway-v4-a-1' {
+	ipv4 {
+		import filter ospf_gateway_routes;
+		export filter announced_routes;
+		table network4_0;
+	};
+	area 0 {
+		interface "eth0" {
+			cost 20;
+			hello 5;
+			dead 20;
+			bfd on;
+			authentication cryptographic;
+			include "`,
+				`protocol bfd {
+	interface "eth0" {
+		min rx interval 300ms;
+		min tx interval 300ms;
+		multiplier 5;
+	};
+	interface "*" {`,
+			},
+		},
+		{
+			name: "stub VIPs with import policy",
+			vips: []string{"20.0.0.1/32", "2000::1/128"},
+			gateway: &gateway{
+				name:     "gateway-v6-a-1",
+				address:  "100:100::150",
+				protocol: v1alpha1.OSPF,
+				intf:     "eth0",
+				ospf: &ospfSpec{
+					area:             "0.0.0.10",
+					vipAdvertisement: v1alpha1.OspfStub,
+				},
+				imports: &importPolicy{
+					prefixes:     []string{"2001::/32"},
+					defaultRoute: newBool(false),
+				},
+			},
+			want: []string{
+				`protocol ospf v3 'gateway-v6-a-1' {
+	ipv6 {
+		import filter {
+			if ( net ~ [ 2001::/32+ ] ) then accept;
+			reject;
+		};
+		export none;
+		table network6_0;
+	};
+	area 0.0.0.10 {
+		stubnet 2000::1/128;
+		interface "eth0" {
+		};
+	};
+}`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBird(t)
+			b.ConfigFile = filepath.Join(t.TempDir(), "bird.conf")
+			b.PasswordDirectory = t.TempDir()
+
+			if err := b.Configure(context.TODO(), newVIPs(tt.vips...), []bird.Gateway{tt.gateway}); err != nil {
+				t.Fatalf("Bird.Configure() error = %v", err)
+			}
+
+			t.Cleanup(func() {
+				if err := b.Configure(context.TODO(), []bird.VIP{}, []bird.Gateway{}); err != nil {
+					t.Errorf("Bird.Configure() cleanup error = %v", err)
+				}
+			})
+
+			config, err := os.ReadFile(b.ConfigFile)
+			if err != nil {
+				t.Fatalf("error reading bird config file = %v", err)
+			}
+
+			for _, want := range tt.want {
+				if !strings.Contains(string(config), want) {
+					t.Errorf("Bird.Configure() config = %v, want %v", string(config), want)
+				}
+			}
+
+			if strings.Contains(string(config), "secret") {
+				t.Errorf("Bird.Configure() config contains the password: %v", string(config))
+			}
+		})
+	}
+}
+
+func TestBird_ConfigureRoutingInstances(t *testing.T) {
+	b := newBird(t)
+	b.ConfigFile = filepath.Join(t.TempDir(), "bird.conf")
+
+	gateways := []bird.Gateway{
+		&gateway{
+			name:     "gateway-v4-b-1",
+			address:  "169.254.200.150",
+			protocol: v1alpha1.BGP,
+			bgp:      bgp,
+			intf:     "net2",
+		},
+		&gateway{
+			name:     "gateway-v4-a-1",
+			address:  "169.254.100.150",
+			protocol: v1alpha1.BGP,
+			bgp:      bgp,
+			intf:     "net1",
+		},
+	}
+
+	if err := b.Configure(context.TODO(), newVIPs("20.0.0.1/32"), gateways); err != nil {
+		t.Fatalf("Bird.Configure() error = %v", err)
+	}
+
+	config, err := os.ReadFile(b.ConfigFile)
+	if err != nil {
+		t.Fatalf("error reading bird config file = %v", err)
+	}
+
+	for _, want := range []string{
+		`ipv4 table network4_0;
+
+protocol pipe NETWORK4_0 {
+	table master4;
+	peer table network4_0;
+	import filter gateway_routes;
+	export filter announced_routes;
+}
+
+protocol kernel NETWORK4_0_KERNEL {
+	ipv4 {
+		table network4_0;
+		import none;
+		export filter gateway_routes;
+	};
+	kernel table 4100;
+	merge paths on;
+}`,
+		"kernel table 4101;",
+		`protocol bgp 'gateway-v4-a-1' from BGP_TEMPLATE {
+	interface "net1";`,
+		`		export filter announced_routes;
+		table network4_0;
+	};`,
+		`		export filter announced_routes;
+		table network4_1;
+	};`,
+	} {
+		if !strings.Contains(string(config), want) {
+			t.Errorf("Bird.Configure() config = %v, want %v", string(config), want)
+		}
+	}
+
+	if got := getRoutingInstancePolicyRoutes(t); !reflect.DeepEqual(got, []string{
+		"20.0.0.1/32 iif net1 table 4100", "20.0.0.1/32 iif net2 table 4101",
+	}) {
+		t.Errorf("Bird.Configure() routing instance policy routes = %v", got)
+	}
+
+	if err := b.Configure(context.TODO(), []bird.VIP{}, []bird.Gateway{}); err != nil {
+		t.Fatalf("Bird.Configure() cleanup error = %v", err)
+	}
+
+	if got := getRoutingInstancePolicyRoutes(t); len(got) != 0 {
+		t.Errorf("Bird.Configure() routing instance policy routes not removed = %v", got)
+	}
+}
+
+func TestBird_ConfigureTooManyNetworks(t *testing.T) {
+	b := newBird(t)
+	b.ConfigFile = filepath.Join(t.TempDir(), "bird.conf")
+
+	gateways := []bird.Gateway{}
+
+	for i := range 101 {
+		gateways = append(gateways, &gateway{
+			name:     fmt.Sprintf("gateway-%d", i),
+			address:  fmt.Sprintf("169.254.%d.150", i),
+			protocol: v1alpha1.BGP,
+			bgp:      bgp,
+			intf:     fmt.Sprintf("net%d", i),
+		})
+	}
+
+	if err := b.Configure(context.TODO(), newVIPs("20.0.0.1/32"), gateways); err == nil {
+		t.Errorf("Bird.Configure() error = nil, want an error for the networks over the maximum")
+	}
+
+	if _, err := os.Stat(b.ConfigFile); err == nil {
+		t.Errorf("Bird.Configure() config written with the networks over the maximum")
+	}
+
+	if got := getRoutingInstancePolicyRoutes(t); len(got) != 0 {
+		t.Errorf("Bird.Configure() routing instance policy routes = %v, want none", got)
+	}
+}
+
+func getRoutingInstancePolicyRoutes(t *testing.T) []string {
+	t.Helper()
+
+	rules, err := netlink.RuleList(netlink.FAMILY_ALL)
+	if err != nil {
+		t.Fatalf("failed to list rules: %v", err)
+	}
+
+	policyRoutes := []string{}
+
+	for _, rule := range rules {
+		if rule.Table < 4100 || rule.Table >= 4200 {
+			continue
+		}
+
+		policyRoutes = append(policyRoutes, fmt.Sprintf("%s iif %s table %d", rule.Src, rule.IifName, rule.Table))
+	}
+
+	sort.Strings(policyRoutes)
+
+	return policyRoutes
+}
+
+func TestBird_ConfigureGracefulRestart(t *testing.T) {
+	tests := []struct {
+		name            string
+		gracefulRestart bool
+		gateway         *gateway
+		want            []string
+		notWant         []string
+	}{
+		{
+			name:            "graceful restart and long lived graceful restart",
+			gracefulRestart: true,
+			gateway: &gateway{
+				name:     "gateway-v4-a-1",
+				address:  "169.254.100.150",
+				protocol: v1alpha1.BGP,
+				bgp: &bgpSpec{
+					bfd: bfd,
+					gracefulRestart: &gracefulRestart{
+						sw:                 newBool(true),
+						restartTime:        "90s",
+						longLivedStaleTime: "1h",
+					},
+				},
+				intf: "eth0",
+			},
+			want: []string{
+				`	kernel table 4096;
+	merge paths on;
+	persist;`,
+				`	};
+	graceful restart on;
+	graceful restart time 90;
+	long lived graceful restart on;
+	long lived stale time 3600;`,
+			},
+		},
+		{
+			name: "graceful restart with default and out of range timers",
+			gateway: &gateway{
+				name:     "gateway-v4-a-1",
+				address:  "169.254.100.150",
+				protocol: v1alpha1.BGP,
+				bgp: &bgpSpec{
+					bfd: bfd,
+					gracefulRestart: &gracefulRestart{
+						sw:                 newBool(true),
+						restartTime:        "2h",
+						longLivedStaleTime: "invalid",
+					},
+				},
+				intf: "eth0",
+			},
+			want: []string{
+				`	graceful restart on;
+	graceful restart time 4095;
+	hold time`,
+			},
+			notWant: []string{"persist;", "long lived"},
+		},
+		{
+			name: "graceful restart off",
+			gateway: &gateway{
+				name:     "gateway-v4-a-1",
+				address:  "169.254.100.150",
+				protocol: v1alpha1.BGP,
+				bgp: &bgpSpec{
+					bfd: bfd,
+					gracefulRestart: &gracefulRestart{
+						sw:          newBool(false),
+						restartTime: "90s",
+					},
+				},
+				intf: "eth0",
+			},
+			notWant: []string{"graceful restart on;", "long lived"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBird(t)
+			b.ConfigFile = filepath.Join(t.TempDir(), "bird.conf")
+			b.GracefulRestart = tt.gracefulRestart
+
+			if err := b.Configure(context.TODO(), []bird.VIP{}, []bird.Gateway{tt.gateway}); err != nil {
+				t.Fatalf("Bird.Configure() error = %v", err)
+			}
+
+			config, err := os.ReadFile(b.ConfigFile)
+			if err != nil {
+				t.Fatalf("error reading bird config file = %v", err)
+			}
+
+			for _, want := range tt.want {
+				if !strings.Contains(string(config), want) {
+					t.Errorf("Bird.Configure() config = %v, want %v", string(config), want)
+				}
+			}
+
+			for _, notWant := range tt.notWant {
+				if strings.Contains(string(config), notWant) {
+					t.Errorf("Bird.Configure() config = %v, not want %v", string(config), notWant)
+				}
+			}
+		})
+	}
+}
+
+func TestBird_GetImportStatistics(t *testing.T) {
+	socketPath, _ := fakeBird(t, birdWelcome, func(string) string {
+		return showProtocolsAllReply
+	})
+
+	b := newBird(t)
+	b.SocketPath = socketPath
+
+	statistics, err := b.GetImportStatistics(context.TODO())
+	if err != nil {
+		t.Fatalf("Bird.GetImportStatistics() error = %v", err)
+	}
+
+	want := map[string]*bird.ImportStatistics{
+		"NBR-gateway-a": {Imported: 1, Rejected: 1},
+		"NBR-gateway-b": {Imported: 0, Rejected: 0},
+	}
+
+	if !reflect.DeepEqual(statistics, want) {
+		t.Errorf("Bird.GetImportStatistics() = %v, want %v", statistics, want)
+	}
+}
+
+func TestBird_ConfigureRunning(t *testing.T) {
+	invalid := false
+	configureFails := false
+
+	socketPath, commands := fakeBird(t, birdWelcome, func(command string) string {
+		switch {
+		case strings.HasPrefix(command, "configure check") && invalid:
+			return "8002 bird.conf:3:1 syntax error\n"
+		case strings.HasPrefix(command, "configure check"):
+			return "0020 Configuration OK\n"
+		case configureFails:
+			configureFails = false
+
+			return "8001 Reconfiguration failed\n"
+		default:
+			return "0003 Reconfigured\n"
+		}
+	})
+
+	b := newBird(t)
+	b.ConfigFile = filepath.Join(t.TempDir(), "bird.conf")
+	b.PasswordDirectory = filepath.Join(t.TempDir(), "passwords")
+	b.SocketPath = socketPath
+	b.SetRunning(true)
+
+	configure := func(vips []string, wantErr bool) {
+		t.Helper()
+
+		*commands = []string{}
+
+		err := b.Configure(context.TODO(), newVIPs(vips...), []bird.Gateway{})
+		if (err != nil) != wantErr {
+			t.Fatalf("Bird.Configure() error = %v, wantErr %v", err, wantErr)
+		}
+	}
+
+	checkConfig := func(want string, wantCommands ...string) {
+		t.Helper()
+
+		config, err := os.ReadFile(b.ConfigFile)
+		if err != nil || string(config) != want {
+			t.Errorf("Bird.Configure() config = %v (err: %v), want %v", string(config), err, want)
+		}
+
+		if len(*commands) != len(wantCommands) {
+			t.Fatalf("Bird.Configure() commands = %v, want %v", *commands, wantCommands)
+		}
+
+		for i, command := range *commands {
+			if !strings.HasPrefix(command, wantCommands[i]) {
+				t.Errorf("Bird.Configure() command = %v, want %v", command, wantCommands[i])
+			}
+		}
+
+		entries, _ := os.ReadDir(filepath.Dir(b.ConfigFile))
+		if len(entries) != 1 {
+			t.Errorf("Bird.Configure() temporary files not removed: %v", entries)
+		}
+	}
+
+	// applied after being checked
+	configure([]string{"20.0.0.1/32", "40.0.0.150/32", "2000::1/128", "4000::150/128"}, false)
+	checkConfig(ipv4AndIPv6VIPsConfig, "configure check", "configure \""+b.ConfigFile)
+
+	// unchanged
+	configure([]string{"20.0.0.1/32", "40.0.0.150/32", "2000::1/128", "4000::150/128"}, false)
+	checkConfig(ipv4AndIPv6VIPsConfig)
+
+	// rejected by the check: the last valid configuration is kept
+	invalid = true
+
+	configure([]string{"20.0.0.1/32"}, true)
+	checkConfig(ipv4AndIPv6VIPsConfig, "configure check", "configure \""+b.ConfigFile)
+
+	// rejected while being applied: the last valid configuration is restored
+	invalid = false
+	configureFails = true
+
+	configure([]string{"2000::1/128"}, true)
+	checkConfig(ipv4AndIPv6VIPsConfig, "configure check", "configure \""+b.ConfigFile, "configure \""+b.ConfigFile)
+
+	// not running: the configuration is only written once parsed by bird
+	b.SetRunning(false)
+	configure([]string{}, false)
+	checkConfig(emptyConfig)
+
+	// not running and rejected by bird: the last valid configuration is kept
+	b.Binary = fakeBirdBinary(t, "echo 'bird.conf:3:1 syntax error'; exit 1")
+	configure([]string{"20.0.0.1/32"}, true)
+	checkConfig(emptyConfig)
+
+	vips, err := getPolicyRoutes()
+	if err != nil || len(vips) != 0 {
+		t.Errorf("Bird.Configure() policy routes = %v (err: %v)", vips, err)
+	}
+}
+
+func TestBird_RunRestart(t *testing.T) {
+	dir := t.TempDir()
+	runs := filepath.Join(dir, "runs")
+
+	// fake bird accepting every configuration in parse-only mode, saving the configuration
+	// it has been started with and exiting immediately.
+	binary := filepath.Join(dir, "bird")
+
+	err := os.WriteFile(binary, []byte("#!/bin/sh\n[ \"$1\" = -p ] && exit 0\ncat \"$3\" >> "+runs+"\necho '#' >> "+runs+"\nexit 1\n"), 0o700)
+	if err != nil {
+		t.Fatalf("failed to write the fake bird: %v", err)
+	}
+
+	b := bird.New()
+	b.Binary = binary
+	b.ConfigFile = filepath.Join(t.TempDir(), "bird.conf")
+	b.PasswordDirectory = filepath.Join(t.TempDir(), "passwords")
+	b.SocketPath = filepath.Join(dir, "bird.ctl")
+
+	err = b.Configure(context.TODO(), newVIPs("20.0.0.1/32", "40.0.0.150/32", "2000::1/128", "4000::150/128"), nil)
+	if err != nil {
+		t.Fatalf("Bird.Configure() error = %v", err)
+	}
+
+	// the configuration is replayed even if the configuration file has been lost.
+	_ = os.Remove(b.ConfigFile)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+
+	go func() {
+		done <- b.Run(ctx)
+	}()
+
+	var configs []string
+
+	for range 50 {
+		content, _ := os.ReadFile(runs)
+
+		configs = strings.Split(string(content), "#\n")
+		if len(configs) > 2 {
+			break
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	cancel()
+
+	if err := <-done; err != nil {
+		t.Errorf("Bird.Run() error = %v", err)
+	}
+
+	if len(configs) < 3 {
+		t.Fatalf("Bird.Run() bird has not been restarted: %v", configs)
+	}
+
+	for _, config := range configs[:2] {
+		if config != ipv4AndIPv6VIPsConfig {
+			t.Errorf("Bird.Run() config = %v, want %v", config, ipv4AndIPv6VIPsConfig)
+		}
+	}
+
+	if err := b.Healthz(nil); err != nil {
+		t.Errorf("Bird.Healthz() error = %v", err)
+	}
+
+	if err := b.Readyz(httptest.NewRequest(http.MethodGet, "/readyz", nil)); err == nil {
+		t.Errorf("Bird.Readyz() error = nil while bird is not running")
+	}
+
+	err = b.Configure(context.TODO(), newVIPs(), nil)
+	if err != nil {
+		t.Fatalf("Bird.Configure() error = %v", err)
+	}
+}
+
+// newBird returns a bird using a fake bird binary accepting every configuration.
+func newBird(t *testing.T) *bird.Bird {
+	t.Helper()
+
+	b := bird.New()
+	b.Binary = fakeBirdBinary(t, "exit 0")
+
+	return b
+}
+
+// fakeBirdBinary writes a fake bird binary running the shell script in parameter.
+func fakeBirdBinary(t *testing.T, script string) string {
+	t.Helper()
+
+	binary := filepath.Join(t.TempDir(), "bird")
+
+	err := os.WriteFile(binary, []byte("#!/bin/sh\n"+script+"\n"), 0o700)
+	if err != nil {
+		t.Fatalf("failed to write the fake bird: %v", err)
+	}
+
+	return binary
+}
+
+func bgpProtocolConfig(
+	name string,
+	local string,
+	neighbor string,
+	bfd string,
+	holdTime int,
+	keepaliveTime int,
+	ipFamily string,
+) string {
+	keepalive := ""
+	if holdTime != 0 {
+		keepalive = fmt.Sprintf("\n\tkeepalive time %d;", keepaliveTime)
+	}
+
+	return fmt.Sprintf(`protocol bgp '%s' from BGP_TEMPLATE {
+	interface "eth0";
+	local port %s;
+	neighbor %s;
+	%s
+	hold time %d;%s
+	%s {
+		import filter gateway_routes;
+		export filter announced_routes;
+		table network%s_0;
+	};
+}`, name, local, neighbor, bfd, holdTime, keepalive, ipFamily, ipFamily[len(ipFamily)-1:])
+}
+
 func getPolicyRoutes() ([]string, error) {
 	rules, err := netlink.RuleListFiltered(netlink.FAMILY_ALL, &netlink.Rule{
 		Table: 4096,
@@ -156,6 +1439,50 @@ func getPolicyRoutes() ([]string, error) {
 	return vips, nil
 }
 
+// networkConfig is the routing instance of the gateways reachable over eth0.
+var networkConfig = `ipv4 table network4_0;
+
+protocol pipe NETWORK4_0 {
+	table master4;
+	peer table network4_0;
+	import filter gateway_routes;
+	export filter announced_routes;
+}
+
+protocol kernel NETWORK4_0_KERNEL {
+	ipv4 {
+		table network4_0;
+		import none;
+		export filter gateway_routes;
+	};
+	kernel table 4100;
+	merge paths on;
+}
+
+ipv6 table network6_0;
+
+protocol pipe NETWORK6_0 {
+	table master6;
+	peer table network6_0;
+	import filter gateway_routes;
+	export filter announced_routes;
+}
+
+protocol kernel NETWORK6_0_KERNEL {
+	ipv6 {
+		table network6_0;
+		import none;
+		export filter gateway_routes;
+	};
+	kernel table 4100;
+	merge paths on;
+}`
+
+var bfdProtocolConfig = `protocol bfd {
+	interface "*" {
+	};
+}`
+
 var emptyConfig = `log "/var/log/bird.log" 20000 "/var/log/bird.log.backup" { debug, trace, info, remote, warning, error, auth, fatal, bug };
 log stderr all;
 
@@ -166,6 +1493,13 @@ filter gateway_routes {
 	if ( net ~ [ 0.0.0.0/0 ] ) then accept;
 	if ( net ~ [ 0::/0 ] ) then accept;
 	if source = RTS_BGP then accept;
+	if source ~ [ RTS_OSPF, RTS_OSPF_IA, RTS_OSPF_EXT1, RTS_OSPF_EXT2 ] then accept;
+	else reject;
+}
+
+filter ospf_gateway_routes {
+	if ( net ~ [ 0.0.0.0/0 ] ) then accept;
+	if ( net ~ [ 0::/0 ] ) then accept;
 	else reject;
 }
 
@@ -266,6 +1600,13 @@ filter gateway_routes {
 	if ( net ~ [ 0.0.0.0/0 ] ) then accept;
 	if ( net ~ [ 0::/0 ] ) then accept;
 	if source = RTS_BGP then accept;
+	if source ~ [ RTS_OSPF, RTS_OSPF_IA, RTS_OSPF_EXT1, RTS_OSPF_EXT2 ] then accept;
+	else reject;
+}
+
+filter ospf_gateway_routes {
+	if ( net ~ [ 0.0.0.0/0 ] ) then accept;
+	if ( net ~ [ 0::/0 ] ) then accept;
 	else reject;
 }
 
@@ -378,6 +1719,13 @@ filter gateway_routes {
 	if ( net ~ [ 0.0.0.0/0 ] ) then accept;
 	if ( net ~ [ 0::/0 ] ) then accept;
 	if source = RTS_BGP then accept;
+	if source ~ [ RTS_OSPF, RTS_OSPF_IA, RTS_OSPF_EXT1, RTS_OSPF_EXT2 ] then accept;
+	else reject;
+}
+
+filter ospf_gateway_routes {
+	if ( net ~ [ 0.0.0.0/0 ] ) then accept;
+	if ( net ~ [ 0::/0 ] ) then accept;
 	else reject;
 }
 
@@ -463,6 +1811,8 @@ protocol static DROP6 {
 	};
 }
 
+` + networkConfig + `
+
 protocol bgp 'gateway-v4-a-1' from BGP_TEMPLATE {
 	interface "eth0";
 	local port 10179 as 8103;
@@ -472,10 +1822,12 @@ protocol bgp 'gateway-v4-a-1' from BGP_TEMPLATE {
 		min tx interval 300ms;
 		multiplier 5;
 	};
-	hold time 3;
+	hold time 24;
+	keepalive time 8;
 	ipv4 {
 		import filter gateway_routes;
 		export filter announced_routes;
+		table network4_0;
 	};
 }
 
@@ -488,10 +1840,12 @@ protocol bgp 'gateway-v6-a-1' from BGP_TEMPLATE {
 		min tx interval 300ms;
 		multiplier 5;
 	};
-	hold time 3;
+	hold time 24;
+	keepalive time 8;
 	ipv6 {
 		import filter gateway_routes;
 		export filter announced_routes;
+		table network6_0;
 	};
 }
 
@@ -500,6 +1854,66 @@ protocol bfd {
 	};
 }`
 
+type vip struct {
+	cidr          string
+	bgpAttributes *bgpAttributes
+	exportedTo    []string
+}
+
+func newVIPs(cidrs ...string) []bird.VIP {
+	vips := []bird.VIP{}
+
+	for _, cidr := range cidrs {
+		vips = append(vips, &vip{cidr: cidr})
+	}
+
+	return vips
+}
+
+func (v *vip) GetCIDR() string {
+	return v.cidr
+}
+
+func (v *vip) GetBgpAttributes() bird.BgpAttributes {
+	if v.bgpAttributes == nil {
+		return nil
+	}
+
+	return v.bgpAttributes
+}
+
+func (v *vip) GetExportedTo() []string {
+	return v.exportedTo
+}
+
+type bgpAttributes struct {
+	communities      []string
+	largeCommunities []string
+	med              *uint32
+	localPreference  *uint32
+	asPathPrepend    *uint32
+}
+
+func (ba *bgpAttributes) GetCommunities() []string {
+	return ba.communities
+}
+
+func (ba *bgpAttributes) GetLargeCommunities() []string {
+	return ba.largeCommunities
+}
+
+func (ba *bgpAttributes) GetMED() *uint32 {
+	return ba.med
+}
+
+func (ba *bgpAttributes) GetLocalPreference() *uint32 {
+	return ba.localPreference
+}
+
+func (ba *bgpAttributes) GetASPathPrepend() *uint32 {
+	return ba.asPathPrepend
+}
+
 type gateway struct {
 	name     string
 	address  string
@@ -507,6 +1921,8 @@ type gateway struct {
 	protocol v1alpha1.RoutingProtocol
 	bgp      *bgpSpec
 	static   *staticSpec
+	ospf     *ospfSpec
+	imports  *importPolicy
 }
 
 func (gw *gateway) GetName() string {
@@ -533,13 +1949,48 @@ func (gw *gateway) GetStatic() bird.StaticSpec {
 	return gw.static
 }
 
+func (gw *gateway) GetOspfSpec() bird.OspfSpec {
+	return gw.ospf
+}
+
+func (gw *gateway) GetImportPolicy() bird.ImportPolicy {
+	if gw.imports == nil {
+		return nil
+	}
+
+	return gw.imports
+}
+
+type importPolicy struct {
+	prefixes     []string
+	defaultRoute *bool
+	maxPrefixes  *uint32
+}
+
+func (ip *importPolicy) GetPrefixes() []string {
+	return ip.prefixes
+}
+
+func (ip *importPolicy) GetDefaultRoute() *bool {
+	return ip.defaultRoute
+}
+
+func (ip *importPolicy) GetMaxPrefixes() *uint32 {
+	return ip.maxPrefixes
+}
+
 type bgpSpec struct {
-	remoteASN  *uint32
-	localASN   *uint32
-	bfd        *bfdSpec
-	holdTime   string
-	remotePort *uint16
-	localPort  *uint16
+	remoteASN       *uint32
+	localASN        *uint32
+	bfd             *bfdSpec
+	holdTime        string
+	remotePort      *uint16
+	localPort       *uint16
+	password        string
+	multihop        *uint8
+	sourceAddress   string
+	nextHop         string
+	gracefulRestart *gracefulRestart
 }
 
 func (bgps *bgpSpec) GetRemoteASN() *uint32 {
@@ -566,6 +2017,90 @@ func (bgps *bgpSpec) GetLocalPort() *uint16 {
 	return bgps.localPort
 }
 
+func (bgps *bgpSpec) GetPassword() string {
+	return bgps.password
+}
+
+func (bgps *bgpSpec) GetMultihop() *uint8 {
+	return bgps.multihop
+}
+
+func (bgps *bgpSpec) GetSourceAddress() string {
+	return bgps.sourceAddress
+}
+
+func (bgps *bgpSpec) GetNextHop() string {
+	return bgps.nextHop
+}
+
+func (bgps *bgpSpec) GetGracefulRestart() bird.GracefulRestartSpec {
+	if bgps.gracefulRestart == nil {
+		return nil
+	}
+
+	return bgps.gracefulRestart
+}
+
+type gracefulRestart struct {
+	sw                 *bool
+	restartTime        string
+	longLivedStaleTime string
+}
+
+func (gr *gracefulRestart) GetSwitch() *bool {
+	return gr.sw
+}
+
+func (gr *gracefulRestart) GetRestartTime() string {
+	return gr.restartTime
+}
+
+func (gr *gracefulRestart) GetLongLivedStaleTime() string {
+	return gr.longLivedStaleTime
+}
+
+type ospfSpec struct {
+	area             string
+	cost             *uint16
+	helloInterval    string
+	deadInterval     string
+	password         string
+	bfd              *bfdSpec
+	vipAdvertisement v1alpha1.OspfVIPAdvertisement
+}
+
+func (ospfs *ospfSpec) GetArea() string {
+	return ospfs.area
+}
+
+func (ospfs *ospfSpec) GetCost() *uint16 {
+	return ospfs.cost
+}
+
+func (ospfs *ospfSpec) GetHelloInterval() string {
+	return ospfs.helloInterval
+}
+
+func (ospfs *ospfSpec) GetDeadInterval() string {
+	return ospfs.deadInterval
+}
+
+func (ospfs *ospfSpec) GetPassword() string {
+	return ospfs.password
+}
+
+func (ospfs *ospfSpec) GetBfdSpec() bird.BfdSpec {
+	if ospfs.bfd == nil {
+		return nil
+	}
+
+	return ospfs.bfd
+}
+
+func (ospfs *ospfSpec) GetVIPAdvertisement() v1alpha1.OspfVIPAdvertisement {
+	return ospfs.vipAdvertisement
+}
+
 type staticSpec struct {
 	bfd *bfdSpec
 }
@@ -601,6 +2136,10 @@ func newBool(val bool) *bool {
 	return &val
 }
 
+func newUint8(val uint8) *uint8 {
+	return &val
+}
+
 func newUint16(val uint16) *uint16 {
 	return &val
 }
@@ -0,0 +1,240 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bird
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// replyCodeWelcome is the code of the message sent by bird when a client connects.
+	replyCodeWelcome = 1
+	// replyCodeConfigurationOK is the code returned by a successful "configure check".
+	replyCodeConfigurationOK = 20
+	// replyCodeRuntimeError is the first code representing an error (8xxx: runtime error, 9xxx: parse error).
+	replyCodeRuntimeError = 8000
+	replyCodeLength       = 4
+)
+
+var (
+	errUnexpectedWelcome = errors.New("unexpected bird welcome message")
+	errMalformedReply    = errors.New("malformed bird reply")
+	errCommandFailed     = errors.New("bird command failed")
+)
+
+// Client communicates with bird over its control socket (the protocol used by birdc).
+//
+// Each command opens a new connection to the control socket, so the client can be
+// shared between goroutines.
+type Client struct {
+	// SocketPath is the full path with filename of the bird control socket.
+	SocketPath string
+}
+
+// NewClient is the constructor of Client.
+func NewClient(socketPath string) *Client {
+	return &Client{
+		SocketPath: socketPath,
+	}
+}
+
+// Reply represents a reply of bird to a command.
+type Reply struct {
+	// Code of the last line of the reply. Codes starting with 0 are
+	// successful replies, 8xxx are runtime errors and 9xxx parse errors.
+	Code int
+	// Lines of the reply. The continuation lines get the code of the line
+	// they are continuing.
+	Lines []ReplyLine
+}
+
+// ReplyLine represents a line of a reply of bird.
+type ReplyLine struct {
+	Code int
+	Text string
+}
+
+// Message returns the text of the lines having the code of the reply.
+func (r *Reply) Message() string {
+	lines := []string{}
+
+	for _, line := range r.Lines {
+		if line.Code == r.Code {
+			lines = append(lines, strings.TrimSpace(line.Text))
+		}
+	}
+
+	return strings.Join(lines, "; ")
+}
+
+// Command sends a command to bird over the control socket and returns its reply.
+// An error is returned if bird replies with a runtime or a parse error.
+func (c *Client) Command(ctx context.Context, command string) (*Reply, error) {
+	var dialer net.Dialer
+
+	conn, err := dialer.DialContext(ctx, "unix", c.SocketPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the bird socket %s: %w", c.SocketPath, err)
+	}
+
+	defer conn.Close()
+
+	// Unblock the reads and writes once the context is done.
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetDeadline(time.Now())
+	})
+
+	defer stop()
+
+	reader := bufio.NewReader(conn)
+
+	welcome, err := readReply(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the bird welcome message: %w", err)
+	}
+
+	if welcome.Code != replyCodeWelcome {
+		return nil, fmt.Errorf("%w: %s", errUnexpectedWelcome, welcome.Message())
+	}
+
+	_, err = conn.Write([]byte(command + "\n"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to send the bird command %q: %w", command, err)
+	}
+
+	reply, err := readReply(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the reply of the bird command %q: %w", command, err)
+	}
+
+	if reply.Code >= replyCodeRuntimeError {
+		return reply, fmt.Errorf("%w: %q: %d %s", errCommandFailed, command, reply.Code, reply.Message())
+	}
+
+	return reply, nil
+}
+
+// ConfigureCheck validates the configuration file without applying it ("configure check").
+func (c *Client) ConfigureCheck(ctx context.Context, configFile string) error {
+	reply, err := c.Command(ctx, fmt.Sprintf("configure check \"%s\"", configFile))
+	if err != nil {
+		return err
+	}
+
+	if reply.Code != replyCodeConfigurationOK {
+		return fmt.Errorf("%w: configure check: %d %s", errCommandFailed, reply.Code, reply.Message())
+	}
+
+	return nil
+}
+
+// Configure applies the configuration file ("configure").
+func (c *Client) Configure(ctx context.Context, configFile string) error {
+	_, err := c.Command(ctx, fmt.Sprintf("configure \"%s\"", configFile))
+
+	return err
+}
+
+// EnableProtocol enables a protocol ("enable <protocol>").
+// No error is returned if the protocol is already enabled.
+func (c *Client) EnableProtocol(ctx context.Context, name string) error {
+	_, err := c.Command(ctx, fmt.Sprintf("enable \"%s\"", name))
+
+	return err
+}
+
+// DisableProtocol disables a protocol ("disable <protocol>").
+// No error is returned if the protocol is already disabled.
+func (c *Client) DisableProtocol(ctx context.Context, name string) error {
+	_, err := c.Command(ctx, fmt.Sprintf("disable \"%s\"", name))
+
+	return err
+}
+
+// ShowProtocols returns the protocols with their details ("show protocols all").
+func (c *Client) ShowProtocols(ctx context.Context) ([]*Protocol, error) {
+	reply, err := c.Command(ctx, "show protocols all")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseProtocols(reply), nil
+}
+
+// ShowRouteExport returns the routes exported by a protocol ("show route export <protocol>").
+func (c *Client) ShowRouteExport(ctx context.Context, protocol string) ([]*Route, error) {
+	reply, err := c.Command(ctx, fmt.Sprintf("show route export '%s'", protocol))
+	if err != nil {
+		return nil, err
+	}
+
+	return parseRoutes(reply), nil
+}
+
+// readReply reads the lines sent by bird until the last line of the reply
+// ("<code> <text>"). Other lines are either "<code>-<text>" or continuation
+// lines starting with a space. Asynchronous messages ("+<text>") are ignored.
+func readReply(reader *bufio.Reader) (*Reply, error) {
+	reply := &Reply{
+		Lines: []ReplyLine{},
+	}
+
+	lastCode := 0
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("failed to read: %w", err)
+		}
+
+		line = strings.TrimSuffix(line, "\n")
+
+		switch {
+		case strings.HasPrefix(line, "+"):
+			continue
+		case strings.HasPrefix(line, " "):
+			reply.Lines = append(reply.Lines, ReplyLine{Code: lastCode, Text: line[1:]})
+
+			continue
+		}
+
+		if len(line) <= replyCodeLength || (line[replyCodeLength] != ' ' && line[replyCodeLength] != '-') {
+			return nil, fmt.Errorf("%w: %q", errMalformedReply, line)
+		}
+
+		code, err := strconv.Atoi(line[:replyCodeLength])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", errMalformedReply, line)
+		}
+
+		lastCode = code
+		reply.Lines = append(reply.Lines, ReplyLine{Code: code, Text: line[replyCodeLength+1:]})
+
+		if line[replyCodeLength] == ' ' {
+			reply.Code = code
+
+			return reply, nil
+		}
+	}
+}
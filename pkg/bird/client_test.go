@@ -0,0 +1,368 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bird_test
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/bird"
+)
+
+const birdWelcome = "0001 BIRD 2.0.12 ready.\n"
+
+const showProtocolsAllReply = `2002-Name       Proto      Table      State  Since         Info
+1002-device1    Device     ---        up     12:28:38.373  
+1006-
+1002-BFD        BFD        ---        up     12:28:38.373  
+1006-
+1002-NBR-gateway-a BGP        ---        up     12:28:42.012  Established   
+1006-  BGP state:          Established
+    Neighbor address: 169.254.100.150%eth0
+    Neighbor AS:      4248829953
+    Local AS:         8103
+    Hold timer:       21.437/24
+    Keepalive timer:  2.301/8
+  Channel ipv4
+    State:          UP
+    Table:          master4
+    Preference:     100
+    Input filter:   default_rt
+    Output filter:  cluster_e_static
+    Routes:         1 imported, 0 filtered, 2 exported, 1 preferred
+    Route change stats:     received   rejected   filtered    ignored   accepted
+      Import updates:              3          1          0          0          2
+      Import withdraws:            0          0        ---          0          0
+      Export updates:              2          0          0        ---          2
+      Export withdraws:            0        ---        ---        ---          0
+    BGP Next hop:   169.254.100.1
+1006-
+1002-NBR-gateway-b BGP        ---        start  2024-06-10 12:28:42  Active        Socket: Connection refused
+1006-  BGP state:          Active
+    Neighbor address: 169.254.100.254%eth0
+    Neighbor AS:      4248829953
+    Local AS:         8103
+    Last error:       Socket: Connection refused
+0000 
+`
+
+const showRouteExportReply = `1007-Table master4:
+1007-20.0.0.1/32          unicast [VIP4 12:28:38.373] * (110)
+1007-	dev lo
+1007-0.0.0.0/0            unicast [NBR-gateway-a 12:28:42.012] * (100) [AS4248829953i]
+1007-	via 169.254.100.150 on eth0
+1007-	via 169.254.100.254 on eth0
+1007-Table master6:
+1007-2000::1/128          unicast [VIP6 12:28:38.373] * (110)
+1007-	dev lo
+0000 
+`
+
+// fakeBird serves the control socket of bird. reply is called for each command
+// received and returns the raw reply sent back to the client.
+func fakeBird(t *testing.T, welcome string, reply func(command string) string) (string, *[]string) {
+	t.Helper()
+
+	socketPath := filepath.Join(t.TempDir(), "bird.ctl")
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("failed to listen on %s: %v", socketPath, err)
+	}
+
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+
+	commands := []string{}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			_, _ = conn.Write([]byte(welcome))
+
+			command, err := bufio.NewReader(conn).ReadString('\n')
+			if err == nil {
+				command = strings.TrimSuffix(command, "\n")
+				commands = append(commands, command)
+				_, _ = conn.Write([]byte(reply(command)))
+			}
+
+			_ = conn.Close()
+		}
+	}()
+
+	return socketPath, &commands
+}
+
+func TestClient_Command(t *testing.T) {
+	tests := []struct {
+		name        string
+		welcome     string
+		command     func(ctx context.Context, client *bird.Client) error
+		reply       string
+		wantCommand string
+		wantErr     bool
+	}{
+		{
+			name:    "configure",
+			welcome: birdWelcome,
+			command: func(ctx context.Context, client *bird.Client) error {
+				return client.Configure(ctx, "/etc/bird/bird.conf")
+			},
+			reply:       "0002-Reading configuration from /etc/bird/bird.conf\n0003 Reconfigured\n",
+			wantCommand: `configure "/etc/bird/bird.conf"`,
+			wantErr:     false,
+		},
+		{
+			name:    "configure parse error",
+			welcome: birdWelcome,
+			command: func(ctx context.Context, client *bird.Client) error {
+				return client.Configure(ctx, "/etc/bird/bird.conf")
+			},
+			reply:       "0002-Reading configuration from /etc/bird/bird.conf\n8002 /etc/bird/bird.conf:3:1 syntax error\n",
+			wantCommand: `configure "/etc/bird/bird.conf"`,
+			wantErr:     true,
+		},
+		{
+			name:    "configure check",
+			welcome: birdWelcome,
+			command: func(ctx context.Context, client *bird.Client) error {
+				return client.ConfigureCheck(ctx, "/etc/bird/bird.conf")
+			},
+			reply:       "0002-Reading configuration from /etc/bird/bird.conf\n0020 Configuration OK\n",
+			wantCommand: `configure check "/etc/bird/bird.conf"`,
+			wantErr:     false,
+		},
+		{
+			name:    "configure check without configuration ok",
+			welcome: birdWelcome,
+			command: func(ctx context.Context, client *bird.Client) error {
+				return client.ConfigureCheck(ctx, "/etc/bird/bird.conf")
+			},
+			reply:       "0002 Reading configuration from /etc/bird/bird.conf\n",
+			wantCommand: `configure check "/etc/bird/bird.conf"`,
+			wantErr:     true,
+		},
+		{
+			name:    "enable protocol",
+			welcome: birdWelcome,
+			command: func(ctx context.Context, client *bird.Client) error {
+				return client.EnableProtocol(ctx, "NBR-gateway-a")
+			},
+			reply:       "0011 NBR-gateway-a: enabled\n",
+			wantCommand: `enable "NBR-gateway-a"`,
+			wantErr:     false,
+		},
+		{
+			name:    "disable unknown protocol",
+			welcome: birdWelcome,
+			command: func(ctx context.Context, client *bird.Client) error {
+				return client.DisableProtocol(ctx, "NBR-gateway-c")
+			},
+			reply:       "8003 NBR-gateway-c: No such protocol\n",
+			wantCommand: `disable "NBR-gateway-c"`,
+			wantErr:     true,
+		},
+		{
+			name:    "asynchronous message ignored",
+			welcome: birdWelcome,
+			command: func(ctx context.Context, client *bird.Client) error {
+				return client.EnableProtocol(ctx, "NBR-gateway-a")
+			},
+			reply:       "+NBR-gateway-a: state changed\n0011 NBR-gateway-a: enabled\n",
+			wantCommand: `enable "NBR-gateway-a"`,
+			wantErr:     false,
+		},
+		{
+			name:    "malformed reply",
+			welcome: birdWelcome,
+			command: func(ctx context.Context, client *bird.Client) error {
+				return client.EnableProtocol(ctx, "NBR-gateway-a")
+			},
+			reply:       "enabled\n",
+			wantCommand: `enable "NBR-gateway-a"`,
+			wantErr:     true,
+		},
+		{
+			name:    "unexpected welcome",
+			welcome: "0013 Shutdown requested\n",
+			command: func(ctx context.Context, client *bird.Client) error {
+				return client.EnableProtocol(ctx, "NBR-gateway-a")
+			},
+			reply:       "0011 NBR-gateway-a: enabled\n",
+			wantCommand: "",
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			socketPath, commands := fakeBird(t, tt.welcome, func(string) string {
+				return tt.reply
+			})
+
+			err := tt.command(context.TODO(), bird.NewClient(socketPath))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Command() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantCommand != "" && (len(*commands) != 1 || (*commands)[0] != tt.wantCommand) {
+				t.Errorf("Command() sent %v, want %q", *commands, tt.wantCommand)
+			}
+		})
+	}
+}
+
+func TestClient_CommandNoSocket(t *testing.T) {
+	client := bird.NewClient(filepath.Join(t.TempDir(), "bird.ctl"))
+
+	_, err := client.Command(context.TODO(), "show status")
+	if err == nil {
+		t.Errorf("Command() expected an error when bird is not running")
+	}
+}
+
+func TestClient_ShowProtocols(t *testing.T) {
+	socketPath, _ := fakeBird(t, birdWelcome, func(string) string {
+		return showProtocolsAllReply
+	})
+
+	protocols, err := bird.NewClient(socketPath).ShowProtocols(context.TODO())
+	if err != nil {
+		t.Fatalf("ShowProtocols() error = %v", err)
+	}
+
+	want := []*bird.Protocol{
+		{Name: "device1", Proto: "Device", Table: "---", State: "up", Since: "12:28:38.373"},
+		{Name: "BFD", Proto: "BFD", Table: "---", State: "up", Since: "12:28:38.373"},
+		{
+			Name:            "NBR-gateway-a",
+			Proto:           "BGP",
+			Table:           "---",
+			State:           "up",
+			Since:           "12:28:42.012",
+			Info:            "Established",
+			BGPState:        "Established",
+			NeighborAddress: "169.254.100.150%eth0",
+			NeighborAS:      4248829953,
+			LocalAS:         8103,
+			Channels: []*bird.Channel{
+				{
+					Name:            "ipv4",
+					State:           "UP",
+					Table:           "master4",
+					InputFilter:     "default_rt",
+					OutputFilter:    "cluster_e_static",
+					ImportedRoutes:  1,
+					FilteredRoutes:  0,
+					ExportedRoutes:  2,
+					PreferredRoutes: 1,
+					ImportUpdates:   bird.RouteChangeStats{Received: 3, Rejected: 1, Accepted: 2},
+					ImportWithdraws: bird.RouteChangeStats{},
+					ExportUpdates:   bird.RouteChangeStats{Received: 2, Accepted: 2},
+					ExportWithdraws: bird.RouteChangeStats{},
+				},
+			},
+		},
+		{
+			Name:            "NBR-gateway-b",
+			Proto:           "BGP",
+			Table:           "---",
+			State:           "start",
+			Since:           "2024-06-10 12:28:42",
+			Info:            "Active Socket: Connection refused",
+			BGPState:        "Active",
+			NeighborAddress: "169.254.100.254%eth0",
+			NeighborAS:      4248829953,
+			LocalAS:         8103,
+			LastError:       "Socket: Connection refused",
+		},
+	}
+
+	if !reflect.DeepEqual(protocols, want) {
+		for i := range protocols {
+			t.Logf("got %+v", protocols[i])
+		}
+
+		t.Errorf("ShowProtocols() = %v, want %v", protocols, want)
+	}
+}
+
+func TestClient_ShowRouteExport(t *testing.T) {
+	socketPath, commands := fakeBird(t, birdWelcome, func(string) string {
+		return showRouteExportReply
+	})
+
+	routes, err := bird.NewClient(socketPath).ShowRouteExport(context.TODO(), "NBR-gateway-a")
+	if err != nil {
+		t.Fatalf("ShowRouteExport() error = %v", err)
+	}
+
+	if len(*commands) != 1 || (*commands)[0] != "show route export 'NBR-gateway-a'" {
+		t.Errorf("ShowRouteExport() sent %v", *commands)
+	}
+
+	want := []*bird.Route{
+		{
+			Table:      "master4",
+			Prefix:     "20.0.0.1/32",
+			Type:       "unicast",
+			Protocol:   "VIP4",
+			Since:      "12:28:38.373",
+			Primary:    true,
+			Preference: 110,
+			NextHops:   []string{"dev lo"},
+		},
+		{
+			Table:      "master4",
+			Prefix:     "0.0.0.0/0",
+			Type:       "unicast",
+			Protocol:   "NBR-gateway-a",
+			Since:      "12:28:42.012",
+			Primary:    true,
+			Preference: 100,
+			NextHops:   []string{"via 169.254.100.150 on eth0", "via 169.254.100.254 on eth0"},
+		},
+		{
+			Table:      "master6",
+			Prefix:     "2000::1/128",
+			Type:       "unicast",
+			Protocol:   "VIP6",
+			Since:      "12:28:38.373",
+			Primary:    true,
+			Preference: 110,
+			NextHops:   []string{"dev lo"},
+		},
+	}
+
+	if !reflect.DeepEqual(routes, want) {
+		for i := range routes {
+			t.Logf("got %+v", routes[i])
+		}
+
+		t.Errorf("ShowRouteExport() = %v, want %v", routes, want)
+	}
+}
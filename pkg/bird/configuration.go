@@ -2,15 +2,22 @@ package bird
 
 import (
 	"fmt"
+	"net"
+	"time"
 
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/api/v1alpha1"
 )
 
 // getConfig combines the logs, base, vips and gateways configs.
-func (b *Bird) getConfig(vips []string, gateways []Gateway) string {
+func (b *Bird) getConfig(vips []VIP, gateways []Gateway) string {
 	conf := fmt.Sprintf("%s\n\n%s",
 		b.logConfig(),
-		fmt.Sprintf(baseConfig, defaultKernelTableID, defaultKernelTableID),
+		fmt.Sprintf(baseConfig,
+			b.Tables.TableID, b.kernelOptions(),
+			b.Tables.TableID, b.kernelOptions(),
+			b.Tables.BlackholeTableID, b.kernelOptions(),
+			b.Tables.BlackholeTableID, b.kernelOptions(),
+		),
 	)
 
 	vipsConfig := vipsConfig(vips)
@@ -18,7 +25,12 @@ func (b *Bird) getConfig(vips []string, gateways []Gateway) string {
 		conf = fmt.Sprintf("%s\n\n%s", conf, vipsConfig)
 	}
 
-	gatewaysConfig := gatewaysConfig(gateways)
+	instances := routingInstances(gateways, b.Tables.NetworkTableID)
+	if len(instances) > 0 {
+		conf = fmt.Sprintf("%s\n\n%s", conf, b.routingInstancesConfig(instances))
+	}
+
+	gatewaysConfig := gatewaysConfig(gateways, vips, instances, b.PasswordDirectory)
 	if gatewaysConfig != "" {
 		conf = fmt.Sprintf("%s\n\n%s", conf, gatewaysConfig)
 	}
@@ -26,6 +38,15 @@ func (b *Bird) getConfig(vips []string, gateways []Gateway) string {
 	return conf
 }
 
+// kernelOptions returns the options of the kernel protocols.
+func (b *Bird) kernelOptions() string {
+	if !b.GracefulRestart {
+		return ""
+	}
+
+	return kernelGracefulRestartOptions
+}
+
 func (b *Bird) logConfig() string {
 	conf := ""
 
@@ -49,14 +70,14 @@ func (b *Bird) logConfig() string {
 // only advertised to BGP peers and not synced into local network stack.
 //
 // Note: VIPs shall be advertised only if external connectivity is OK.
-func vipsConfig(vips []string) string {
+func vipsConfig(vips []VIP) string {
 	ipv4, ipv6 := "", ""
 
 	for _, vip := range vips {
-		if isIPv6CIDR(vip) {
-			ipv6 += fmt.Sprintf(vipRouteTemplate, vip)
-		} else if isIPv4CIDR(vip) {
-			ipv4 += fmt.Sprintf(vipRouteTemplate, vip)
+		if isIPv6CIDR(vip.GetCIDR()) {
+			ipv6 += vipRouteConfig(vip)
+		} else if isIPv4CIDR(vip.GetCIDR()) {
+			ipv4 += vipRouteConfig(vip)
 		}
 	}
 
@@ -81,32 +102,42 @@ func vipsConfig(vips []string) string {
 // Note: When VRRP IPs are configured, BGP sessions won't import any routes from external
 // peers, as external routes are going to be taken care of by static default routes (VRRP IPs
 // as next hops).
-func gatewaysConfig(gateways []Gateway) string {
+//
+// The routes received from each gateway are also imported into the table of the routing
+// instance of its interface.
+func gatewaysConfig(gateways []Gateway, vips []VIP, instances []*routingInstance, passwordDirectory string) string {
 	conf := ""
 
+	if hasMultihopGateway(gateways) {
+		conf += multihopIGPConfig
+		conf += "\n\n"
+	}
+
 	for _, gateway := range gateways {
-		conf += gatewayConfig(gateway)
+		conf += gatewayConfig(gateway, vips, instances, passwordDirectory)
 		conf += "\n\n"
 	}
 
-	conf += bfdTemplate
+	conf += fmt.Sprintf(bfdTemplate, bfdInterfacesConfig(gateways))
 
 	return conf
 }
 
-func gatewayConfig(gateway Gateway) string {
+func gatewayConfig(gateway Gateway, vips []VIP, instances []*routingInstance, passwordDirectory string) string {
 	conf := ""
 
 	switch gateway.GetProtocol() {
 	case v1alpha1.BGP:
-		conf += bgpConfig(gateway)
+		conf += bgpConfig(gateway, vips, instances, passwordDirectory)
+	case v1alpha1.OSPF:
+		conf += ospfConfig(gateway, vips, instances, passwordDirectory)
 	case v1alpha1.Static: // todo: static
 	}
 
 	return conf
 }
 
-func bgpConfig(gateway Gateway) string {
+func bgpConfig(gateway Gateway, vips []VIP, instances []*routingInstance, passwordDirectory string) string {
 	ipFamily := ""
 
 	if isIPv4(gateway.GetAddress()) {
@@ -121,7 +152,7 @@ func bgpConfig(gateway Gateway) string {
 	}
 
 	localASN := defaultLocalASN
-	if gateway.GetBgpSpec().GetLocalPort() != nil {
+	if gateway.GetBgpSpec().GetLocalASN() != nil {
 		localASN = *gateway.GetBgpSpec().GetLocalASN()
 	}
 
@@ -131,37 +162,177 @@ func bgpConfig(gateway Gateway) string {
 	}
 
 	remoteASN := defaultRemoteASN
-	if gateway.GetBgpSpec().GetRemotePort() != nil {
+	if gateway.GetBgpSpec().GetRemoteASN() != nil {
 		remoteASN = *gateway.GetBgpSpec().GetRemoteASN()
 	}
 
+	holdTime := bgpHoldTime(gateway.GetBgpSpec().GetHoldTime())
+
+	keepaliveTime := ""
+	if holdTime != noBGPHoldTime {
+		keepaliveTime = fmt.Sprintf(bgpKeepaliveTemplate, holdTime/bgpKeepaliveRatio)
+	}
+
+	sessionConfig := bfdConfig(gateway.GetBgpSpec().GetBfdSpec())
+	sessionConfig += gracefulRestartConfig(gateway.GetBgpSpec().GetGracefulRestart())
+
+	// The password is included from a separate file, so it is never written in
+	// the bird configuration file.
+	if gateway.GetBgpSpec().GetPassword() != "" {
+		sessionConfig += "\n\t" + fmt.Sprintf(bgpPasswordTemplate, passwordFile(passwordDirectory, gateway))
+	}
+
 	return fmt.Sprintf(bgpTemplate,
 		gateway.GetName(),
-		gateway.GetInterface(),
+		bgpSessionConfig(gateway),
+		bgpSourceAddressConfig(gateway.GetBgpSpec().GetSourceAddress()),
 		localPort,
 		localASN,
 		gateway.GetAddress(),
 		remotePort,
 		remoteASN,
-		bfdConfig(gateway.GetBgpSpec().GetBfdSpec()),
-		defaultBGPHoldTime,
+		sessionConfig,
+		holdTime,
+		keepaliveTime,
 		ipFamily,
+		bgpImportConfig(gateway.GetImportPolicy(), ipFamily),
+		bgpExportConfig(gateway, vips, localASN, ipFamily),
+		bgpChannelConfig(gateway, ipFamily, routingInstanceTable(instances, gateway.GetInterface(), ipFamily)),
 	)
 }
 
+// bgpSessionConfig returns the interface the gateway is directly connected to,
+// or the maximum number of hops to reach it for multihop sessions.
+func bgpSessionConfig(gateway Gateway) string {
+	multihop := gateway.GetBgpSpec().GetMultihop()
+	if multihop == nil {
+		return fmt.Sprintf(bgpDirectTemplate, gateway.GetInterface())
+	}
+
+	return fmt.Sprintf(bgpMultihopTemplate, *multihop)
+}
+
+// bgpSourceAddressConfig returns the local address of the BGP session, or an empty
+// string if bird must pick the address of the interface.
+func bgpSourceAddressConfig(sourceAddress string) string {
+	if net.ParseIP(sourceAddress) == nil {
+		return ""
+	}
+
+	return " " + sourceAddress
+}
+
+// bgpChannelConfig returns the channel options specific to the gateway: the table of its
+// routing instance, the IGP table used to resolve the next hops of multihop sessions and
+// the next hop override.
+func bgpChannelConfig(gateway Gateway, ipFamily string, table string) string {
+	conf := ""
+
+	if table != "" {
+		conf += fmt.Sprintf(channelTableTemplate, table)
+	}
+
+	if gateway.GetBgpSpec().GetMultihop() != nil {
+		igpTable := "igp4"
+		if ipFamily == "ipv6" {
+			igpTable = "igp6"
+		}
+
+		conf += fmt.Sprintf(bgpIGPTableTemplate, igpTable)
+	}
+
+	nextHop := net.ParseIP(gateway.GetBgpSpec().GetNextHop())
+	if nextHop != nil && (nextHop.To4() != nil) == (ipFamily == "ipv4") {
+		conf += fmt.Sprintf(bgpNextHopTemplate, nextHop.String())
+	}
+
+	return conf
+}
+
+func hasMultihopGateway(gateways []Gateway) bool {
+	for _, gateway := range gateways {
+		if gateway.GetProtocol() == v1alpha1.BGP && gateway.GetBgpSpec().GetMultihop() != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// bgpHoldTime parses the hold time of a BGP session and rounds it by second.
+// The default hold time is returned if the value is empty or invalid. A hold time
+// of 0 is kept (no keepalives are used), the values between 0 and the minimum are
+// rejected by the API and the minimum hold time is returned for them.
+func bgpHoldTime(holdTime string) uint64 {
+	if holdTime == "" {
+		return defaultBGPHoldTime
+	}
+
+	duration, err := time.ParseDuration(holdTime)
+	if err != nil {
+		return defaultBGPHoldTime
+	}
+
+	seconds := uint64(duration.Round(time.Second) / time.Second)
+	if seconds != noBGPHoldTime && seconds < minBGPHoldTime {
+		return minBGPHoldTime
+	}
+
+	return seconds
+}
+
+// gracefulRestartConfig returns the graceful restart options of a BGP session, or an empty
+// string if the graceful restart is turned off (default of the BGP template).
+func gracefulRestartConfig(gracefulRestart GracefulRestartSpec) string {
+	if gracefulRestart == nil || gracefulRestart.GetSwitch() == nil || !*gracefulRestart.GetSwitch() {
+		return ""
+	}
+
+	conf := fmt.Sprintf(bgpGracefulRestartTemplate,
+		gracefulRestartTime(gracefulRestart.GetRestartTime(), defaultGracefulRestartTime, maxGracefulRestartTime))
+
+	staleTime := gracefulRestartTime(gracefulRestart.GetLongLivedStaleTime(), 0, maxLongLivedStaleTime)
+	if staleTime > 0 {
+		conf += fmt.Sprintf(bgpLongLivedGracefulRestartTemplate, staleTime)
+	}
+
+	return conf
+}
+
+// gracefulRestartTime parses a graceful restart timer and rounds it by second.
+// The default value is returned if the value is empty or invalid, and the
+// maximum value is returned if the value is greater than the maximum.
+func gracefulRestartTime(restartTime string, defaultTime uint64, maxTime uint64) uint64 {
+	if restartTime == "" {
+		return defaultTime
+	}
+
+	duration, err := time.ParseDuration(restartTime)
+	if err != nil || duration < 0 {
+		return defaultTime
+	}
+
+	seconds := uint64(duration.Round(time.Second) / time.Second)
+	if seconds > maxTime {
+		return maxTime
+	}
+
+	return seconds
+}
+
 func bfdConfig(bfd BfdSpec) string {
-	if bfd.GetSwitch() == nil || !*bfd.GetSwitch() {
+	if bfd == nil || bfd.GetSwitch() == nil || !*bfd.GetSwitch() {
 		return "\tbfd off;"
 	}
 
 	conf := ""
 
-	if bfd.GetMinRx() != "" {
-		conf += fmt.Sprintf("\t\tmin rx interval %s;\n", bfd.GetMinRx())
+	if minRx := bfdInterval(bfd.GetMinRx()); minRx != "" {
+		conf += fmt.Sprintf("\t\tmin rx interval %s;\n", minRx)
 	}
 
-	if bfd.GetMinTx() != "" {
-		conf += fmt.Sprintf("\t\tmin tx interval %s;\n", bfd.GetMinTx())
+	if minTx := bfdInterval(bfd.GetMinTx()); minTx != "" {
+		conf += fmt.Sprintf("\t\tmin tx interval %s;\n", minTx)
 	}
 
 	if bfd.GetMultiplier() != nil {
@@ -174,3 +345,24 @@ func bfdConfig(bfd BfdSpec) string {
 
 	return fmt.Sprintf(bgpBfdTemplate, conf)
 }
+
+// bfdInterval parses a BFD timer and rounds it by millisecond.
+// An empty string is returned if the value is empty or invalid so
+// the bird default is used.
+func bfdInterval(interval string) string {
+	if interval == "" {
+		return ""
+	}
+
+	duration, err := time.ParseDuration(interval)
+	if err != nil || duration <= 0 {
+		return ""
+	}
+
+	milliseconds := duration.Round(time.Millisecond) / time.Millisecond
+	if milliseconds <= 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("%dms", milliseconds)
+}
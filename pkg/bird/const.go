@@ -17,17 +17,33 @@ limitations under the License.
 package bird
 
 const (
-	defaultBGPHoldTime          = 3
-	defaultLocalASN      uint32 = 8103
-	defaultLocalPort     uint16 = 10179
-	defaultRemoteASN     uint32 = 4248829953
-	defaultRemotePort    uint16 = 10179
-	defaultKernelTableID        = 4096
-	defaultLogFileSize          = 20000
+	defaultBGPHoldTime                = 3
+	minBGPHoldTime                    = 3
+	noBGPHoldTime                     = 0
+	bgpKeepaliveRatio                 = 3
+	defaultLocalASN            uint32 = 8103
+	defaultLocalPort           uint16 = 10179
+	defaultRemoteASN           uint32 = 4248829953
+	defaultRemotePort          uint16 = 10179
+	defaultKernelTableID              = 4096
+	defaultBlackholeTableID           = 4097
+	defaultRulePriority               = 100
+	defaultNetworkTableID             = 4100
+	defaultNetworkRulePriority        = 99
+	defaultLogFileSize                = 20000
+	defaultGracefulRestartTime        = 120
+	maxGracefulRestartTime            = 4095
+	maxLongLivedStaleTime             = 16777215
 )
 
 // 0: kernel table ID
-// 1: kernel table ID
+// 1: kernel protocol options
+// 2: kernel table ID
+// 3: kernel protocol options
+// 4: blackhole kernel table ID
+// 5: kernel protocol options
+// 6: blackhole kernel table ID
+// 7: kernel protocol options
 const baseConfig = `protocol device {
 }
 
@@ -35,6 +51,13 @@ filter gateway_routes {
 	if ( net ~ [ 0.0.0.0/0 ] ) then accept;
 	if ( net ~ [ 0::/0 ] ) then accept;
 	if source = RTS_BGP then accept;
+	if source ~ [ RTS_OSPF, RTS_OSPF_IA, RTS_OSPF_EXT1, RTS_OSPF_EXT2 ] then accept;
+	else reject;
+}
+
+filter ospf_gateway_routes {
+	if ( net ~ [ 0.0.0.0/0 ] ) then accept;
+	if ( net ~ [ 0::/0 ] ) then accept;
 	else reject;
 }
 
@@ -70,7 +93,7 @@ protocol kernel {
 		export filter gateway_routes;
 	};
 	kernel table %d;
-	merge paths on;
+	merge paths on;%s
 }
 
 protocol kernel {
@@ -79,7 +102,7 @@ protocol kernel {
 		export filter gateway_routes;
 	};
 	kernel table %d;
-	merge paths on;
+	merge paths on;%s
 }
 
 ipv4 table drop4;
@@ -92,7 +115,7 @@ protocol kernel {
 		import none;
 		export all;
 	};
-	kernel table 4097;
+	kernel table %d;%s
 }
 
 protocol kernel {
@@ -101,7 +124,7 @@ protocol kernel {
 		import none;
 		export all;
 	};
-	kernel table 4097;
+	kernel table %d;%s
 }
 
 protocol static DROP4 {
@@ -131,33 +154,217 @@ const vipsTemplate = `protocol static %s {
 // 0: CIDR of the route
 const vipRouteTemplate = "\troute %s via \"lo\";\n"
 
+// 0: CIDR of the route
+// 1: attributes of the route
+const vipRouteAttributesTemplate = "\troute %s via \"lo\" {\n%s\t};\n"
+
+// Default import filter of the BGP protocols.
+const bgpImportFilter = "filter gateway_routes"
+
+// Import filter of the BGP protocols with an import policy.
+// 0: accept statements
+const bgpImportPolicyFilterTemplate = `filter {
+%s			reject;
+		}`
+
+// 0: prefixes
+const bgpImportAcceptTemplate = "\t\t\tif ( net ~ [ %s ] ) then accept;\n"
+
+// 0: maximum number of prefixes
+const bgpImportLimitTemplate = ";\n\t\timport limit %d action block"
+
+// Default export filter of the BGP protocols.
+const bgpExportFilter = "filter announced_routes"
+
+// Export filter of the BGP and OSPF protocols announcing only some of the VIPs
+// or prepending the AS path of some VIPs. Same as the announced_routes filter.
+// 0: Statement rejecting the VIPs not exported to the gateway
+// 1: AS path prepend statements
+const bgpExportCustomFilterTemplate = `filter {
+%s%s			if ( net ~ [ 0.0.0.0/0 ] ) then reject;
+			if ( net ~ [ 0::/0 ] ) then reject;
+			if source = RTS_STATIC && dest != RTD_BLACKHOLE then accept;
+			else reject;
+		}`
+
+// 0: CIDR of the VIP
+// 1: AS path prepend statements
+const bgpExportPrependTemplate = "\t\t\tif ( net ~ [ %s ] ) then {%s }\n"
+
+// 0: CIDRs of the VIPs exported to the gateway
+const bgpExportScopeTemplate = "\t\t\tif ( net !~ [ %s ] ) then reject;\n"
+
 // Represents the BGP protocol
 // 0: Name of the gateway
-// 1: Interface used for the gateway
-// 2: Local Port
-// 3: Local ASN
-// 4: Remote IP (Gateway IP)
-// 5: Remote Port
-// 6: Remote ASN
-// 7: BFD
-// 8: Hold Time
-// 9: IP Family
+// 1: Session (interface of a directly connected gateway or multihop)
+// 2: Local Address
+// 3: Local Port
+// 4: Local ASN
+// 5: Remote IP (Gateway IP)
+// 6: Remote Port
+// 7: Remote ASN
+// 8: BFD
+// 9: Hold Time
+// 10: Keepalive Time (omitted if the hold time is 0, keepalives are then not used)
+// 11: IP Family
+// 12: Import filter
+// 13: Export filter
+// 14: Channel options
 const bgpTemplate = `protocol bgp '%s' from BGP_TEMPLATE {
-	interface "%s";
-	local port %d as %d;
+	%s
+	local%s port %d as %d;
 	neighbor %s port %d as %d;
 	%s
-	hold time %d;
+	hold time %d;%s
+	%s {
+		import %s;
+		export %s;%s
+	};
+}`
+
+// 0: Keepalive Time
+const bgpKeepaliveTemplate = "\n\tkeepalive time %d;"
+
+// 0: Interface used for the gateway
+const bgpDirectTemplate = "interface \"%s\";"
+
+// 0: Maximum number of hops to the gateway
+const bgpMultihopTemplate = "multihop %d;"
+
+// 0: IGP table used to resolve the next hops received from the multihop gateways
+const bgpIGPTableTemplate = "\n\t\tigp table %s;"
+
+// 0: Next hop announced to the gateway
+const bgpNextHopTemplate = "\n\t\tnext hop address %s;"
+
+// The next hops received from the multihop gateways are not directly connected, they are
+// resolved recursively via the routes of the main kernel table learned in the IGP tables.
+const multihopIGPConfig = `ipv4 table igp4;
+
+ipv6 table igp6;
+
+protocol kernel IGP4 {
+	ipv4 {
+		table igp4;
+		import all;
+		export none;
+	};
+	learn;
+}
+
+protocol kernel IGP6 {
+	ipv6 {
+		table igp6;
+		import all;
+		export none;
+	};
+	learn;
+}
+
+protocol direct IGP4_DIRECT {
+	ipv4 { table igp4; };
+}
+
+protocol direct IGP6_DIRECT {
+	ipv6 { table igp6; };
+}`
+
+// Routing instance of a network: the VIPs are exported from the master table to the table
+// of the routing instance, and the routes received from the gateways of the network are
+// imported into the master table (default kernel table) and into the kernel table of the
+// routing instance.
+// 0: IP family
+// 1: table of the routing instance
+// 2: name of the routing instance
+// 3: IP version (4 or 6)
+// 4: table of the routing instance
+// 5: name of the routing instance
+// 6: IP family
+// 7: table of the routing instance
+// 8: kernel table ID
+// 9: kernel protocol options
+const routingInstanceTemplate = `%s table %s;
+
+protocol pipe %s {
+	table master%s;
+	peer table %s;
+	import filter gateway_routes;
+	export filter announced_routes;
+}
+
+protocol kernel %s_KERNEL {
 	%s {
-		import filter gateway_routes;
-		export filter announced_routes;
+		table %s;
+		import none;
+		export filter gateway_routes;
 	};
+	kernel table %d;
+	merge paths on;%s
 }`
 
+// 0: Table of the routing instance of the gateway
+const channelTableTemplate = "\n\t\ttable %s;"
+
+// 0: Restart time (in seconds)
+const bgpGracefulRestartTemplate = `
+	graceful restart on;
+	graceful restart time %d;`
+
+// 0: Stale time (in seconds)
+const bgpLongLivedGracefulRestartTemplate = `
+	long lived graceful restart on;
+	long lived stale time %d;`
+
+// Options of the kernel protocols when the graceful restart is enabled: the routes are kept
+// in the kernel tables when bird stops, and recovered by bird when it starts again.
+const kernelGracefulRestartOptions = "\n\tpersist;"
+
+// 0: Path of the file containing the password
+const bgpPasswordTemplate = "include \"%s\";"
+
+// 0: Password
+const passwordTemplate = "password \"%s\";\n"
+
+// 0: interfaces with specific BFD timers
 const bfdTemplate = `protocol bfd {
-	interface "*" {
+%s	interface "*" {
 	};
 }`
 
+// 0: interface
+// 1: bfd properties
+const bfdInterfaceTemplate = "\tinterface \"%s\" {\n%s\t};\n"
+
 // 0: bfd properties
 const bgpBfdTemplate = `bfd {%s};`
+
+// Represents the OSPF protocol
+// 0: OSPF version (v2 or v3)
+// 1: Name of the gateway
+// 2: IP Family
+// 3: Import filter
+// 4: Export filter
+// 5: Channel options
+// 6: Area
+// 7: Stub networks
+// 8: Interface used for the gateway
+// 9: Interface options
+const ospfTemplate = `protocol ospf %s '%s' {
+	%s {
+		import %s;
+		export %s;%s
+	};
+	area %s {%s
+		interface "%s" {%s
+		};
+	};
+}`
+
+// Default import filter of the OSPF protocols: only the default routes are imported.
+const ospfImportFilter = "filter ospf_gateway_routes"
+
+// 0: CIDR of the VIP
+const ospfStubnetTemplate = "\n\t\tstubnet %s;"
+
+// 0: Path of the file containing the password
+const ospfPasswordTemplate = "\n\t\t\tauthentication cryptographic;\n\t\t\tinclude \"%s\";"
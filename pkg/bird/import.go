@@ -0,0 +1,114 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bird
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ImportStatistics represents the statistics of the routes imported from a gateway.
+type ImportStatistics struct {
+	// Number of routes currently imported.
+	Imported int
+	// Number of route updates rejected by the import filter or the import limit.
+	Rejected int
+}
+
+// bgpImportConfig returns the import filter of a BGP protocol. Only the prefixes of the
+// import policy (and the default route if enabled) are accepted. The default filter
+// (gateway_routes) is used if there is no import policy.
+func bgpImportConfig(importPolicy ImportPolicy, ipFamily string) string {
+	if importPolicy == nil {
+		return bgpImportFilter
+	}
+
+	accept := ""
+
+	if importPolicy.GetDefaultRoute() == nil || *importPolicy.GetDefaultRoute() {
+		defaultRoute := "0.0.0.0/0"
+		if ipFamily == "ipv6" {
+			defaultRoute = "0::/0"
+		}
+
+		accept += fmt.Sprintf(bgpImportAcceptTemplate, defaultRoute)
+	}
+
+	prefixes := importPrefixes(importPolicy.GetPrefixes(), ipFamily)
+	if len(prefixes) > 0 {
+		accept += fmt.Sprintf(bgpImportAcceptTemplate, strings.Join(prefixes, ", "))
+	}
+
+	conf := fmt.Sprintf(bgpImportPolicyFilterTemplate, accept)
+
+	if importPolicy.GetMaxPrefixes() != nil {
+		conf += fmt.Sprintf(bgpImportLimitTemplate, *importPolicy.GetMaxPrefixes())
+	}
+
+	return conf
+}
+
+// importPrefixes returns the valid prefixes of the IP family in the bird format
+// matching the prefix and all the more specific ones (e.g. 10.0.0.0/8+).
+func importPrefixes(cidrs []string, ipFamily string) []string {
+	prefixes := []string{}
+
+	for _, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			continue
+		}
+
+		if (ipFamily == "ipv4") != (ipNet.IP.To4() != nil) {
+			continue
+		}
+
+		prefixes = append(prefixes, ipNet.String()+"+")
+	}
+
+	return prefixes
+}
+
+// GetImportStatistics returns the statistics of the routes imported from each
+// BGP and OSPF gateway (key: name of the gateway).
+func (b *Bird) GetImportStatistics(ctx context.Context) (map[string]*ImportStatistics, error) {
+	protocols, err := NewClient(b.SocketPath).ShowProtocols(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the bird protocols: %w", err)
+	}
+
+	statistics := map[string]*ImportStatistics{}
+
+	for _, protocol := range protocols {
+		if protocol.Proto != "BGP" && protocol.Proto != "OSPF" {
+			continue
+		}
+
+		protocolStatistics := &ImportStatistics{}
+
+		for _, channel := range protocol.Channels {
+			protocolStatistics.Imported += channel.ImportedRoutes
+			protocolStatistics.Rejected += channel.ImportUpdates.Filtered + channel.ImportUpdates.Rejected
+		}
+
+		statistics[protocol.Name] = protocolStatistics
+	}
+
+	return statistics, nil
+}
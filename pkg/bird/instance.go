@@ -0,0 +1,214 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bird
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/api/v1alpha1"
+	"github.com/vishvananda/netlink"
+)
+
+// maxNetworks is the number of routing instances, so the number of kernel tables
+// reserved from RoutingTables.NetworkTableID.
+const maxNetworks = 100
+
+var errTooManyNetworks = errors.New("too many networks")
+
+// routingInstance represents the routing of the gateways reachable over the same
+// interface (network). The routes received from these gateways are written into a
+// dedicated kernel table, so the traffic of the VIPs received over a network is
+// routed back to the gateways of this same network, even if the same VIP is used
+// over several networks.
+type routingInstance struct {
+	// interface of the network.
+	intf string
+	// index of the routing instance, used to name the bird tables and protocols.
+	index int
+	// kernel table ID.
+	tableID int
+}
+
+// routingInstances returns a routing instance per interface of the BGP and OSPF gateways, with
+// the kernel tables starting from firstTableID. The number of interfaces must have been checked
+// by checkNetworks.
+func routingInstances(gateways []Gateway, firstTableID int) []*routingInstance {
+	interfaces := networkInterfaces(gateways)
+	instances := []*routingInstance{}
+
+	for index, intf := range interfaces {
+		instances = append(instances, &routingInstance{
+			intf:    intf,
+			index:   index,
+			tableID: firstTableID + index,
+		})
+	}
+
+	return instances
+}
+
+// checkNetworks returns an error if the BGP and OSPF gateways are reachable over more
+// interfaces than the number of routing instances.
+func checkNetworks(gateways []Gateway) error {
+	networks := len(networkInterfaces(gateways))
+	if networks > maxNetworks {
+		return fmt.Errorf("%w: %d interfaces (maximum %d)", errTooManyNetworks, networks, maxNetworks)
+	}
+
+	return nil
+}
+
+// networkInterfaces returns the sorted interfaces of the BGP and OSPF gateways.
+func networkInterfaces(gateways []Gateway) []string {
+	interfaces := []string{}
+	exists := map[string]struct{}{}
+
+	for _, gateway := range gateways {
+		if gateway.GetProtocol() != v1alpha1.BGP && gateway.GetProtocol() != v1alpha1.OSPF {
+			continue
+		}
+
+		if _, ok := exists[gateway.GetInterface()]; ok || gateway.GetInterface() == "" {
+			continue
+		}
+
+		exists[gateway.GetInterface()] = struct{}{}
+		interfaces = append(interfaces, gateway.GetInterface())
+	}
+
+	sort.Strings(interfaces)
+
+	return interfaces
+}
+
+// routingInstanceTable returns the bird table of the routing instance of the interface, or an
+// empty string if there is no routing instance for it.
+func routingInstanceTable(instances []*routingInstance, intf string, ipFamily string) string {
+	for _, instance := range instances {
+		if instance.intf != intf {
+			continue
+		}
+
+		if ipFamily == "ipv6" {
+			return fmt.Sprintf("network6_%d", instance.index)
+		}
+
+		return fmt.Sprintf("network4_%d", instance.index)
+	}
+
+	return ""
+}
+
+// routingInstancesConfig returns the bird tables of the routing instances, the pipes
+// exchanging the VIPs and the received routes with the master tables, and the kernel
+// protocols writing the received routes into the kernel tables of the routing instances.
+func (b *Bird) routingInstancesConfig(instances []*routingInstance) string {
+	conf := []string{}
+
+	for _, instance := range instances {
+		for _, ipFamily := range []string{"ipv4", "ipv6"} {
+			conf = append(conf, fmt.Sprintf(routingInstanceTemplate,
+				ipFamily,
+				routingInstanceTable(instances, instance.intf, ipFamily),
+				routingInstanceName(instance, ipFamily),
+				ipFamily[len(ipFamily)-1:],
+				routingInstanceTable(instances, instance.intf, ipFamily),
+				routingInstanceName(instance, ipFamily),
+				ipFamily,
+				routingInstanceTable(instances, instance.intf, ipFamily),
+				instance.tableID,
+				b.kernelOptions(),
+			))
+		}
+	}
+
+	return strings.Join(conf, "\n\n")
+}
+
+func routingInstanceName(instance *routingInstance, ipFamily string) string {
+	return fmt.Sprintf("NETWORK%s_%d", ipFamily[len(ipFamily)-1:], instance.index)
+}
+
+// setRoutingInstancePolicyRoutes sets, for each routing instance, a policy route per VIP
+// matching the interface of the routing instance as ingress interface. The network of a
+// packet is only given by the interface it is received on (no connection tracking), so
+// it is routed the same way by all the routers, whichever received the other packets of
+// its flow. The policy routes of the routing instances no longer existing are removed.
+func setRoutingInstancePolicyRoutes(vips []string, instances []*routingInstance, tables RoutingTables) error {
+	rules, err := netlink.RuleList(netlink.FAMILY_ALL)
+	if err != nil {
+		return fmt.Errorf("failed to list rules: %w", err)
+	}
+
+	wanted := map[string]*netlink.Rule{}
+
+	for _, instance := range instances {
+		for _, vip := range vips {
+			_, vipIPNet, err := net.ParseCIDR(vip)
+			if err != nil {
+				continue
+			}
+
+			rule := netlink.NewRule()
+			rule.Priority = tables.NetworkRulePriority
+			rule.Table = instance.tableID
+			rule.IifName = instance.intf
+			rule.Src = vipIPNet
+
+			wanted[ruleKey(rule)] = rule
+		}
+	}
+
+	var errFinal error
+
+	for _, rule := range rules {
+		if !tables.networkTables().Contains(rule.Table) {
+			continue
+		}
+
+		currentRule := rule
+
+		_, exists := wanted[ruleKey(&currentRule)]
+		if exists {
+			delete(wanted, ruleKey(&currentRule))
+
+			continue
+		}
+
+		err := netlink.RuleDel(&currentRule)
+		if err != nil {
+			errFinal = fmt.Errorf("failed to RuleDel ; %w; %w", err, errFinal)
+		}
+	}
+
+	for _, rule := range wanted {
+		err := netlink.RuleAdd(rule)
+		if err != nil {
+			errFinal = fmt.Errorf("failed to RuleAdd ; %w; %w", err, errFinal)
+		}
+	}
+
+	return errFinal
+}
+
+func ruleKey(rule *netlink.Rule) string {
+	return fmt.Sprintf("%d/%s/%s", rule.Table, rule.IifName, rule.Src.String())
+}
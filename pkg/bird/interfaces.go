@@ -18,6 +18,38 @@ package bird
 
 import "github.com/lioneljouin/l-3-4-gateway-api-poc/api/v1alpha1"
 
+// VIP defines a VIP announced by bird.
+type VIP interface {
+	// CIDR of the VIP (e.g. 20.0.0.1/32).
+	GetCIDR() string
+
+	// BGP attributes set on the VIP when announced to the BGP peers.
+	// No attribute is set if nil.
+	GetBgpAttributes() BgpAttributes
+
+	// Names of the gateways the VIP is exported to.
+	// The VIP is exported to all the gateways if nil.
+	GetExportedTo() []string
+}
+
+// BgpAttributes defines the BGP path attributes set on a VIP announced to the BGP peers.
+type BgpAttributes interface {
+	// BGP communities (RFC1997) in the format <ASN>:<value> (e.g. 65000:100).
+	GetCommunities() []string
+
+	// BGP large communities (RFC8092) in the format <ASN>:<value>:<value> (e.g. 65000:1:2).
+	GetLargeCommunities() []string
+
+	// Multi Exit Discriminator.
+	GetMED() *uint32
+
+	// Local preference (only sent to internal BGP peers).
+	GetLocalPreference() *uint32
+
+	// Number of times the local ASN is prepended to the AS path.
+	GetASPathPrepend() *uint32
+}
+
 // GatewaySpec defines the desired state of Gateway.
 type Gateway interface {
 	GetName() string
@@ -39,6 +71,29 @@ type Gateway interface {
 	// Parameters to work with the static routing configured on the Gateway Router with specified Address.
 	// If the Protocol is bgp, this property must be empty.
 	GetStatic() StaticSpec
+
+	// Parameters to set up the OSPF adjacency with the Gateway Router over the Interface.
+	// If the Protocol is not OSPF, this property must be empty.
+	GetOspfSpec() OspfSpec
+
+	// Routes imported from the Gateway Router.
+	// When nil, the default routes and all the routes received over BGP are imported.
+	GetImportPolicy() ImportPolicy
+}
+
+// ImportPolicy defines the routes imported from a Gateway Router.
+type ImportPolicy interface {
+	// Prefixes (CIDRs) imported from the Gateway Router. A prefix matches the CIDR
+	// and all the more specific prefixes within it.
+	GetPrefixes() []string
+
+	// Import the default routes (0.0.0.0/0 and ::/0) from the Gateway Router.
+	// Default is true.
+	GetDefaultRoute() *bool
+
+	// Maximum number of prefixes imported from the Gateway Router.
+	// No limit is set when nil.
+	GetMaxPrefixes() *uint32
 }
 
 // BgpSpec defines the parameters to set up a BGP session.
@@ -63,6 +118,77 @@ type BgpSpec interface {
 
 	// BGP listening port of the Attractor FrontEnds.
 	GetLocalPort() *uint16
+
+	// Password (pre-shared key) of the BGP authentication (RFC2385).
+	// No authentication is configured when empty.
+	GetPassword() string
+
+	// Maximum number of hops (TTL) to reach the Gateway Router (multihop eBGP).
+	// When nil, the Gateway Router is directly connected via the Interface.
+	GetMultihop() *uint8
+
+	// Local address used as source of the BGP session (e.g. a loopback address).
+	// When empty, the address of the Interface is used.
+	GetSourceAddress() string
+
+	// Next hop announced with the VIPs to the Gateway Router.
+	// When empty, the address of the router is announced (next hop self).
+	GetNextHop() string
+
+	// Graceful restart of the BGP session.
+	// When nil, graceful restart is turned off.
+	GetGracefulRestart() GracefulRestartSpec
+}
+
+// GracefulRestartSpec defines the graceful restart parameters of a BGP session.
+type GracefulRestartSpec interface {
+	// Graceful restart (RFC4724).
+	// When left empty, graceful restart is turned off.
+	GetSwitch() *bool
+
+	// Restart time advertised to the Gateway Router.
+	// The value must be a valid duration format. For example, 90s, 1m, 1h.
+	// The duration will be rounded by second.
+	// When left empty, the bird default is used.
+	GetRestartTime() string
+
+	// Long-lived graceful restart (RFC9494) stale time.
+	// The value must be a valid duration format. For example, 90s, 1m, 1h.
+	// The duration will be rounded by second.
+	// When left empty, long-lived graceful restart is turned off.
+	GetLongLivedStaleTime() string
+}
+
+// OspfSpec defines the parameters to set up an OSPF adjacency.
+type OspfSpec interface {
+	// OSPF area of the Interface, as a number or in the dotted format (e.g. 0 or 0.0.0.10).
+	// When empty, the backbone area (0) is used.
+	GetArea() string
+
+	// Cost of the Interface.
+	// When nil, the bird default is used.
+	GetCost() *uint16
+
+	// Interval between the hello packets.
+	// The value must be a valid duration format. For example, 10s, 1m.
+	// The duration will be rounded by second.
+	GetHelloInterval() string
+
+	// Time after which the Gateway Router is considered down if no hello packet has been received.
+	// The value must be a valid duration format. For example, 40s, 1m.
+	// The duration will be rounded by second.
+	GetDeadInterval() string
+
+	// Password (key) of the OSPF cryptographic authentication.
+	// No authentication is configured when empty.
+	GetPassword() string
+
+	// BFD monitoring of the OSPF adjacency.
+	GetBfdSpec() BfdSpec
+
+	// How the VIPs are advertised (stub networks or external routes).
+	// When empty, the VIPs are advertised as external routes.
+	GetVIPAdvertisement() v1alpha1.OspfVIPAdvertisement
 }
 
 // StaticSpec defines the parameters to set up static routes.
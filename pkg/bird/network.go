@@ -19,10 +19,17 @@ package bird
 import (
 	"fmt"
 	"net"
+	"slices"
 
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/api/v1alpha1"
 	"github.com/vishvananda/netlink"
 )
 
+const (
+	ipv4Bits = 32
+	ipv6Bits = 128
+)
+
 func isIPv4CIDR(cidr string) bool {
 	ipAddr, _, err := net.ParseCIDR(cidr)
 	if err != nil {
@@ -61,12 +68,14 @@ func isIPv6(ip string) bool {
 	return ipAddr.To4() == nil
 }
 
-// setPolicyRoutes finds all policy routes with the table ID used for Bird.
-// It deleted all policy routes for no longer existing vips and creates the
-// ones for the newly added.
-func setPolicyRoutes(vips []string) error {
+// SetPolicyRoutes finds all policy routes with the table ID in parameter (the kernel table
+// the routes received from the gateways are written to). It deleted all policy routes for no
+// longer existing vips and creates the ones for the newly added with the priority in parameter.
+// The traffic from the source addresses of the BGP sessions is routed the same way as the traffic
+// from the vips.
+func SetPolicyRoutes(vips []string, tableID int, priority int) error {
 	rules, err := netlink.RuleListFiltered(netlink.FAMILY_ALL, &netlink.Rule{
-		Table: defaultKernelTableID,
+		Table: tableID,
 	}, netlink.RT_FILTER_TABLE)
 	if err != nil {
 		return fmt.Errorf("failed to list rules: %w", err)
@@ -103,8 +112,8 @@ func setPolicyRoutes(vips []string) error {
 
 	for _, vipIPNet := range vipMap {
 		rule := netlink.NewRule()
-		rule.Priority = 100
-		rule.Table = defaultKernelTableID
+		rule.Priority = priority
+		rule.Table = tableID
 		rule.Src = vipIPNet
 
 		err := netlink.RuleAdd(rule)
@@ -115,3 +124,95 @@ func setPolicyRoutes(vips []string) error {
 
 	return errFinal
 }
+
+// SourceAddressCIDRs returns the source addresses of the BGP sessions as host CIDRs.
+func SourceAddressCIDRs(gateways []Gateway) []string {
+	cidrs := []string{}
+
+	for _, gateway := range gateways {
+		if gateway.GetProtocol() != v1alpha1.BGP {
+			continue
+		}
+
+		ipAddr := net.ParseIP(gateway.GetBgpSpec().GetSourceAddress())
+		if ipAddr == nil {
+			continue
+		}
+
+		cidr := (&net.IPNet{IP: ipAddr, Mask: net.CIDRMask(ipv6Bits, ipv6Bits)}).String()
+		if ipAddr.To4() != nil {
+			cidr = (&net.IPNet{IP: ipAddr.To4(), Mask: net.CIDRMask(ipv4Bits, ipv4Bits)}).String()
+		}
+
+		if !slices.Contains(cidrs, cidr) {
+			cidrs = append(cidrs, cidr)
+		}
+	}
+
+	return cidrs
+}
+
+// SetSourceAddresses adds the source addresses of the BGP sessions to the loopback
+// interface. The addresses previously added (in parameter) which are no longer used
+// are removed, the addresses already existing on the loopback interface are left
+// untouched. The addresses added are returned.
+func SetSourceAddresses(cidrs []string, added []string) ([]string, error) {
+	loopback, err := netlink.LinkByName("lo")
+	if err != nil {
+		return added, fmt.Errorf("failed to get the loopback interface: %w", err)
+	}
+
+	existing, err := netlink.AddrList(loopback, netlink.FAMILY_ALL)
+	if err != nil {
+		return added, fmt.Errorf("failed to list the addresses of the loopback interface: %w", err)
+	}
+
+	var errFinal error
+
+	newAdded := []string{}
+
+	for _, cidr := range cidrs {
+		if slices.Contains(added, cidr) {
+			newAdded = append(newAdded, cidr)
+
+			continue
+		}
+
+		addr, err := netlink.ParseAddr(cidr)
+		if err != nil {
+			continue
+		}
+
+		if slices.ContainsFunc(existing, addr.Equal) {
+			continue
+		}
+
+		err = netlink.AddrAdd(loopback, addr)
+		if err != nil {
+			errFinal = fmt.Errorf("failed to AddrAdd %s ; %w; %w", cidr, err, errFinal)
+
+			continue
+		}
+
+		newAdded = append(newAdded, cidr)
+	}
+
+	for _, cidr := range added {
+		if slices.Contains(cidrs, cidr) {
+			continue
+		}
+
+		addr, err := netlink.ParseAddr(cidr)
+		if err != nil {
+			continue
+		}
+
+		err = netlink.AddrDel(loopback, addr)
+		if err != nil {
+			errFinal = fmt.Errorf("failed to AddrDel %s ; %w; %w", cidr, err, errFinal)
+			newAdded = append(newAdded, cidr)
+		}
+	}
+
+	return newAdded, errFinal
+}
@@ -0,0 +1,167 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bird
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/api/v1alpha1"
+)
+
+// ospfConfig returns the OSPF protocol of a gateway (OSPFv2 for IPv4 and OSPFv3 for IPv6).
+// The VIPs exported to the gateway are advertised as stub networks of the area or as
+// external routes, and the routes learned are filtered by the import policy (only the
+// default routes if none).
+func ospfConfig(gateway Gateway, vips []VIP, instances []*routingInstance, passwordDirectory string) string {
+	ospfSpec := gateway.GetOspfSpec()
+
+	version, ipFamily := "v2", "ipv4"
+	if isIPv6(gateway.GetAddress()) {
+		version, ipFamily = "v3", "ipv6"
+	}
+
+	importConfig := ospfImportFilter
+	if gateway.GetImportPolicy() != nil {
+		importConfig = bgpImportConfig(gateway.GetImportPolicy(), ipFamily)
+	}
+
+	export := exportConfig(gateway, vips, ipFamily, "")
+	stubnets := ""
+
+	if ospfSpec.GetVIPAdvertisement() == v1alpha1.OspfStub {
+		export = "none"
+
+		for _, vip := range ExportedVIPs(vips, gateway) {
+			if sameIPFamily(vip.GetCIDR(), ipFamily) {
+				stubnets += fmt.Sprintf(ospfStubnetTemplate, vip.GetCIDR())
+			}
+		}
+	}
+
+	channelConfig := ""
+	if table := routingInstanceTable(instances, gateway.GetInterface(), ipFamily); table != "" {
+		channelConfig = fmt.Sprintf(channelTableTemplate, table)
+	}
+
+	return fmt.Sprintf(ospfTemplate,
+		version,
+		gateway.GetName(),
+		ipFamily,
+		importConfig,
+		export,
+		channelConfig,
+		ospfArea(ospfSpec.GetArea()),
+		stubnets,
+		gateway.GetInterface(),
+		ospfInterfaceConfig(gateway, passwordDirectory),
+	)
+}
+
+// ospfInterfaceConfig returns the options of the interface of an OSPF gateway.
+func ospfInterfaceConfig(gateway Gateway, passwordDirectory string) string {
+	ospfSpec := gateway.GetOspfSpec()
+	conf := ""
+
+	if ospfSpec.GetCost() != nil {
+		conf += fmt.Sprintf("\n\t\t\tcost %d;", *ospfSpec.GetCost())
+	}
+
+	if hello := ospfInterval(ospfSpec.GetHelloInterval()); hello > 0 {
+		conf += fmt.Sprintf("\n\t\t\thello %d;", hello)
+	}
+
+	if dead := ospfInterval(ospfSpec.GetDeadInterval()); dead > 0 {
+		conf += fmt.Sprintf("\n\t\t\tdead %d;", dead)
+	}
+
+	if bfdEnabled(ospfSpec.GetBfdSpec()) {
+		conf += "\n\t\t\tbfd on;"
+	}
+
+	// The password is included from a separate file, so it is never written in
+	// the bird configuration file.
+	if ospfSpec.GetPassword() != "" {
+		conf += fmt.Sprintf(ospfPasswordTemplate, passwordFile(passwordDirectory, gateway))
+	}
+
+	return conf
+}
+
+// ospfArea returns the area ID as a number or in the dotted format. The
+// backbone area is returned if the value is empty or invalid.
+func ospfArea(area string) string {
+	if _, err := strconv.ParseUint(area, 10, 32); err == nil {
+		return area
+	}
+
+	if ip := net.ParseIP(area); ip != nil && ip.To4() != nil {
+		return ip.To4().String()
+	}
+
+	return "0"
+}
+
+// ospfInterval parses an OSPF timer and rounds it by second. 0 is returned if the
+// value is empty or invalid so the bird default is used.
+func ospfInterval(interval string) uint64 {
+	duration, err := time.ParseDuration(interval)
+	if err != nil || duration <= 0 {
+		return 0
+	}
+
+	return uint64(duration.Round(time.Second) / time.Second)
+}
+
+// bfdInterfacesConfig returns the BFD timers of the interfaces of the OSPF gateways. The
+// BFD timers of the OSPF sessions can only be set per interface in the BFD protocol.
+func bfdInterfacesConfig(gateways []Gateway) string {
+	conf := ""
+
+	for _, gateway := range gateways {
+		if gateway.GetProtocol() != v1alpha1.OSPF || !bfdEnabled(gateway.GetOspfSpec().GetBfdSpec()) {
+			continue
+		}
+
+		bfd := gateway.GetOspfSpec().GetBfdSpec()
+		options := ""
+
+		if minRx := bfdInterval(bfd.GetMinRx()); minRx != "" {
+			options += fmt.Sprintf("\t\tmin rx interval %s;\n", minRx)
+		}
+
+		if minTx := bfdInterval(bfd.GetMinTx()); minTx != "" {
+			options += fmt.Sprintf("\t\tmin tx interval %s;\n", minTx)
+		}
+
+		if bfd.GetMultiplier() != nil {
+			options += fmt.Sprintf("\t\tmultiplier %d;\n", *bfd.GetMultiplier())
+		}
+
+		if options != "" {
+			conf += fmt.Sprintf(bfdInterfaceTemplate, gateway.GetInterface(), options)
+		}
+	}
+
+	return conf
+}
+
+func bfdEnabled(bfd BfdSpec) bool {
+	return bfd != nil && bfd.GetSwitch() != nil && *bfd.GetSwitch()
+}
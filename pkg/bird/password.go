@@ -0,0 +1,123 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bird
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/api/v1alpha1"
+)
+
+const (
+	passwordFileExtension = ".conf"
+	passwordDirectoryMode = 0o700
+	passwordFileMode      = 0o600
+)
+
+var errInvalidPassword = errors.New("the password contains characters not supported by bird")
+
+// passwordFile returns the path of the file containing the password of the gateway.
+func passwordFile(passwordDirectory string, gateway Gateway) string {
+	return filepath.Join(passwordDirectory, gateway.GetName()+passwordFileExtension)
+}
+
+// gatewayPassword returns the password of the BGP or OSPF authentication of the gateway.
+func gatewayPassword(gateway Gateway) string {
+	switch gateway.GetProtocol() {
+	case v1alpha1.BGP:
+		return gateway.GetBgpSpec().GetPassword()
+	case v1alpha1.OSPF:
+		return gateway.GetOspfSpec().GetPassword()
+	case v1alpha1.Static:
+	}
+
+	return ""
+}
+
+// writePasswords writes the password of each BGP or OSPF gateway using authentication in
+// a dedicated file only readable by its owner. The files of the gateways no longer
+// using authentication are removed.
+// The passwords are never part of the returned errors.
+func writePasswords(passwordDirectory string, gateways []Gateway) error {
+	passwordFiles := map[string]struct{}{}
+
+	for _, gateway := range gateways {
+		password := gatewayPassword(gateway)
+		if password == "" {
+			continue
+		}
+
+		// bird strings cannot contain double quotes or new lines.
+		if strings.ContainsAny(password, "\"\n\r") {
+			return fmt.Errorf("failed to write the password of %s: %w", gateway.GetName(), errInvalidPassword)
+		}
+
+		err := os.MkdirAll(passwordDirectory, passwordDirectoryMode)
+		if err != nil {
+			return fmt.Errorf("failed to create %v, err: %w", passwordDirectory, err)
+		}
+
+		file := passwordFile(passwordDirectory, gateway)
+
+		err = os.WriteFile(file, []byte(fmt.Sprintf(passwordTemplate, password)), passwordFileMode)
+		if err != nil {
+			return fmt.Errorf("failed to write the password of %s to %v, err: %w", gateway.GetName(), file, err)
+		}
+
+		passwordFiles[file] = struct{}{}
+	}
+
+	return removePasswords(passwordDirectory, passwordFiles)
+}
+
+// removePasswords removes the password files which are not in the list passed in parameter.
+func removePasswords(passwordDirectory string, passwordFiles map[string]struct{}) error {
+	entries, err := os.ReadDir(passwordDirectory)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		return fmt.Errorf("failed to read %v, err: %w", passwordDirectory, err)
+	}
+
+	var errFinal error
+
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != passwordFileExtension {
+			continue
+		}
+
+		file := filepath.Join(passwordDirectory, entry.Name())
+
+		_, exists := passwordFiles[file]
+		if exists {
+			continue
+		}
+
+		err := os.Remove(file)
+		if err != nil {
+			errFinal = fmt.Errorf("failed to remove %v ; %w; %w", file, err, errFinal)
+		}
+	}
+
+	return errFinal
+}
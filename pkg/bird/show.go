@@ -0,0 +1,342 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bird
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	replyCodeProtocolList    = 1002
+	replyCodeProtocolDetails = 1006
+	replyCodeRouteList       = 1007
+
+	// number of columns before the "since" column in the protocol list.
+	protocolListColumns = 4
+)
+
+// Protocol represents a bird protocol as shown by "show protocols all".
+type Protocol struct {
+	Name  string
+	Proto string
+	Table string
+	State string
+	Since string
+	Info  string
+	// BGP details
+	BGPState        string
+	NeighborAddress string
+	NeighborAS      uint32
+	LocalAS         uint32
+	LastError       string
+	Channels        []*Channel
+}
+
+// Channel represents a channel of a bird protocol.
+type Channel struct {
+	Name            string
+	State           string
+	Table           string
+	InputFilter     string
+	OutputFilter    string
+	ImportedRoutes  int
+	FilteredRoutes  int
+	ExportedRoutes  int
+	PreferredRoutes int
+	ImportUpdates   RouteChangeStats
+	ImportWithdraws RouteChangeStats
+	ExportUpdates   RouteChangeStats
+	ExportWithdraws RouteChangeStats
+}
+
+// RouteChangeStats represents the route change statistics of a channel.
+// The statistics not provided by bird ("---") are set to 0.
+type RouteChangeStats struct {
+	Received int
+	Rejected int
+	Filtered int
+	Ignored  int
+	Accepted int
+}
+
+// Route represents a route as shown by "show route".
+type Route struct {
+	Table      string
+	Prefix     string
+	Type       string
+	Protocol   string
+	Since      string
+	Primary    bool
+	Preference int
+	// Next hops of the route (e.g. "via 169.254.100.150 on eth0", "dev lo").
+	NextHops []string
+}
+
+// parseProtocols parses the reply of "show protocols all".
+func parseProtocols(reply *Reply) []*Protocol {
+	protocols := []*Protocol{}
+
+	var protocol *Protocol
+
+	var channel *Channel
+
+	for _, line := range reply.Lines {
+		switch line.Code {
+		case replyCodeProtocolList:
+			protocol = parseProtocol(line.Text)
+			channel = nil
+
+			protocols = append(protocols, protocol)
+		case replyCodeProtocolDetails:
+			if protocol == nil {
+				continue
+			}
+
+			channel = parseProtocolDetails(protocol, channel, line.Text)
+		}
+	}
+
+	return protocols
+}
+
+// parseProtocol parses a line of the protocol list:
+// "<name> <proto> <table> <state> <since> <info>".
+// since can contain a date and a time depending on the time format of bird.
+func parseProtocol(text string) *Protocol {
+	fields := strings.Fields(text)
+	protocol := &Protocol{}
+
+	columns := []*string{&protocol.Name, &protocol.Proto, &protocol.Table, &protocol.State}
+	for i := 0; i < len(columns) && i < len(fields); i++ {
+		*columns[i] = fields[i]
+	}
+
+	if len(fields) <= protocolListColumns {
+		return protocol
+	}
+
+	fields = fields[protocolListColumns:]
+	protocol.Since = fields[0]
+	fields = fields[1:]
+
+	if len(fields) > 0 && strings.Contains(protocol.Since, "-") && strings.Contains(fields[0], ":") {
+		protocol.Since += " " + fields[0]
+		fields = fields[1:]
+	}
+
+	protocol.Info = strings.Join(fields, " ")
+
+	return protocol
+}
+
+// parseProtocolDetails parses a detail line of a protocol and returns the current channel.
+func parseProtocolDetails(protocol *Protocol, channel *Channel, text string) *Channel {
+	text = strings.TrimSpace(text)
+
+	if name, found := strings.CutPrefix(text, "Channel "); found {
+		channel = &Channel{Name: strings.TrimSpace(name)}
+		protocol.Channels = append(protocol.Channels, channel)
+
+		return channel
+	}
+
+	key, value, found := strings.Cut(text, ":")
+	if !found {
+		return channel
+	}
+
+	value = strings.TrimSpace(value)
+
+	if channel != nil {
+		parseChannelDetails(channel, key, value)
+
+		return channel
+	}
+
+	switch key {
+	case "BGP state":
+		protocol.BGPState = value
+	case "Neighbor address":
+		protocol.NeighborAddress = value
+	case "Neighbor AS":
+		protocol.NeighborAS = parseUint32(value)
+	case "Local AS":
+		protocol.LocalAS = parseUint32(value)
+	case "Last error":
+		protocol.LastError = value
+	}
+
+	return channel
+}
+
+func parseChannelDetails(channel *Channel, key string, value string) {
+	switch key {
+	case "State":
+		channel.State = value
+	case "Table":
+		channel.Table = value
+	case "Input filter":
+		channel.InputFilter = value
+	case "Output filter":
+		channel.OutputFilter = value
+	case "Routes":
+		parseChannelRoutes(channel, value)
+	case "Import updates":
+		channel.ImportUpdates = parseRouteChangeStats(value)
+	case "Import withdraws":
+		channel.ImportWithdraws = parseRouteChangeStats(value)
+	case "Export updates":
+		channel.ExportUpdates = parseRouteChangeStats(value)
+	case "Export withdraws":
+		channel.ExportWithdraws = parseRouteChangeStats(value)
+	}
+}
+
+// parseChannelRoutes parses the route counters of a channel:
+// "1 imported, 0 filtered, 2 exported, 1 preferred".
+func parseChannelRoutes(channel *Channel, value string) {
+	for _, counter := range strings.Split(value, ",") {
+		fields := strings.Fields(counter)
+		if len(fields) != 2 { //nolint:gomnd
+			continue
+		}
+
+		count, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+
+		switch fields[1] {
+		case "imported":
+			channel.ImportedRoutes = count
+		case "filtered":
+			channel.FilteredRoutes = count
+		case "exported":
+			channel.ExportedRoutes = count
+		case "preferred":
+			channel.PreferredRoutes = count
+		}
+	}
+}
+
+// parseRouteChangeStats parses a line of the route change statistics:
+// "<received> <rejected> <filtered> <ignored> <accepted>".
+func parseRouteChangeStats(value string) RouteChangeStats {
+	stats := RouteChangeStats{}
+	fields := strings.Fields(value)
+	counters := []*int{&stats.Received, &stats.Rejected, &stats.Filtered, &stats.Ignored, &stats.Accepted}
+
+	for i := 0; i < len(counters) && i < len(fields); i++ {
+		count, err := strconv.Atoi(fields[i])
+		if err != nil {
+			continue
+		}
+
+		*counters[i] = count
+	}
+
+	return stats
+}
+
+// parseRoutes parses the reply of "show route":
+//
+//	Table master4:
+//	20.0.0.1/32          unicast [VIP4 12:28:38.373] * (110)
+//		dev lo
+func parseRoutes(reply *Reply) []*Route {
+	routes := []*Route{}
+	table := ""
+
+	var route *Route
+
+	for _, line := range reply.Lines {
+		if line.Code != replyCodeRouteList || strings.TrimSpace(line.Text) == "" {
+			continue
+		}
+
+		text := line.Text
+
+		switch {
+		case strings.HasPrefix(text, "Table ") && strings.HasSuffix(text, ":"):
+			table = strings.TrimSuffix(strings.TrimPrefix(text, "Table "), ":")
+			route = nil
+		case strings.HasPrefix(text, "\t") || strings.HasPrefix(text, " "):
+			if route != nil {
+				route.NextHops = append(route.NextHops, strings.TrimSpace(text))
+			}
+		default:
+			route = parseRoute(text)
+			route.Table = table
+
+			routes = append(routes, route)
+		}
+	}
+
+	return routes
+}
+
+// parseRoute parses a route line:
+// "<prefix> <type> [<protocol> <since> ...] [*] (<preference>[/<metric>]) ...".
+func parseRoute(text string) *Route {
+	route := &Route{}
+
+	head, rest, _ := strings.Cut(text, "[")
+
+	fields := strings.Fields(head)
+	if len(fields) > 0 {
+		route.Prefix = fields[0]
+	}
+
+	if len(fields) > 1 {
+		route.Type = fields[1]
+	}
+
+	source, rest, _ := strings.Cut(rest, "]")
+
+	fields = strings.Fields(source)
+	if len(fields) > 0 {
+		route.Protocol = fields[0]
+	}
+
+	if len(fields) > 1 {
+		route.Since = fields[1]
+	}
+
+	rest = strings.TrimSpace(rest)
+	if primary, found := strings.CutPrefix(rest, "*"); found {
+		route.Primary = true
+		rest = strings.TrimSpace(primary)
+	}
+
+	if preference, found := strings.CutPrefix(rest, "("); found {
+		preference, _, _ = strings.Cut(preference, ")")
+		preference, _, _ = strings.Cut(preference, "/")
+		route.Preference, _ = strconv.Atoi(preference)
+	}
+
+	return route
+}
+
+func parseUint32(value string) uint32 {
+	number, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0
+	}
+
+	return uint32(number)
+}
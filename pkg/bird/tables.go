@@ -0,0 +1,167 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bird
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/networking"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// maxRulePriority is the highest priority of the policy rules, the rules with a higher
+// priority value (main and default tables) are evaluated after them.
+const maxRulePriority = 32765
+
+var (
+	errInvalidTableID      = errors.New("invalid kernel table ID")
+	errInvalidRulePriority = errors.New("invalid policy rule priority")
+	errTablesOverlap       = errors.New("kernel table ranges are overlapping")
+)
+
+// RoutingTables are the kernel tables and the priorities of the policy rules used to route
+// the traffic of the VIPs via the gateways. The defaults stay below the forwarding marks
+// used by nfqlb (5000+).
+type RoutingTables struct {
+	// TableID is the ID of the kernel table the routes received from the gateways are
+	// written to. The traffic from the VIPs is routed via this table.
+	TableID int
+	// BlackholeTableID is the ID of the kernel table holding the blackhole default routes,
+	// so the traffic from the VIPs is dropped when no gateway is available.
+	BlackholeTableID int
+	// RulePriority is the priority of the policy rules routing the traffic from the VIPs
+	// via TableID.
+	RulePriority int
+	// NetworkTableID is the ID of the kernel table of the first routing instance. maxNetworks
+	// tables are reserved from this one, one per interface the gateways are reachable over.
+	NetworkTableID int
+	// NetworkRulePriority is the priority of the policy rules of the routing instances. It must
+	// be lower than RulePriority so these rules are evaluated first.
+	NetworkRulePriority int
+}
+
+// DefaultRoutingTables returns the default kernel tables and policy rule priorities.
+func DefaultRoutingTables() RoutingTables {
+	return RoutingTables{
+		TableID:             defaultKernelTableID,
+		BlackholeTableID:    defaultBlackholeTableID,
+		RulePriority:        defaultRulePriority,
+		NetworkTableID:      defaultNetworkTableID,
+		NetworkRulePriority: defaultNetworkRulePriority,
+	}
+}
+
+// Ranges returns the ranges of kernel tables used.
+func (rt RoutingTables) Ranges() []networking.TableRange {
+	return []networking.TableRange{
+		{First: rt.TableID, Last: rt.TableID},
+		{First: rt.BlackholeTableID, Last: rt.BlackholeTableID},
+		rt.networkTables(),
+	}
+}
+
+// PolicyRouteRanges returns the ranges of kernel tables used by the routing suites without
+// blackhole routes nor routing instances (FRR and GoBGP), only TableID is used.
+func (rt RoutingTables) PolicyRouteRanges() []networking.TableRange {
+	return []networking.TableRange{
+		{First: rt.TableID, Last: rt.TableID},
+	}
+}
+
+func (rt RoutingTables) networkTables() networking.TableRange {
+	return networking.TableRange{First: rt.NetworkTableID, Last: rt.NetworkTableID + maxNetworks - 1}
+}
+
+// Validate checks the table IDs are not reserved by the kernel, the table ranges are not
+// overlapping and the policy rule priorities are evaluated before the main table.
+func (rt RoutingTables) Validate() error {
+	ranges := rt.Ranges()
+
+	for i, tableRange := range ranges {
+		if tableRange.First <= unix.RT_TABLE_UNSPEC ||
+			tableRange.Overlaps(networking.TableRange{First: unix.RT_TABLE_COMPAT, Last: unix.RT_TABLE_LOCAL}) {
+			return fmt.Errorf("%w: %s", errInvalidTableID, tableRange)
+		}
+
+		for _, other := range ranges[i+1:] {
+			if tableRange.Overlaps(other) {
+				return fmt.Errorf("%w: %s and %s", errTablesOverlap, tableRange, other)
+			}
+		}
+	}
+
+	for _, priority := range []int{rt.RulePriority, rt.NetworkRulePriority} {
+		if priority <= 0 || priority > maxRulePriority {
+			return fmt.Errorf("%w: %d (must be between 1 and %d)", errInvalidRulePriority, priority, maxRulePriority)
+		}
+	}
+
+	if rt.NetworkRulePriority >= rt.RulePriority {
+		return fmt.Errorf("%w: the network rule priority (%d) must be lower than the rule priority (%d)",
+			errInvalidRulePriority, rt.NetworkRulePriority, rt.RulePriority)
+	}
+
+	return nil
+}
+
+// Conflicts returns the policy rules and routes using the kernel tables or the rule priorities
+// which have not been added by the routing suites (e.g. added by the host or by a CNI).
+func (rt RoutingTables) Conflicts() ([]string, error) {
+	//nolint:wrapcheck
+	return networking.RoutingConflicts(
+		rt.Ranges(),
+		[]int{rt.RulePriority, rt.NetworkRulePriority},
+		rt.ownedRule,
+		ownedRoute,
+	)
+}
+
+// PolicyRouteConflicts is Conflicts limited to the kernel table and the rule priority used by
+// the routing suites without blackhole routes nor routing instances (FRR and GoBGP).
+func (rt RoutingTables) PolicyRouteConflicts() ([]string, error) {
+	//nolint:wrapcheck
+	return networking.RoutingConflicts(
+		rt.PolicyRouteRanges(),
+		[]int{rt.RulePriority},
+		rt.ownedRule,
+		ownedRoute,
+	)
+}
+
+// ownedRule returns true if the rule has been added by SetPolicyRoutes or by the routing instances.
+func (rt RoutingTables) ownedRule(rule *netlink.Rule) bool {
+	if rule.Priority == rt.RulePriority && rule.Table == rt.TableID && rule.Mark <= 0 {
+		return true
+	}
+
+	return rule.Priority == rt.NetworkRulePriority &&
+		rt.networkTables().Contains(rule.Table) &&
+		rule.IifName != ""
+}
+
+// ownedRoute returns true if the route has been written by one of the routing suites (bird,
+// FRR (zebra) or GoBGP).
+func ownedRoute(route *netlink.Route) bool {
+	switch route.Protocol {
+	case unix.RTPROT_BIRD, unix.RTPROT_ZEBRA, unix.RTPROT_BGP:
+		return true
+	default:
+		return false
+	}
+}
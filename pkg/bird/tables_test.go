@@ -0,0 +1,177 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bird_test
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/bird"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+func TestRoutingTables_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*bird.RoutingTables)
+		wantErr bool
+	}{
+		{
+			name:    "default",
+			modify:  func(*bird.RoutingTables) {},
+			wantErr: false,
+		},
+		{
+			name: "main table",
+			modify: func(rt *bird.RoutingTables) {
+				rt.TableID = 254
+			},
+			wantErr: true,
+		},
+		{
+			name: "blackhole table in the network tables",
+			modify: func(rt *bird.RoutingTables) {
+				rt.BlackholeTableID = 4150
+			},
+			wantErr: true,
+		},
+		{
+			name: "network rule priority after the rule priority",
+			modify: func(rt *bird.RoutingTables) {
+				rt.NetworkRulePriority = 100
+			},
+			wantErr: true,
+		},
+		{
+			name: "rule priority after the main table",
+			modify: func(rt *bird.RoutingTables) {
+				rt.RulePriority = 32766
+			},
+			wantErr: true,
+		},
+		{
+			name: "other ranges",
+			modify: func(rt *bird.RoutingTables) {
+				rt.TableID = 1000
+				rt.BlackholeTableID = 1001
+				rt.NetworkTableID = 2000
+				rt.RulePriority = 2000
+				rt.NetworkRulePriority = 1999
+			},
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tables := bird.DefaultRoutingTables()
+			tt.modify(&tables)
+
+			if err := tables.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("RoutingTables.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRoutingTables_Conflicts(t *testing.T) {
+	tables := bird.RoutingTables{
+		TableID:             6096,
+		BlackholeTableID:    6097,
+		RulePriority:        6100,
+		NetworkTableID:      6100,
+		NetworkRulePriority: 6099,
+	}
+
+	_, vip, _ := net.ParseCIDR("20.0.0.1/32")
+
+	err := bird.SetPolicyRoutes([]string{vip.String()}, tables.TableID, tables.RulePriority)
+	if err != nil {
+		t.Fatalf("SetPolicyRoutes() error = %v", err)
+	}
+
+	t.Cleanup(func() {
+		_ = bird.SetPolicyRoutes([]string{}, tables.TableID, tables.RulePriority)
+	})
+
+	conflicts, err := tables.Conflicts()
+	if err != nil {
+		t.Fatalf("RoutingTables.Conflicts() error = %v", err)
+	}
+
+	if len(conflicts) != 0 {
+		t.Errorf("RoutingTables.Conflicts() = %v, want none", conflicts)
+	}
+
+	// a rule of another component using the priority of the policy rules.
+	foreignRule := netlink.NewRule()
+	foreignRule.Priority = tables.RulePriority
+	foreignRule.Table = unix.RT_TABLE_MAIN
+	foreignRule.Src = vip
+
+	// a route of another component in the blackhole table.
+	foreignRoute := &netlink.Route{
+		Type:  unix.RTN_BLACKHOLE,
+		Table: tables.BlackholeTableID,
+		Dst:   vip,
+	}
+
+	if err := netlink.RuleAdd(foreignRule); err != nil {
+		t.Fatalf("RuleAdd() error = %v", err)
+	}
+
+	t.Cleanup(func() {
+		_ = netlink.RuleDel(foreignRule)
+	})
+
+	if err := netlink.RouteAdd(foreignRoute); err != nil {
+		t.Fatalf("RouteAdd() error = %v", err)
+	}
+
+	t.Cleanup(func() {
+		_ = netlink.RouteDel(foreignRoute)
+	})
+
+	conflicts, err = tables.Conflicts()
+	if err != nil {
+		t.Fatalf("RoutingTables.Conflicts() error = %v", err)
+	}
+
+	want := []string{
+		"rule 6100: from 20.0.0.1/32 table 254",
+		"route 20.0.0.1/32 table 6097 proto 3",
+	}
+
+	if strings.Join(conflicts, ",") != strings.Join(want, ",") {
+		t.Errorf("RoutingTables.Conflicts() = %v, want %v", conflicts, want)
+	}
+
+	// the blackhole table is not used by FRR and GoBGP.
+	conflicts, err = tables.PolicyRouteConflicts()
+	if err != nil {
+		t.Fatalf("RoutingTables.PolicyRouteConflicts() error = %v", err)
+	}
+
+	want = []string{
+		"rule 6100: from 20.0.0.1/32 table 254",
+	}
+
+	if strings.Join(conflicts, ",") != strings.Join(want, ",") {
+		t.Errorf("RoutingTables.PolicyRouteConflicts() = %v, want %v", conflicts, want)
+	}
+}
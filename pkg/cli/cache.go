@@ -0,0 +1,90 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"github.com/spf13/cobra"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CacheOptions represents the options of the cache of a controller manager.
+type CacheOptions struct {
+	// WatchNamespaces are the namespaces the namespaced resources are watched in (all if empty).
+	WatchNamespaces []string
+}
+
+// SetCacheFlags sets the flags for the cache options.
+func (co *CacheOptions) SetCacheFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(
+		&co.WatchNamespaces,
+		"watch-namespaces",
+		nil,
+		"Namespaces the resources are watched in (all namespaces if empty), so the RBAC can be restricted to them.",
+	)
+}
+
+// SetManagerCacheOptions restricts the cache of the manager to the watched namespaces.
+func (co *CacheOptions) SetManagerCacheOptions(options *ctrl.Options) {
+	if len(co.WatchNamespaces) == 0 {
+		return
+	}
+
+	options.Cache.DefaultNamespaces = map[string]cache.Config{}
+
+	for _, namespace := range co.WatchNamespaces {
+		options.Cache.DefaultNamespaces[namespace] = cache.Config{}
+	}
+}
+
+// SetGatewayCacheOptions restricts the cache of the manager of a gateway data plane to the namespace
+// of the gateway. Only the crossNamespaceObjects (e.g. the L34Routes attached to the gateway) are
+// cached in the watched namespaces (all namespaces if empty).
+func (co *CacheOptions) SetGatewayCacheOptions(
+	options *ctrl.Options,
+	namespace string,
+	crossNamespaceObjects ...client.Object,
+) {
+	options.Cache.DefaultNamespaces = map[string]cache.Config{
+		namespace: {},
+	}
+
+	namespaces := map[string]cache.Config{
+		cache.AllNamespaces: {},
+	}
+
+	if len(co.WatchNamespaces) > 0 {
+		namespaces = map[string]cache.Config{
+			namespace: {},
+		}
+
+		for _, watchNamespace := range co.WatchNamespaces {
+			namespaces[watchNamespace] = cache.Config{}
+		}
+	}
+
+	if options.Cache.ByObject == nil {
+		options.Cache.ByObject = map[client.Object]cache.ByObject{}
+	}
+
+	for _, object := range crossNamespaceObjects {
+		options.Cache.ByObject[object] = cache.ByObject{
+			Namespaces: namespaces,
+		}
+	}
+}
@@ -0,0 +1,112 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/api/v1alpha1"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/cli"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+)
+
+func TestSetManagerCacheOptions(t *testing.T) {
+	tests := []struct {
+		name            string
+		watchNamespaces []string
+		want            map[string]cache.Config
+	}{
+		{
+			name:            "all namespaces",
+			watchNamespaces: nil,
+			want:            nil,
+		},
+		{
+			name:            "single namespace",
+			watchNamespaces: []string{"ns-a"},
+			want: map[string]cache.Config{
+				"ns-a": {},
+			},
+		},
+		{
+			name:            "multiple namespaces",
+			watchNamespaces: []string{"ns-a", "ns-b", "ns-c"},
+			want: map[string]cache.Config{
+				"ns-a": {},
+				"ns-b": {},
+				"ns-c": {},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			co := &cli.CacheOptions{WatchNamespaces: tt.watchNamespaces}
+			options := &ctrl.Options{}
+
+			co.SetManagerCacheOptions(options)
+
+			if !reflect.DeepEqual(options.Cache.DefaultNamespaces, tt.want) {
+				t.Errorf("SetManagerCacheOptions() = %v, want %v", options.Cache.DefaultNamespaces, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetGatewayCacheOptions(t *testing.T) {
+	tests := []struct {
+		name            string
+		watchNamespaces []string
+		want            map[string]cache.Config
+	}{
+		{
+			name:            "all namespaces",
+			watchNamespaces: nil,
+			want: map[string]cache.Config{
+				cache.AllNamespaces: {},
+			},
+		},
+		{
+			name:            "multiple namespaces",
+			watchNamespaces: []string{"ns-a", "ns-b"},
+			want: map[string]cache.Config{
+				"ns-gateway": {},
+				"ns-a":       {},
+				"ns-b":       {},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			co := &cli.CacheOptions{WatchNamespaces: tt.watchNamespaces}
+			options := &ctrl.Options{}
+			route := &v1alpha1.L34Route{}
+
+			co.SetGatewayCacheOptions(options, "ns-gateway", route)
+
+			wantDefault := map[string]cache.Config{"ns-gateway": {}}
+			if !reflect.DeepEqual(options.Cache.DefaultNamespaces, wantDefault) {
+				t.Errorf("SetGatewayCacheOptions() default = %v, want %v", options.Cache.DefaultNamespaces, wantDefault)
+			}
+
+			if !reflect.DeepEqual(options.Cache.ByObject[route].Namespaces, tt.want) {
+				t.Errorf("SetGatewayCacheOptions() L34Route = %v, want %v", options.Cache.ByObject[route].Namespaces, tt.want)
+			}
+		})
+	}
+}
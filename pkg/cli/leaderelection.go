@@ -0,0 +1,101 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	defaultLeaseDuration = 15 * time.Second
+	defaultRenewDeadline = 10 * time.Second
+	defaultRetryPeriod   = 2 * time.Second
+)
+
+// LeaderElectionOptions represents the leader election options of a controller manager,
+// so several replicas can run with a single one reconciling the resources. The webhooks
+// are served by every replica.
+type LeaderElectionOptions struct {
+	LeaderElect             bool
+	LeaderElectionID        string
+	LeaderElectionNamespace string
+	LeaseDuration           time.Duration
+	RenewDeadline           time.Duration
+	RetryPeriod             time.Duration
+}
+
+// SetLeaderElectionFlags sets the flags for the leader election options, the lease
+// is named after the id in parameter by default.
+func (leo *LeaderElectionOptions) SetLeaderElectionFlags(cmd *cobra.Command, id string) {
+	cmd.Flags().BoolVar(
+		&leo.LeaderElect,
+		"leader-elect",
+		false,
+		"Enable leader election, so only one replica of the controller manager reconciles the resources.",
+	)
+
+	cmd.Flags().StringVar(
+		&leo.LeaderElectionID,
+		"leader-election-id",
+		id,
+		"Name of the lease used for the leader election.",
+	)
+
+	cmd.Flags().StringVar(
+		&leo.LeaderElectionNamespace,
+		"leader-election-namespace",
+		"",
+		"Namespace of the lease used for the leader election (namespace of the pod if empty).",
+	)
+
+	cmd.Flags().DurationVar(
+		&leo.LeaseDuration,
+		"leader-election-lease-duration",
+		defaultLeaseDuration,
+		"Duration the non-leader replicas wait before trying to acquire the lease.",
+	)
+
+	cmd.Flags().DurationVar(
+		&leo.RenewDeadline,
+		"leader-election-renew-deadline",
+		defaultRenewDeadline,
+		"Duration the leader retries to renew the lease before giving up the leadership.",
+	)
+
+	cmd.Flags().DurationVar(
+		&leo.RetryPeriod,
+		"leader-election-retry-period",
+		defaultRetryPeriod,
+		"Duration the replicas wait between tries to acquire or renew the lease.",
+	)
+}
+
+// SetManagerOptions sets the leader election options of the manager.
+func (leo *LeaderElectionOptions) SetManagerOptions(options *ctrl.Options) {
+	options.LeaderElection = leo.LeaderElect
+	options.LeaderElectionID = leo.LeaderElectionID
+	options.LeaderElectionNamespace = leo.LeaderElectionNamespace
+	options.LeaseDuration = &leo.LeaseDuration
+	options.RenewDeadline = &leo.RenewDeadline
+	options.RetryPeriod = &leo.RetryPeriod
+	// the lease is released when the manager is stopped so another replica takes
+	// over without waiting for the lease to expire.
+	options.LeaderElectionReleaseOnCancel = true
+}
@@ -0,0 +1,127 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gatewayclass
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/log"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	gatewayapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+// Controller reconciles the GatewayClass objects to accept the classes owned by a
+// controller manager and to publish the features it supports.
+type Controller struct {
+	client.Client
+	// ControllerName is the name of the controller owning the gateway classes (the
+	// controllerName of the GatewayClass), e.g. l-3-4-gateway-api-poc/stateless-load-balancer.
+	ControllerName string
+	// SupportedFeatures are the features supported by the controller.
+	SupportedFeatures []gatewayapiv1.SupportedFeature
+}
+
+// Reconcile implements the reconciliation of the GatewayClasses owned by the controller.
+// This function is trigger by any change (create/update/delete) of a GatewayClass.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.13.0/pkg/reconcile
+func (c *Controller) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	gatewayClass := &gatewayapiv1.GatewayClass{}
+
+	err := c.Get(ctx, req.NamespacedName, gatewayClass)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+
+		return ctrl.Result{}, fmt.Errorf("failed to get the gateway class: %w", err)
+	}
+
+	if string(gatewayClass.Spec.ControllerName) != c.ControllerName {
+		return ctrl.Result{}, nil
+	}
+
+	status := gatewayClass.Status.DeepCopy()
+
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               string(gatewayapiv1.GatewayClassConditionStatusAccepted),
+		Status:             metav1.ConditionTrue,
+		Reason:             string(gatewayapiv1.GatewayClassReasonAccepted),
+		Message:            "The gateway class is accepted by " + c.ControllerName,
+		ObservedGeneration: gatewayClass.GetGeneration(),
+	})
+
+	// the supported features must be sorted in ascending alphabetical order.
+	status.SupportedFeatures = slices.Clone(c.SupportedFeatures)
+	slices.Sort(status.SupportedFeatures)
+
+	if reflect.DeepEqual(status, &gatewayClass.Status) {
+		return ctrl.Result{}, nil
+	}
+
+	gatewayClass.Status = *status
+
+	err = c.Status().Update(ctx, gatewayClass)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to update the gateway class status: %w", err)
+	}
+
+	log.FromContextOrGlobal(ctx).Info("gateway class accepted", "gatewayClass", gatewayClass.GetName())
+
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (c *Controller) SetupWithManager(mgr ctrl.Manager) error {
+	err := ctrl.NewControllerManagedBy(mgr).
+		For(&gatewayapiv1.GatewayClass{}).
+		Complete(c)
+	if err != nil {
+		return fmt.Errorf("failed to build the gateway class controller: %w", err)
+	}
+
+	return nil
+}
+
+// Manages returns true if the gateway belongs to the controller: the gateway class name
+// of the gateway is the name of the controller, or the GatewayClass of the gateway is
+// owned by the controller. False is returned without error only if the GatewayClass
+// exists and is owned by another controller, a missing GatewayClass is returned as a
+// NotFound error since the gateway might still belong to the controller once it is created.
+func Manages(ctx context.Context, c client.Reader, controllerName string, gateway *gatewayapiv1.Gateway) (bool, error) {
+	if string(gateway.Spec.GatewayClassName) == controllerName {
+		return true, nil
+	}
+
+	gatewayClass := &gatewayapiv1.GatewayClass{}
+
+	err := c.Get(ctx, types.NamespacedName{Name: string(gateway.Spec.GatewayClassName)}, gatewayClass)
+	if err != nil {
+		return false, fmt.Errorf("failed to get the gateway class %s: %w", gateway.Spec.GatewayClassName, err)
+	}
+
+	return string(gatewayClass.Spec.ControllerName) == controllerName, nil
+}
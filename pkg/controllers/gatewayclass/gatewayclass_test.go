@@ -0,0 +1,96 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gatewayclass_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/controllers/gatewayclass"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	gatewayapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+func TestManages(t *testing.T) {
+	tests := []struct {
+		name             string
+		gatewayClassName string
+		want             bool
+		wantNotFound     bool
+	}{
+		{
+			name:             "class named after the controller",
+			gatewayClassName: "controller",
+			want:             true,
+		},
+		{
+			name:             "class owned by the controller",
+			gatewayClassName: "owned",
+			want:             true,
+		},
+		{
+			name:             "class owned by another controller",
+			gatewayClassName: "other",
+			want:             false,
+		},
+		{
+			name:             "missing class",
+			gatewayClassName: "missing",
+			want:             false,
+			wantNotFound:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+
+			err := gatewayapiv1.Install(scheme)
+			if err != nil {
+				t.Fatalf("Install() error = %v", err)
+			}
+
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+				getGatewayClass("owned", "controller"),
+				getGatewayClass("other", "other-controller"),
+			).Build()
+
+			gateway := &gatewayapiv1.Gateway{
+				ObjectMeta: v1meta.ObjectMeta{Name: "gw", Namespace: "ns"},
+				Spec:       gatewayapiv1.GatewaySpec{GatewayClassName: gatewayapiv1.ObjectName(tt.gatewayClassName)},
+			}
+
+			got, err := gatewayclass.Manages(context.TODO(), c, "controller", gateway)
+			if (err != nil) != tt.wantNotFound || (err != nil && !apierrors.IsNotFound(err)) {
+				t.Fatalf("Manages() error = %v, wantNotFound %v", err, tt.wantNotFound)
+			}
+
+			if got != tt.want {
+				t.Errorf("Manages() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func getGatewayClass(name string, controllerName string) *gatewayapiv1.GatewayClass {
+	return &gatewayapiv1.GatewayClass{
+		ObjectMeta: v1meta.ObjectMeta{Name: name},
+		Spec:       gatewayapiv1.GatewayClassSpec{ControllerName: gatewayapiv1.GatewayController(controllerName)},
+	}
+}
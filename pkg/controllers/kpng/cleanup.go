@@ -0,0 +1,103 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kpng
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/endpointslice"
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	gatewayapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+// finalizer keeps the gateways until their data plane is drained and their endpointslices removed.
+const finalizer = "l-3-4-gateway-api-poc/" + controllerManagerName
+
+// cleanup removes the data plane and the endpointslices of a gateway which is deleted or
+// not handled by this controller (e.g. its class changed), and then releases the gateway.
+func (c *Controller) cleanup(ctx context.Context, gateway *gatewayapiv1.Gateway) error {
+	if !c.DisabledDaemonSet {
+		deleted, err := c.deleteKPNGDaemonSet(ctx, gateway)
+		if err != nil {
+			return err
+		}
+
+		// the gateway is reconciled again once the daemonset is gone.
+		if !deleted {
+			return nil
+		}
+	}
+
+	// the endpointslices are only generated for the gateways with the finalizer, so they are
+	// not searched for each reconciliation of the gateways of other classes.
+	if !controllerutil.ContainsFinalizer(gateway, finalizer) {
+		return nil
+	}
+
+	err := endpointslice.DeleteStale(ctx, c.Client, controllerManagerName, gateway, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete the endpointslices: %w", err)
+	}
+
+	if controllerutil.RemoveFinalizer(gateway, finalizer) {
+		err = c.Update(ctx, gateway)
+		if err != nil {
+			return fmt.Errorf("failed to remove the finalizer of the gateway: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// deleteKPNGDaemonSet deletes the kpng daemonset of the gateway in foreground, so the daemonset
+// is kept until its pods are terminated and drained. true is returned once the daemonset is gone.
+func (c *Controller) deleteKPNGDaemonSet(ctx context.Context, gateway *gatewayapiv1.Gateway) (bool, error) {
+	daemonSet := &appsv1.DaemonSet{}
+
+	err := c.Get(ctx, types.NamespacedName{
+		Name:      getKPNGDaemonSetName(gateway),
+		Namespace: gateway.Namespace,
+	}, daemonSet)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+
+		return false, fmt.Errorf("failed to get the kpng daemonset: %w", err)
+	}
+
+	if !metav1.IsControlledBy(daemonSet, gateway) {
+		return true, nil
+	}
+
+	if !daemonSet.GetDeletionTimestamp().IsZero() {
+		return false, nil
+	}
+
+	err = c.Delete(ctx, daemonSet, client.PropagationPolicy(metav1.DeletePropagationForeground))
+	if err != nil && !apierrors.IsNotFound(err) {
+		return false, fmt.Errorf("failed to delete the kpng daemonset: %w", err)
+	}
+
+	return false, nil
+}
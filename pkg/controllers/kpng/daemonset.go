@@ -34,6 +34,9 @@ const (
 	label               = "app"
 	routerContainerName = "router"
 	kpngContainerName   = "kpng"
+
+	// controllerManagerName identifies the resources managed by this controller.
+	controllerManagerName = "kpng-controller-manager"
 )
 
 func (c *Controller) reconcileKPNGDaemonSet(
@@ -27,6 +27,7 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	gatewayapiv1 "sigs.k8s.io/gateway-api/apis/v1"
 )
 
 type createUpdateEndpointSliceFunc func(
@@ -38,6 +39,7 @@ type createUpdateEndpointSliceFunc func(
 // reconcileEndpointSlices reconciles the EndpointSlices for IPv4 and IPv6 for a specific service.
 func (c *Controller) reconcileEndpointSlices(
 	ctx context.Context,
+	gateway *gatewayapiv1.Gateway,
 	service *v1.Service,
 	pods *v1.PodList,
 	networks []*v1alpha1.Network,
@@ -75,6 +77,7 @@ func (c *Controller) reconcileEndpointSlices(
 	// reconcile ipv4 endpointslice
 	err = c.reconcileEndpointSlice(
 		ctx,
+		gateway,
 		service,
 		pods,
 		v1discovery.AddressTypeIPv4,
@@ -88,6 +91,7 @@ func (c *Controller) reconcileEndpointSlices(
 	// reconcile ipv6 endpointslice
 	err = c.reconcileEndpointSlice(
 		ctx,
+		gateway,
 		service,
 		pods,
 		v1discovery.AddressTypeIPv6,
@@ -103,6 +107,7 @@ func (c *Controller) reconcileEndpointSlices(
 
 func (c *Controller) reconcileEndpointSlice(
 	ctx context.Context,
+	gateway *gatewayapiv1.Gateway,
 	service *v1.Service,
 	pods *v1.PodList,
 	addressType v1discovery.AddressType,
@@ -120,6 +125,8 @@ func (c *Controller) reconcileEndpointSlice(
 		return fmt.Errorf("failed to reconcile %v EndpointSlice: %w", addressType, err)
 	}
 
+	endpointslice.SetOwnership(endpointSlice, controllerManagerName, gateway)
+
 	if c.SetPortsInEndpointSlices {
 		for _, port := range service.Spec.Ports {
 			endpointSlice.Ports = append(endpointSlice.Ports, v1discovery.EndpointPort{
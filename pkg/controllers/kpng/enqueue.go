@@ -20,8 +20,10 @@ import (
 	"context"
 
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/api/v1alpha1"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/controllers/gatewayclass"
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/log"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/kubernetes/pkg/proxy/apis"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -56,7 +58,8 @@ func (c *Controller) podEnqueue(
 ) []reconcile.Request {
 	reconcileRequests := []reconcile.Request{}
 
-	services := c.getServicesForGateways(ctx, c.getGatewaysForGatewayClass(ctx))
+	// the services select the pods of their namespace, which is the namespace of their gateway.
+	services := c.getServicesForGateways(ctx, c.getGatewaysForGatewayClass(ctx, object.GetNamespace()))
 
 items:
 	for _, service := range services {
@@ -98,7 +101,9 @@ func (c *Controller) getServicesForGateways(ctx context.Context, gateways []gate
 			serviceList,
 			client.MatchingLabels{
 				apis.LabelServiceProxyName: gateway.Name,
-			}) // todo: filter namespace
+			},
+			client.InNamespace(gateway.GetNamespace()),
+		)
 		if err != nil {
 			log.FromContextOrGlobal(ctx).Error(err, "failed listing the services during the pod enqueue")
 		}
@@ -109,7 +114,8 @@ func (c *Controller) getServicesForGateways(ctx context.Context, gateways []gate
 	return services
 }
 
-func (c *Controller) getGatewaysForGatewayClass(ctx context.Context) []gatewayapiv1.Gateway {
+// getGatewaysForGatewayClass returns the gateways of the gateway class in the namespace.
+func (c *Controller) getGatewaysForGatewayClass(ctx context.Context, namespace string) []gatewayapiv1.Gateway {
 	gatewayList := &gatewayapiv1.GatewayList{}
 
 	// err := c.List(ctx,
@@ -118,7 +124,9 @@ func (c *Controller) getGatewaysForGatewayClass(ctx context.Context) []gatewayap
 	// 		"spec.gatewayClassName": c.GatewayClassName,
 	// 	})
 	err := c.List(ctx,
-		gatewayList) // todo: filter namespace
+		gatewayList,
+		client.InNamespace(namespace),
+	)
 	if err != nil {
 		log.FromContextOrGlobal(ctx).Error(err, "failed listing the gateways during the pod enqueue")
 
@@ -128,7 +136,12 @@ func (c *Controller) getGatewaysForGatewayClass(ctx context.Context) []gatewayap
 	gateways := []gatewayapiv1.Gateway{}
 
 	for _, gateway := range gatewayList.Items {
-		if string(gateway.Spec.GatewayClassName) != c.GatewayClassName {
+		manages, err := gatewayclass.Manages(ctx, c, c.GatewayClassName, &gateway)
+		if err != nil && !apierrors.IsNotFound(err) {
+			log.FromContextOrGlobal(ctx).Error(err, "failed to check the class of the gateway", "gateway", gateway.GetName())
+		}
+
+		if !manages {
 			continue
 		}
 
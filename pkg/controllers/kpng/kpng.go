@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/controllers/gatewayclass"
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/endpointslice"
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/log"
 	appsv1 "k8s.io/api/apps/v1"
@@ -29,6 +30,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	gatewayapiv1 "sigs.k8s.io/gateway-api/apis/v1"
 )
@@ -63,11 +65,33 @@ func (c *Controller) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		return ctrl.Result{}, fmt.Errorf("failed to get the gateway: %w", err)
 	}
 
-	if string(gateway.Spec.GatewayClassName) != c.GatewayClassName {
-		// this should not happen if the controller is configured correctly.
+	manages, err := gatewayclass.Manages(ctx, c, c.GatewayClassName, gateway)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return ctrl.Result{}, fmt.Errorf("failed to check the class of the gateway: %w", err)
+	}
+
+	// the gateway is deleted or its class is (now) owned by another controller. The
+	// gateway is left untouched while its class does not exist.
+	if !gateway.GetDeletionTimestamp().IsZero() || (err == nil && !manages) {
+		err = c.cleanup(ctx, gateway)
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to clean up the gateway: %w", err)
+		}
+
 		return ctrl.Result{}, nil
 	}
 
+	if !manages {
+		return ctrl.Result{}, nil
+	}
+
+	if controllerutil.AddFinalizer(gateway, finalizer) {
+		err = c.Update(ctx, gateway)
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to add the finalizer to the gateway: %w", err)
+		}
+	}
+
 	if !c.DisabledDaemonSet {
 		err = c.reconcileKPNGDaemonSet(ctx, gateway)
 		if err != nil {
@@ -84,6 +108,15 @@ func (c *Controller) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 
 	log.FromContextOrGlobal(ctx).Info("KPNG services and endpointslices reconciled")
 
+	// the status of the gateways whose data plane is not deployed by this controller
+	// (e.g. istio) is set by the controller deploying it.
+	if !c.DisabledDaemonSet {
+		err = c.reconcileGatewayStatus(ctx, gateway)
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to reconcile the gateway status: %w", err)
+		}
+	}
+
 	return ctrl.Result{}, nil
 }
 
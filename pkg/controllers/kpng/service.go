@@ -21,8 +21,10 @@ import (
 	"fmt"
 
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/api/v1alpha1"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/endpointslice"
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/networkattachment"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/kubernetes/pkg/proxy/apis"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	gatewayapiv1 "sigs.k8s.io/gateway-api/apis/v1"
@@ -39,25 +41,29 @@ func (c *Controller) reconcileServices(ctx context.Context, gateway *gatewayapiv
 		apis.LabelServiceProxyName: gateway.Name,
 	}
 
-	err := c.List(ctx, services, matchingLabels) // todo: filter namespace
+	err := c.List(ctx, services, matchingLabels, client.InNamespace(gateway.GetNamespace()))
 	if err != nil {
 		return fmt.Errorf("failed to list services: %w", err)
 	}
 
 	serviceIPs := []string{}
+	reconciledServices := []*v1.Service{}
 
 	for _, service := range services.Items {
 		s := service
 
-		err = c.reconcileService(ctx, &s, networks)
+		err = c.reconcileService(ctx, gateway, &s, networks)
 		if err != nil {
 			return err
 		}
 
+		reconciledServices = append(reconciledServices, &s)
+
 		serviceIPs = append(serviceIPs, service.Spec.ExternalIPs...)
 	}
 
 	// update gateway status IPs with service IPs
+	previousAddresses := gateway.Status.Addresses
 	gateway.Status.Addresses = []gatewayapiv1.GatewayStatusAddress{}
 
 	for _, serviceIP := range serviceIPs {
@@ -67,18 +73,28 @@ func (c *Controller) reconcileServices(ctx context.Context, gateway *gatewayapiv
 		})
 	}
 
-	err = c.Status().Update(ctx, gateway)
-	if err != nil {
-		return fmt.Errorf("failed to update gateway status: %w", err)
+	if !equality.Semantic.DeepEqual(previousAddresses, gateway.Status.Addresses) {
+		err = c.Status().Update(ctx, gateway)
+		if err != nil {
+			return fmt.Errorf("failed to update gateway status: %w", err)
+		}
 	}
 
-	// todo: cleanup old endpointslices
+	err = endpointslice.DeleteStale(ctx, c.Client, controllerManagerName, gateway, reconciledServices)
+	if err != nil {
+		return fmt.Errorf("failed to delete the stale endpointslices: %w", err)
+	}
 
 	return nil
 }
 
 // reconcileService reconciles a specific service.
-func (c *Controller) reconcileService(ctx context.Context, service *v1.Service, networks []*v1alpha1.Network) error {
+func (c *Controller) reconcileService(
+	ctx context.Context,
+	gateway *gatewayapiv1.Gateway,
+	service *v1.Service,
+	networks []*v1alpha1.Network,
+) error {
 	// Get pods for this service so the endpointslices can be reconciled.
 	var matchingLabels client.MatchingLabels = service.Spec.Selector
 
@@ -95,7 +111,7 @@ func (c *Controller) reconcileService(ctx context.Context, service *v1.Service,
 		return fmt.Errorf("failed to list the pods: %w", err)
 	}
 
-	return c.reconcileEndpointSlices(ctx, service, pods, networks)
+	return c.reconcileEndpointSlices(ctx, gateway, service, pods, networks)
 }
 
 func ptrTo[T any](a T) *T {
@@ -0,0 +1,78 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kpng
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/api/v1alpha1"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/gatewaystatus"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	gatewayapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+	"sigs.k8s.io/gateway-api/pkg/features"
+)
+
+// SupportedFeatures are the features supported by the kpng gateway class.
+var SupportedFeatures = []gatewayapiv1.SupportedFeature{
+	gatewayapiv1.SupportedFeature(features.SupportGateway),
+}
+
+// reconcileGatewayStatus sets the conditions and the listener statuses of the gateway from
+// the readiness of the kpng daemonset. No route can be attached to the listeners, the
+// services select the gateway with the service-proxy-name label, so no route kind is
+// supported and no route is attached.
+func (c *Controller) reconcileGatewayStatus(ctx context.Context, gateway *gatewayapiv1.Gateway) error {
+	daemonSet := &appsv1.DaemonSet{}
+
+	err := c.Get(ctx, types.NamespacedName{
+		Name:      getKPNGDaemonSetName(gateway),
+		Namespace: gateway.Namespace,
+	}, daemonSet)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to get the kpng daemonset: %w", err)
+		}
+
+		daemonSet = nil
+	}
+
+	previousStatus := gateway.Status.DeepCopy()
+
+	gatewaystatus.Set(gateway, gatewaystatus.DaemonSetWorkload(daemonSet), gatewaystatus.Options{
+		Protocols: []gatewayapiv1.ProtocolType{
+			gatewayapiv1.TCPProtocolType,
+			gatewayapiv1.UDPProtocolType,
+			gatewayapiv1.ProtocolType(v1alpha1.SCTP),
+		},
+	})
+
+	// the status is written only if it changed, so the gateway is not reconciled again for nothing.
+	if equality.Semantic.DeepEqual(previousStatus, &gateway.Status) {
+		return nil
+	}
+
+	err = c.Status().Update(ctx, gateway)
+	if err != nil {
+		return fmt.Errorf("failed to update gateway status: %w", err)
+	}
+
+	return nil
+}
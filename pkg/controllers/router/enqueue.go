@@ -19,6 +19,9 @@ package router
 import (
 	"context"
 
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/api/v1alpha1"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/l34route"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/log"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/kubernetes/pkg/proxy/apis"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -43,3 +46,92 @@ func gatewayRouterEnqueue(
 		},
 	}
 }
+
+// secretEnqueue enqueues the gateway if the secret is referenced by the BGP or OSPF
+// authentication of one of its gateway routers, so a password rotation is applied.
+func (c *Controller) secretEnqueue(
+	ctx context.Context,
+	object client.Object,
+) []reconcile.Request {
+	gatewayRouterList := &v1alpha1.GatewayRouterList{}
+
+	err := c.List(ctx,
+		gatewayRouterList,
+		client.MatchingLabels{
+			apis.LabelServiceProxyName: c.Name,
+		},
+		client.InNamespace(object.GetNamespace()),
+	)
+	if err != nil {
+		log.FromContextOrGlobal(ctx).Error(err, "failed listing the gateway routers during the secret enqueue")
+
+		return []reconcile.Request{}
+	}
+
+	for _, gatewayRouter := range gatewayRouterList.Items {
+		auth := gatewayRouterAuth(&gatewayRouter)
+		if auth == nil || auth.KeySource != object.GetName() {
+			continue
+		}
+
+		return []reconcile.Request{
+			{
+				NamespacedName: types.NamespacedName{
+					Name:      c.Name,
+					Namespace: gatewayRouter.GetNamespace(),
+				},
+			},
+		}
+	}
+
+	return []reconcile.Request{}
+}
+
+func (c *Controller) l34RouteEnqueue(
+	ctx context.Context,
+	object client.Object,
+) []reconcile.Request {
+	l34Route, ok := object.(*v1alpha1.L34Route)
+	if !ok {
+		return []reconcile.Request{}
+	}
+
+	for _, parentRef := range l34Route.Spec.ParentRefs {
+		gateway, ok := l34route.ParentGateway(l34Route, parentRef)
+		if ok && gateway.Name == c.Name && gateway.Namespace == c.Namespace {
+			return c.gatewayEnqueue(ctx, object)
+		}
+	}
+
+	return []reconcile.Request{}
+}
+
+// gatewayEnqueue enqueues the gateway served by the router. This is used for the objects
+// (Namespace) which can change which routes are attached to the gateway.
+func (c *Controller) gatewayEnqueue(
+	_ context.Context,
+	_ client.Object,
+) []reconcile.Request {
+	return []reconcile.Request{
+		{
+			NamespacedName: types.NamespacedName{
+				Name:      c.Name,
+				Namespace: c.Namespace,
+			},
+		},
+	}
+}
+
+// gatewayRouterAuth returns the authentication of the routing protocol of the
+// gateway router, or nil if the gateway router has no authentication.
+func gatewayRouterAuth(gatewayRouter *v1alpha1.GatewayRouter) *v1alpha1.BgpAuth {
+	switch gatewayRouter.Spec.Protocol {
+	case "", v1alpha1.BGP:
+		return gatewayRouter.Spec.Bgp.Auth
+	case v1alpha1.OSPF:
+		return gatewayRouter.Spec.Ospf.Auth
+	case v1alpha1.Static:
+	}
+
+	return nil
+}
@@ -21,7 +21,7 @@ import (
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/bird"
 )
 
-func newGateway(gw *v1alpha1.GatewayRouter) *gateway {
+func newGateway(gw *v1alpha1.GatewayRouter, password string) *gateway {
 	protocol := gw.Spec.Protocol
 	if protocol == "" {
 		protocol = v1alpha1.BGP
@@ -34,14 +34,24 @@ func newGateway(gw *v1alpha1.GatewayRouter) *gateway {
 		protocol: protocol,
 	}
 
+	if gw.Spec.Import != nil {
+		newGw.importPolicy = &importPolicy{
+			gw.Spec.Import,
+		}
+	}
+
 	switch newGw.GetProtocol() {
 	case v1alpha1.BGP:
 		newGw.bgp = &bgpSpec{
-			remoteASN:  gw.Spec.Bgp.RemoteASN,
-			localASN:   gw.Spec.Bgp.LocalASN,
-			holdTime:   gw.Spec.Bgp.HoldTime,
-			remotePort: gw.Spec.Bgp.RemotePort,
-			localPort:  gw.Spec.Bgp.LocalPort,
+			remoteASN:     gw.Spec.Bgp.RemoteASN,
+			localASN:      gw.Spec.Bgp.LocalASN,
+			holdTime:      gw.Spec.Bgp.HoldTime,
+			remotePort:    gw.Spec.Bgp.RemotePort,
+			localPort:     gw.Spec.Bgp.LocalPort,
+			password:      password,
+			multihop:      gw.Spec.Bgp.Multihop,
+			sourceAddress: gw.Spec.Bgp.SourceAddress,
+			nextHop:       gw.Spec.Bgp.NextHop,
 			bfd: &bfdSpec{
 				sw:         gw.Spec.Bgp.BFD.Switch,
 				minTx:      gw.Spec.Bgp.BFD.MinTx,
@@ -49,6 +59,23 @@ func newGateway(gw *v1alpha1.GatewayRouter) *gateway {
 				multiplier: gw.Spec.Bgp.BFD.Multiplier,
 			},
 		}
+
+		if gw.Spec.Bgp.GracefulRestart != nil {
+			newGw.bgp.gracefulRestart = &gracefulRestart{
+				gw.Spec.Bgp.GracefulRestart,
+			}
+		}
+	case v1alpha1.OSPF:
+		newGw.ospf = &ospfSpec{
+			OspfSpec: &gw.Spec.Ospf,
+			password: password,
+			bfd: &bfdSpec{
+				sw:         gw.Spec.Ospf.BFD.Switch,
+				minTx:      gw.Spec.Ospf.BFD.MinTx,
+				minRx:      gw.Spec.Ospf.BFD.MinRx,
+				multiplier: gw.Spec.Ospf.BFD.Multiplier,
+			},
+		}
 	case v1alpha1.Static:
 		newGw.static = &staticSpec{
 			bfd: &bfdSpec{
@@ -70,6 +97,9 @@ type gateway struct {
 	protocol v1alpha1.RoutingProtocol
 	bgp      *bgpSpec
 	static   *staticSpec
+	ospf     *ospfSpec
+	// importPolicy is nil if the gateway router has no import policy.
+	importPolicy *importPolicy
 }
 
 func (gw *gateway) GetName() string {
@@ -96,6 +126,34 @@ func (gw *gateway) GetStatic() bird.StaticSpec {
 	return gw.static
 }
 
+func (gw *gateway) GetOspfSpec() bird.OspfSpec {
+	return gw.ospf
+}
+
+func (gw *gateway) GetImportPolicy() bird.ImportPolicy {
+	if gw.importPolicy == nil {
+		return nil
+	}
+
+	return gw.importPolicy
+}
+
+type importPolicy struct {
+	*v1alpha1.ImportPolicy
+}
+
+func (ip *importPolicy) GetPrefixes() []string {
+	return ip.Prefixes
+}
+
+func (ip *importPolicy) GetDefaultRoute() *bool {
+	return ip.DefaultRoute
+}
+
+func (ip *importPolicy) GetMaxPrefixes() *uint32 {
+	return ip.MaxPrefixes
+}
+
 type bgpSpec struct {
 	remoteASN  *uint32
 	localASN   *uint32
@@ -103,6 +161,13 @@ type bgpSpec struct {
 	holdTime   string
 	remotePort *uint16
 	localPort  *uint16
+	// password is never logged since the field is not exported.
+	password      string
+	multihop      *uint8
+	sourceAddress string
+	nextHop       string
+	// gracefulRestart is nil if the graceful restart is not configured.
+	gracefulRestart *gracefulRestart
 }
 
 func (bgps *bgpSpec) GetRemoteASN() *uint32 {
@@ -129,6 +194,81 @@ func (bgps *bgpSpec) GetLocalPort() *uint16 {
 	return bgps.localPort
 }
 
+func (bgps *bgpSpec) GetPassword() string {
+	return bgps.password
+}
+
+func (bgps *bgpSpec) GetMultihop() *uint8 {
+	return bgps.multihop
+}
+
+func (bgps *bgpSpec) GetSourceAddress() string {
+	return bgps.sourceAddress
+}
+
+func (bgps *bgpSpec) GetNextHop() string {
+	return bgps.nextHop
+}
+
+func (bgps *bgpSpec) GetGracefulRestart() bird.GracefulRestartSpec {
+	if bgps.gracefulRestart == nil {
+		return nil
+	}
+
+	return bgps.gracefulRestart
+}
+
+type gracefulRestart struct {
+	*v1alpha1.BgpGracefulRestart
+}
+
+func (gr *gracefulRestart) GetSwitch() *bool {
+	return gr.Switch
+}
+
+func (gr *gracefulRestart) GetRestartTime() string {
+	return gr.RestartTime
+}
+
+func (gr *gracefulRestart) GetLongLivedStaleTime() string {
+	return gr.LongLivedStaleTime
+}
+
+type ospfSpec struct {
+	*v1alpha1.OspfSpec
+	// password is never logged since the field is not exported.
+	password string
+	bfd      *bfdSpec
+}
+
+func (ospfs *ospfSpec) GetArea() string {
+	return ospfs.Area
+}
+
+func (ospfs *ospfSpec) GetCost() *uint16 {
+	return ospfs.Cost
+}
+
+func (ospfs *ospfSpec) GetHelloInterval() string {
+	return ospfs.HelloInterval
+}
+
+func (ospfs *ospfSpec) GetDeadInterval() string {
+	return ospfs.DeadInterval
+}
+
+func (ospfs *ospfSpec) GetPassword() string {
+	return ospfs.password
+}
+
+func (ospfs *ospfSpec) GetBfdSpec() bird.BfdSpec {
+	return ospfs.bfd
+}
+
+func (ospfs *ospfSpec) GetVIPAdvertisement() v1alpha1.OspfVIPAdvertisement {
+	return ospfs.VIPAdvertisement
+}
+
 type staticSpec struct {
 	bfd *bfdSpec
 }
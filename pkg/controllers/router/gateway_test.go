@@ -0,0 +1,71 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package router
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/api/v1alpha1"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/bird"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNewGatewayBGP(t *testing.T) {
+	multihop := uint8(3)
+	gatewayRouter := &v1alpha1.GatewayRouter{
+		ObjectMeta: metav1.ObjectMeta{Name: "gateway-v4-a"},
+		Spec: v1alpha1.GatewayRouterSpec{
+			Address:   "169.254.100.150",
+			Interface: "eth0",
+			Protocol:  v1alpha1.BGP,
+			Bgp: v1alpha1.BgpSpec{
+				Multihop:      &multihop,
+				SourceAddress: "10.0.0.1",
+				NextHop:       "169.254.100.1",
+			},
+		},
+	}
+
+	gw := newGateway(gatewayRouter, "secret")
+
+	bgpSpec := gw.GetBgpSpec()
+	if bgpSpec == nil {
+		t.Fatalf("newGateway() bgp spec is nil")
+	}
+
+	if got := bgpSpec.GetMultihop(); got == nil || *got != multihop {
+		t.Errorf("GetMultihop() = %v, want %v", got, multihop)
+	}
+
+	if got := bgpSpec.GetSourceAddress(); got != "10.0.0.1" {
+		t.Errorf("GetSourceAddress() = %v, want %v", got, "10.0.0.1")
+	}
+
+	if got := bgpSpec.GetNextHop(); got != "169.254.100.1" {
+		t.Errorf("GetNextHop() = %v, want %v", got, "169.254.100.1")
+	}
+
+	if got := bgpSpec.GetPassword(); got != "secret" {
+		t.Errorf("GetPassword() = %v, want %v", got, "secret")
+	}
+
+	want := []string{"10.0.0.1/32"}
+	if got := bird.SourceAddressCIDRs([]bird.Gateway{gw}); !reflect.DeepEqual(got, want) {
+		t.Errorf("SourceAddressCIDRs() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,82 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package router
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/readiness"
+	v1meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	gatewayapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+const readinessPollInterval = 1 * time.Second
+
+// filterReadyVIPs keeps only the VIPs for which the local data plane is ready
+// (flow programmed and targets active). All VIPs are returned if the readiness
+// file is not configured.
+func (c *Controller) filterReadyVIPs(vips []string) ([]string, error) {
+	if c.ReadinessFile == "" {
+		return vips, nil
+	}
+
+	ready, err := readiness.Read(c.ReadinessFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the readiness: %w", err)
+	}
+
+	return readiness.Filter(vips, ready), nil
+}
+
+// readinessSource returns a source triggering a reconciliation of the gateway
+// each time the readiness file changes. The file is polled by a runnable added
+// to the manager.
+func (c *Controller) readinessSource(mgr manager.Manager) (source.Source, error) {
+	events := make(chan event.GenericEvent)
+
+	err := mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		for range readiness.Watch(ctx, c.ReadinessFile, readinessPollInterval) {
+			select {
+			case events <- event.GenericEvent{Object: c.gatewayObject()}:
+			case <-ctx.Done():
+			}
+		}
+
+		return nil
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to add the readiness watcher: %w", err)
+	}
+
+	return source.Channel(events, &handler.EnqueueRequestForObject{}), nil
+}
+
+func (c *Controller) gatewayObject() client.Object {
+	return &gatewayapiv1.Gateway{
+		ObjectMeta: v1meta.ObjectMeta{
+			Name:      c.Name,
+			Namespace: c.Namespace,
+		},
+	}
+}
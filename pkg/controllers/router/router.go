@@ -18,14 +18,19 @@ package router
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"net"
+	"sync/atomic"
 
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/api/v1alpha1"
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/bird"
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/log"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/networking"
+	v1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/kubernetes/pkg/proxy/apis"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -33,10 +38,12 @@ import (
 	gatewayapiv1 "sigs.k8s.io/gateway-api/apis/v1"
 )
 
+var errPasswordNotFound = errors.New("the password key does not exist in the secret")
+
 // RoutingSuite defines an interface to configure a routing suite (e.g. bird).
 type RoutingSuite interface {
 	// Apply applies to configuration.
-	Configure(ctx context.Context, vips []string, gateways []bird.Gateway) error
+	Configure(ctx context.Context, vips []bird.VIP, gateways []bird.Gateway) error
 }
 
 // Controller reconciles the Gateway Object to run a router.
@@ -48,6 +55,16 @@ type Controller struct {
 	// Namespace of the gateway in which this controller is running.
 	Namespace            string
 	RoutingSuiteInstance RoutingSuite
+	// ReadinessFile is the file shared with the stateless-load-balancer in which
+	// the VIPs ready to be announced are written. All VIPs are announced if empty.
+	ReadinessFile string
+	// Recorder emits the events on the gateway (e.g. rejected routing configuration).
+	Recorder record.EventRecorder
+	// GracefulShutdown announces the VIPs with the graceful shutdown community (RFC8326)
+	// while draining, instead of withdrawing them.
+	GracefulShutdown bool
+
+	draining atomic.Bool
 }
 
 // Reconcile implements the reconciliation of the Gateway of the router.
@@ -73,23 +90,52 @@ func (c *Controller) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		return ctrl.Result{}, fmt.Errorf("failed to get the gateway: %w", err)
 	}
 
-	vips := getVIPs(gateway)
+	cidrs, err := c.filterReadyVIPs(getVIPs(gateway))
+	if err != nil {
+		return ctrl.Result{}, err
+	}
 
-	gateways, err := c.getGatewayRouters(ctx)
+	gatewayRouters, err := c.listGatewayRouters(ctx)
 	if err != nil {
 		return ctrl.Result{}, fmt.Errorf("failed to get the gateway routers: %w", err)
 	}
 
-	log.FromContextOrGlobal(ctx).Info("Configure RoutingSuiteInstance", "vips", vips, "gateways", gateways)
+	vips, err := c.getBirdVIPs(ctx, gateway, cidrs, gatewayRouters)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to get the VIPs: %w", err)
+	}
+
+	gateways := c.getGatewayRouters(ctx, gatewayRouters)
+
+	log.FromContextOrGlobal(ctx).Info("Configure RoutingSuiteInstance", "vips", cidrs, "gateways", gateways)
 
 	err = c.RoutingSuiteInstance.Configure(ctx, vips, gateways)
 	if err != nil {
+		if c.Recorder != nil {
+			c.Recorder.Eventf(gateway, v1.EventTypeWarning, "RoutingConfigurationFailed", "%v", err)
+		}
+
 		return ctrl.Result{}, fmt.Errorf("failed to set the gateway: %w", err)
 	}
 
 	return ctrl.Result{}, nil
 }
 
+// Drain withdraws the VIPs (or announces them with the graceful shutdown community),
+// so the traffic is attracted by the other routers before this one stops.
+func (c *Controller) Drain(ctx context.Context) error {
+	c.draining.Store(true)
+
+	_, err := c.Reconcile(ctx, ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Name:      c.Name,
+			Namespace: c.Namespace,
+		},
+	})
+
+	return err
+}
+
 // getVIPs gets the list of addresses in the gateway status and converts them to CIDRs.
 func getVIPs(gateway *gatewayapiv1.Gateway) []string {
 	vips := []string{}
@@ -98,18 +144,11 @@ func getVIPs(gateway *gatewayapiv1.Gateway) []string {
 	vipMap := map[string]struct{}{}
 
 	for _, address := range gateway.Status.Addresses {
-		ipAddr := net.ParseIP(address.Value)
-
-		if ipAddr == nil {
+		vip, ok := networking.GatewayAddressCIDR(address)
+		if !ok {
 			continue
 		}
 
-		vip := fmt.Sprintf("%s/32", ipAddr)
-
-		if ipAddr.To4() == nil {
-			vip = fmt.Sprintf("%s/128", ipAddr)
-		}
-
 		_, exists := vipMap[vip]
 		if exists {
 			continue
@@ -123,36 +162,93 @@ func getVIPs(gateway *gatewayapiv1.Gateway) []string {
 	return vips
 }
 
-// getGatewayRouters gets the list of gateways for this gateway.
-func (c *Controller) getGatewayRouters(ctx context.Context) ([]bird.Gateway, error) {
+// listGatewayRouters lists the gateway routers of this gateway.
+func (c *Controller) listGatewayRouters(ctx context.Context) ([]v1alpha1.GatewayRouter, error) {
 	gatewayList := &v1alpha1.GatewayRouterList{}
-	gateways := []bird.Gateway{}
 
 	err := c.List(ctx,
 		gatewayList,
 		client.MatchingLabels{
 			apis.LabelServiceProxyName: c.Name,
-		}) // todo: filter namespace
+		},
+		client.InNamespace(c.Namespace),
+	)
 	if err != nil {
 		return nil, fmt.Errorf("failed listing the flows: %w", err)
 	}
 
-	for _, gateway := range gatewayList.Items {
+	return gatewayList.Items, nil
+}
+
+// getGatewayRouters gets the list of gateways for this gateway.
+func (c *Controller) getGatewayRouters(ctx context.Context, gatewayRouters []v1alpha1.GatewayRouter) []bird.Gateway {
+	gateways := []bird.Gateway{}
+
+	for _, gateway := range gatewayRouters {
 		g := gateway
-		gateways = append(gateways, newGateway(&g))
+
+		password, err := c.getPassword(ctx, &g)
+		if err != nil {
+			// The session is not configured without its password.
+			log.FromContextOrGlobal(ctx).Error(err, "failed to get the password", "gatewayRouter", g.GetName())
+
+			continue
+		}
+
+		gateways = append(gateways, newGateway(&g, password))
 	}
 
-	return gateways, nil
+	return gateways
+}
+
+// getPassword gets the BGP or OSPF password of the gateway router from the Secret
+// referenced by its authentication. An empty password is returned if the gateway
+// router has no authentication.
+func (c *Controller) getPassword(ctx context.Context, gatewayRouter *v1alpha1.GatewayRouter) (string, error) {
+	auth := gatewayRouterAuth(gatewayRouter)
+	if auth == nil {
+		return "", nil
+	}
+
+	secret := &v1.Secret{}
+
+	err := c.Get(ctx, types.NamespacedName{
+		Name:      auth.KeySource,
+		Namespace: gatewayRouter.GetNamespace(),
+	}, secret)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the secret %s: %w", auth.KeySource, err)
+	}
+
+	password, exists := secret.Data[auth.KeyName]
+	if !exists || len(password) == 0 {
+		return "", fmt.Errorf("%w (secret: %s, key: %s)", errPasswordNotFound, auth.KeySource, auth.KeyName)
+	}
+
+	return string(password), nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (c *Controller) SetupWithManager(mgr ctrl.Manager) error {
-	err := ctrl.NewControllerManagedBy(mgr).
+	builder := ctrl.NewControllerManagedBy(mgr).
 		For(&gatewayapiv1.Gateway{}).
 		// With EnqueueRequestsFromMapFunc, on an update the func is called twice
 		// (1 time for old and 1 time for new object)
 		Watches(&v1alpha1.GatewayRouter{}, handler.EnqueueRequestsFromMapFunc(gatewayRouterEnqueue)).
-		Complete(c)
+		Watches(&v1.Secret{}, handler.EnqueueRequestsFromMapFunc(c.secretEnqueue)).
+		Watches(&v1alpha1.L34Route{}, handler.EnqueueRequestsFromMapFunc(c.l34RouteEnqueue)).
+		Watches(&v1.Namespace{}, handler.EnqueueRequestsFromMapFunc(c.gatewayEnqueue))
+
+	if c.ReadinessFile != "" {
+		readinessSource, err := c.readinessSource(mgr)
+		if err != nil {
+			return err
+		}
+
+		builder = builder.WatchesRawSource(readinessSource)
+	}
+
+	err := builder.Complete(c)
 	if err != nil {
 		return fmt.Errorf("failed to build the router manager: %w", err)
 	}
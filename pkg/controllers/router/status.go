@@ -0,0 +1,222 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package router
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/api/v1alpha1"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/bird"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/log"
+	v1meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/kubernetes/pkg/proxy/apis"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	defaultStatusInterval = 30 * time.Second
+	// statusRefreshPeriod is the period after which the status of the router is
+	// updated even if the statistics have not changed.
+	statusRefreshPeriod = 5 * time.Minute
+	// statusStalePeriod is the period after which the status of a router (e.g. a
+	// deleted pod) is removed from the status of the gateway routers.
+	statusStalePeriod = 15 * time.Minute
+)
+
+// ImportStatisticsGetter defines an interface to get the statistics of the routes
+// imported from the gateway routers (e.g. bird).
+type ImportStatisticsGetter interface {
+	// GetImportStatistics returns the import statistics per gateway router name.
+	GetImportStatistics(ctx context.Context) (map[string]*bird.ImportStatistics, error)
+}
+
+// StatusUpdater periodically reports, in the status of the gateway routers, the
+// number of prefixes imported and rejected by the router.
+type StatusUpdater struct {
+	client.Client
+	// Name of the gateway in which this router is running.
+	Name string
+	// Namespace of the gateway in which this router is running.
+	Namespace string
+	// RouterName is the name of this router in the status of the gateway routers (name of the pod).
+	RouterName string
+	// Statistics is the source of the import statistics.
+	Statistics ImportStatisticsGetter
+	// Interval between 2 updates.
+	Interval time.Duration
+}
+
+// Start implements manager.Runnable and updates the status until the context is cancelled.
+func (su *StatusUpdater) Start(ctx context.Context) error {
+	interval := su.Interval
+	if interval <= 0 {
+		interval = defaultStatusInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		err := su.update(ctx)
+		if err != nil {
+			log.FromContextOrGlobal(ctx).Error(err, "failed to update the status of the gateway routers")
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, each router reports its own status.
+func (su *StatusUpdater) NeedLeaderElection() bool {
+	return false
+}
+
+// SetupWithManager sets up the status updater with the Manager.
+func (su *StatusUpdater) SetupWithManager(mgr ctrl.Manager) error {
+	err := mgr.Add(su)
+	if err != nil {
+		return fmt.Errorf("failed to add the status updater: %w", err)
+	}
+
+	return nil
+}
+
+func (su *StatusUpdater) update(ctx context.Context) error {
+	statistics, err := su.Statistics.GetImportStatistics(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get the import statistics: %w", err)
+	}
+
+	gatewayRouterList := &v1alpha1.GatewayRouterList{}
+
+	err = su.List(ctx,
+		gatewayRouterList,
+		client.MatchingLabels{
+			apis.LabelServiceProxyName: su.Name,
+		},
+		client.InNamespace(su.Namespace),
+	)
+	if err != nil {
+		return fmt.Errorf("failed listing the gateway routers: %w", err)
+	}
+
+	var errFinal error
+
+	for _, gatewayRouter := range gatewayRouterList.Items {
+		gatewayRouterStatistics, exists := statistics[gatewayRouter.GetName()]
+		if !exists {
+			continue
+		}
+
+		err := su.updateGatewayRouter(ctx, types.NamespacedName{
+			Name:      gatewayRouter.GetName(),
+			Namespace: gatewayRouter.GetNamespace(),
+		}, gatewayRouterStatistics)
+		if err != nil {
+			errFinal = fmt.Errorf("%w; %w", err, errFinal)
+		}
+	}
+
+	return errFinal
+}
+
+func (su *StatusUpdater) updateGatewayRouter(
+	ctx context.Context,
+	key types.NamespacedName,
+	statistics *bird.ImportStatistics,
+) error {
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		gatewayRouter := &v1alpha1.GatewayRouter{}
+
+		err := su.Get(ctx, key, gatewayRouter)
+		if err != nil {
+			return fmt.Errorf("failed to get the gateway router: %w", err)
+		}
+
+		routers, changed := setRouterStatus(gatewayRouter.Status.Routers, &v1alpha1.RouterStatus{
+			Name:             su.RouterName,
+			ImportedPrefixes: int32(statistics.Imported),
+			RejectedPrefixes: int32(statistics.Rejected),
+			LastUpdateTime:   v1meta.Now(),
+		})
+		if !changed {
+			return nil
+		}
+
+		gatewayRouter.Status.Routers = routers
+
+		err = su.Status().Update(ctx, gatewayRouter)
+		if err != nil {
+			return fmt.Errorf("failed to update the status of the gateway router: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update the status of %s: %w", key, err)
+	}
+
+	return nil
+}
+
+// setRouterStatus sets the status of the router in the list and removes the stale ones.
+// false is returned if the list has not changed.
+func setRouterStatus(
+	routers []v1alpha1.RouterStatus,
+	routerStatus *v1alpha1.RouterStatus,
+) ([]v1alpha1.RouterStatus, bool) {
+	res := []v1alpha1.RouterStatus{}
+	unchanged := false
+	staleRemoved := false
+
+	for _, router := range routers {
+		if router.Name == routerStatus.Name {
+			unchanged = router.ImportedPrefixes == routerStatus.ImportedPrefixes &&
+				router.RejectedPrefixes == routerStatus.RejectedPrefixes &&
+				routerStatus.LastUpdateTime.Sub(router.LastUpdateTime.Time) < statusRefreshPeriod
+
+			if unchanged {
+				res = append(res, router)
+			}
+
+			continue
+		}
+
+		if routerStatus.LastUpdateTime.Sub(router.LastUpdateTime.Time) > statusStalePeriod {
+			staleRemoved = true
+
+			continue
+		}
+
+		res = append(res, router)
+	}
+
+	if !unchanged {
+		res = append(res, *routerStatus)
+	}
+
+	return res, !unchanged || staleRemoved
+}
@@ -0,0 +1,326 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"slices"
+
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/api/v1alpha1"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/bird"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/l34route"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/log"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	gatewayapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+// gracefulShutdownCommunity is the well-known community (RFC8326) set on the VIPs
+// while draining, so the gateway routers lower their preference.
+const gracefulShutdownCommunity = "65535:0"
+
+// getBirdVIPs returns the VIPs with the BGP attributes and the export of the gateway
+// and of the L34Routes attached to it.
+func (c *Controller) getBirdVIPs(
+	ctx context.Context,
+	gateway *gatewayapiv1.Gateway,
+	cidrs []string,
+	gatewayRouters []v1alpha1.GatewayRouter,
+) ([]bird.VIP, error) {
+	gatewayAttributes := getGatewayBgpAttributes(ctx, gateway)
+	gatewayExport := getGatewayVIPExport(ctx, gateway)
+
+	draining := c.draining.Load()
+	if draining && c.GracefulShutdown {
+		gatewayAttributes = mergeBgpAttributes(gatewayAttributes, &v1alpha1.BgpAttributes{
+			Communities: []string{gracefulShutdownCommunity},
+		})
+	}
+
+	l34Routes, err := c.getL34Routes(ctx, gateway)
+	if err != nil {
+		return nil, err
+	}
+
+	vips := []bird.VIP{}
+
+	for _, cidr := range cidrs {
+		attributes := gatewayAttributes
+		exports := []*v1alpha1.VIPExport{}
+
+		for _, l34Route := range l34Routes {
+			if !containsCIDR(l34Route.Spec.DestinationCIDRs, cidr) {
+				continue
+			}
+
+			if l34Route.Spec.BgpAttributes != nil {
+				attributes = mergeBgpAttributes(attributes, l34Route.Spec.BgpAttributes)
+			}
+
+			if l34Route.Spec.Export != nil {
+				exports = append(exports, l34Route.Spec.Export)
+			}
+		}
+
+		if len(exports) == 0 && gatewayExport != nil {
+			exports = append(exports, gatewayExport)
+		}
+
+		exported := exportedTo(ctx, exports, gatewayRouters)
+		if draining && !c.GracefulShutdown {
+			// withdrawn from all the gateway routers while the policy routes are kept
+			// for the traffic being drained.
+			exported = []string{}
+		}
+
+		vips = append(vips, newVIP(cidr, attributes, exported))
+	}
+
+	return vips, nil
+}
+
+// getGatewayVIPExport returns the export set in the annotation of the gateway.
+func getGatewayVIPExport(ctx context.Context, gateway *gatewayapiv1.Gateway) *v1alpha1.VIPExport {
+	value, exists := gateway.GetAnnotations()[v1alpha1.VIPExportAnnotation]
+	if !exists {
+		return nil
+	}
+
+	export := &v1alpha1.VIPExport{}
+
+	err := json.Unmarshal([]byte(value), export)
+	if err != nil {
+		log.FromContextOrGlobal(ctx).Error(err, "failed to unmarshal the VIP export of the gateway",
+			"annotation", v1alpha1.VIPExportAnnotation)
+
+		return nil
+	}
+
+	return export
+}
+
+// exportedTo returns the names of the gateway routers matching one of the exports,
+// or nil if there is no export (the VIP is exported to all the gateway routers).
+func exportedTo(ctx context.Context, exports []*v1alpha1.VIPExport, gatewayRouters []v1alpha1.GatewayRouter) []string {
+	if len(exports) == 0 {
+		return nil
+	}
+
+	names := []string{}
+
+	for _, gatewayRouter := range gatewayRouters {
+		for _, export := range exports {
+			if exportMatches(ctx, export, &gatewayRouter) {
+				names = append(names, gatewayRouter.GetName())
+
+				break
+			}
+		}
+	}
+
+	return names
+}
+
+// exportMatches returns true if the gateway router is reached over one of the interfaces
+// of the export and matches its selector. An invalid selector matches no gateway router.
+func exportMatches(ctx context.Context, export *v1alpha1.VIPExport, gatewayRouter *v1alpha1.GatewayRouter) bool {
+	if len(export.Interfaces) > 0 && !slices.Contains(export.Interfaces, gatewayRouter.Spec.Interface) {
+		return false
+	}
+
+	if export.GatewayRouterSelector == nil {
+		return true
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(export.GatewayRouterSelector)
+	if err != nil {
+		log.FromContextOrGlobal(ctx).Error(err, "invalid gateway router selector in the VIP export")
+
+		return false
+	}
+
+	return selector.Matches(labels.Set(gatewayRouter.GetLabels()))
+}
+
+// getGatewayBgpAttributes returns the BGP attributes set in the annotation of the gateway.
+func getGatewayBgpAttributes(ctx context.Context, gateway *gatewayapiv1.Gateway) *v1alpha1.BgpAttributes {
+	value, exists := gateway.GetAnnotations()[v1alpha1.BgpAttributesAnnotation]
+	if !exists {
+		return nil
+	}
+
+	attributes := &v1alpha1.BgpAttributes{}
+
+	err := json.Unmarshal([]byte(value), attributes)
+	if err != nil {
+		log.FromContextOrGlobal(ctx).Error(err, "failed to unmarshal the BGP attributes of the gateway",
+			"annotation", v1alpha1.BgpAttributesAnnotation)
+
+		return nil
+	}
+
+	return attributes
+}
+
+// getL34Routes gets the L34Routes attached to the gateway (in all namespaces allowed by
+// the listeners of the gateway).
+func (c *Controller) getL34Routes(ctx context.Context, gateway *gatewayapiv1.Gateway) ([]*v1alpha1.L34Route, error) {
+	l34Routes, err := l34route.Attached(ctx, c, gateway)
+	if err != nil {
+		return nil, fmt.Errorf("failed listing the L34Routes: %w", err)
+	}
+
+	return l34Routes, nil
+}
+
+// mergeBgpAttributes returns the attributes of the gateway completed by the ones of
+// the L34Route: the communities are added and the other attributes are overridden.
+func mergeBgpAttributes(gatewayAttributes *v1alpha1.BgpAttributes,
+	l34RouteAttributes *v1alpha1.BgpAttributes,
+) *v1alpha1.BgpAttributes {
+	if gatewayAttributes == nil {
+		return l34RouteAttributes.DeepCopy()
+	}
+
+	attributes := gatewayAttributes.DeepCopy()
+
+	attributes.Communities = appendMissing(attributes.Communities, l34RouteAttributes.Communities)
+	attributes.LargeCommunities = appendMissing(attributes.LargeCommunities, l34RouteAttributes.LargeCommunities)
+
+	if l34RouteAttributes.MED != nil {
+		attributes.MED = l34RouteAttributes.MED
+	}
+
+	if l34RouteAttributes.LocalPreference != nil {
+		attributes.LocalPreference = l34RouteAttributes.LocalPreference
+	}
+
+	if l34RouteAttributes.ASPathPrepend != nil {
+		attributes.ASPathPrepend = l34RouteAttributes.ASPathPrepend
+	}
+
+	return attributes
+}
+
+func appendMissing(list []string, values []string) []string {
+	for _, value := range values {
+		exists := false
+
+		for _, item := range list {
+			if item == value {
+				exists = true
+
+				break
+			}
+		}
+
+		if !exists {
+			list = append(list, value)
+		}
+	}
+
+	return list
+}
+
+// containsCIDR returns true if one of the CIDRs contains the VIP.
+func containsCIDR(cidrs []string, vip string) bool {
+	ip, vipNet, err := net.ParseCIDR(vip)
+	if err != nil {
+		return false
+	}
+
+	vipOnes, _ := vipNet.Mask.Size()
+
+	for _, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			continue
+		}
+
+		ones, _ := ipNet.Mask.Size()
+
+		if ipNet.Contains(ip) && ones <= vipOnes && (ip.To4() == nil) == (ipNet.IP.To4() == nil) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func newVIP(cidr string, attributes *v1alpha1.BgpAttributes, exportedTo []string) *vip {
+	newVip := &vip{
+		cidr:       cidr,
+		exportedTo: exportedTo,
+	}
+
+	if attributes != nil {
+		newVip.bgpAttributes = &bgpAttributes{
+			attributes,
+		}
+	}
+
+	return newVip
+}
+
+type vip struct {
+	cidr          string
+	bgpAttributes *bgpAttributes
+	exportedTo    []string
+}
+
+func (v *vip) GetCIDR() string {
+	return v.cidr
+}
+
+func (v *vip) GetBgpAttributes() bird.BgpAttributes {
+	if v.bgpAttributes == nil {
+		return nil
+	}
+
+	return v.bgpAttributes
+}
+
+func (v *vip) GetExportedTo() []string {
+	return v.exportedTo
+}
+
+type bgpAttributes struct {
+	*v1alpha1.BgpAttributes
+}
+
+func (ba *bgpAttributes) GetCommunities() []string {
+	return ba.Communities
+}
+
+func (ba *bgpAttributes) GetLargeCommunities() []string {
+	return ba.LargeCommunities
+}
+
+func (ba *bgpAttributes) GetMED() *uint32 {
+	return ba.MED
+}
+
+func (ba *bgpAttributes) GetLocalPreference() *uint32 {
+	return ba.LocalPreference
+}
+
+func (ba *bgpAttributes) GetASPathPrepend() *uint32 {
+	return ba.ASPathPrepend
+}
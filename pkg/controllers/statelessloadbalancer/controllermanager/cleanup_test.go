@@ -0,0 +1,196 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllermanager
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	v1discovery "k8s.io/api/discovery/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	gatewayapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+func TestCleanup(t *testing.T) {
+	tests := []struct {
+		name                string
+		finalizer           bool
+		deploymentOwnerUID  types.UID
+		noDeployment        bool
+		wantDeleted         bool
+		wantFinalizer       bool
+		wantEndpointSlice   bool
+		wantDeploymentExist bool
+	}{
+		{
+			name:                "deployment controlled by the gateway",
+			finalizer:           true,
+			deploymentOwnerUID:  "gateway-uid",
+			wantDeleted:         true,
+			wantFinalizer:       true,
+			wantEndpointSlice:   true,
+			wantDeploymentExist: false,
+		},
+		{
+			name:                "deployment controlled by another gateway",
+			finalizer:           true,
+			deploymentOwnerUID:  "other-uid",
+			wantFinalizer:       false,
+			wantEndpointSlice:   false,
+			wantDeploymentExist: true,
+		},
+		{
+			name:              "deployment gone",
+			finalizer:         true,
+			noDeployment:      true,
+			wantFinalizer:     false,
+			wantEndpointSlice: false,
+		},
+		{
+			name:              "gateway never handled",
+			finalizer:         false,
+			noDeployment:      true,
+			wantFinalizer:     false,
+			wantEndpointSlice: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+
+			err := clientgoscheme.AddToScheme(scheme)
+			if err != nil {
+				t.Fatalf("AddToScheme() error = %v", err)
+			}
+
+			err = gatewayapiv1.Install(scheme)
+			if err != nil {
+				t.Fatalf("Install() error = %v", err)
+			}
+
+			gateway := &gatewayapiv1.Gateway{ObjectMeta: metav1.ObjectMeta{
+				Name:      "gateway",
+				Namespace: "default",
+				UID:       "gateway-uid",
+			}}
+			if tt.finalizer {
+				controllerutil.AddFinalizer(gateway, finalizer)
+			}
+
+			objects := []client.Object{gateway, &v1discovery.EndpointSlice{ObjectMeta: metav1.ObjectMeta{
+				Name:      "service-ipv4",
+				Namespace: "default",
+				Labels: map[string]string{
+					v1discovery.LabelManagedBy:     controllerManagerName,
+					v1discovery.LabelServiceName:   "service",
+					v1alpha1.LabelGatewayName:      "gateway",
+					v1alpha1.LabelGatewayNamespace: "default",
+				},
+			}}}
+
+			if !tt.noDeployment {
+				controller := true
+				objects = append(objects, &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+					Name:      getStatelessLoadBalancerDeploymentName(gateway),
+					Namespace: "default",
+					OwnerReferences: []metav1.OwnerReference{{
+						APIVersion: gatewayapiv1.GroupVersion.String(),
+						Kind:       "Gateway",
+						Name:       "gateway",
+						UID:        tt.deploymentOwnerUID,
+						Controller: &controller,
+					}},
+				}})
+			}
+
+			deleted := false
+
+			c := &Controller{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).
+				WithInterceptorFuncs(interceptor.Funcs{
+					Delete: func(
+						ctx context.Context,
+						c client.WithWatch,
+						obj client.Object,
+						opts ...client.DeleteOption,
+					) error {
+						if _, ok := obj.(*appsv1.Deployment); ok {
+							deleteOptions := &client.DeleteOptions{}
+							deleteOptions.ApplyOptions(opts)
+
+							if deleteOptions.PropagationPolicy == nil ||
+								*deleteOptions.PropagationPolicy != metav1.DeletePropagationForeground {
+								t.Errorf("cleanup() deployment deleted with propagation %v, want foreground",
+									deleteOptions.PropagationPolicy)
+							}
+
+							deleted = true
+						}
+
+						return c.Delete(ctx, obj, opts...)
+					},
+				}).Build()}
+
+			err = c.cleanup(context.Background(), gateway)
+			if err != nil {
+				t.Fatalf("cleanup() error = %v", err)
+			}
+
+			if deleted != tt.wantDeleted {
+				t.Errorf("cleanup() deployment deleted = %v, want %v", deleted, tt.wantDeleted)
+			}
+
+			err = c.Get(context.Background(), types.NamespacedName{
+				Name:      getStatelessLoadBalancerDeploymentName(gateway),
+				Namespace: "default",
+			}, &appsv1.Deployment{})
+			if (err == nil) != tt.wantDeploymentExist {
+				t.Errorf("cleanup() deployment exists = %v, want %v", err == nil, tt.wantDeploymentExist)
+			}
+
+			err = c.Get(context.Background(), types.NamespacedName{Name: "service-ipv4", Namespace: "default"},
+				&v1discovery.EndpointSlice{})
+			if err != nil && !apierrors.IsNotFound(err) {
+				t.Fatalf("Get() error = %v", err)
+			}
+
+			if (err == nil) != tt.wantEndpointSlice {
+				t.Errorf("cleanup() endpointslice exists = %v, want %v", err == nil, tt.wantEndpointSlice)
+			}
+
+			got := &gatewayapiv1.Gateway{}
+
+			err = c.Get(context.Background(), client.ObjectKeyFromObject(gateway), got)
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+
+			if controllerutil.ContainsFinalizer(got, finalizer) != tt.wantFinalizer {
+				t.Errorf("cleanup() finalizers = %v, want finalizer %v", got.GetFinalizers(), tt.wantFinalizer)
+			}
+		})
+	}
+}
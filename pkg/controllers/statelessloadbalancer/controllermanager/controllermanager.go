@@ -21,6 +21,7 @@ import (
 	"fmt"
 
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/api/v1alpha1"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/controllers/gatewayclass"
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/endpointslice"
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
@@ -29,8 +30,10 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	gatewayapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayapiv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
 )
 
 // Controller reconciles the Gateway Object to run the stateless-load-balancer-controller-manager.
@@ -61,11 +64,33 @@ func (c *Controller) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		return ctrl.Result{}, fmt.Errorf("failed to get the gateway: %w", err)
 	}
 
-	if string(gateway.Spec.GatewayClassName) != c.GatewayClassName {
-		// this should not happen if the controller is configured correctly.
+	manages, err := gatewayclass.Manages(ctx, c, c.GatewayClassName, gateway)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return ctrl.Result{}, fmt.Errorf("failed to check the class of the gateway: %w", err)
+	}
+
+	// the gateway is deleted or its class is (now) owned by another controller. The
+	// gateway is left untouched while its class does not exist.
+	if !gateway.GetDeletionTimestamp().IsZero() || (err == nil && !manages) {
+		err = c.cleanup(ctx, gateway)
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to clean up the gateway: %w", err)
+		}
+
+		return ctrl.Result{}, nil
+	}
+
+	if !manages {
 		return ctrl.Result{}, nil
 	}
 
+	if controllerutil.AddFinalizer(gateway, finalizer) {
+		err = c.Update(ctx, gateway)
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to add the finalizer to the gateway: %w", err)
+		}
+	}
+
 	err = c.reconcileStatelessLoadBalancerDeployment(ctx, gateway)
 	if err != nil {
 		return ctrl.Result{}, fmt.Errorf("failed to reconcile the stateless-load-balancer deployment: %w", err)
@@ -90,11 +115,14 @@ func (c *Controller) SetupWithManager(mgr ctrl.Manager) error {
 		For(&gatewayapiv1.Gateway{}).
 		// With EnqueueRequestsFromMapFunc, on an update the func is called twice
 		// (1 time for old and 1 time for new object)
-		Owns(&appsv1.DaemonSet{}).
+		Owns(&appsv1.Deployment{}).
 		Owns(&v1discovery.EndpointSlice{}).
-		Watches(&v1.Service{}, handler.EnqueueRequestsFromMapFunc(serviceEnqueue)).
+		Watches(&v1.Service{}, handler.EnqueueRequestsFromMapFunc(c.serviceEnqueue)).
 		Watches(&v1.Pod{}, handler.EnqueueRequestsFromMapFunc(c.podEnqueue)).
 		Watches(&v1alpha1.L34Route{}, handler.EnqueueRequestsFromMapFunc(l34RouteEnqueue)).
+		Watches(&gatewayapiv1beta1.ReferenceGrant{}, handler.EnqueueRequestsFromMapFunc(c.gatewayClassEnqueue)).
+		Watches(&v1.Namespace{}, handler.EnqueueRequestsFromMapFunc(c.gatewayClassEnqueue)).
+		Watches(&gatewayapiv1.GatewayClass{}, handler.EnqueueRequestsFromMapFunc(c.gatewayClassEnqueue)).
 		Complete(c)
 	if err != nil {
 		return fmt.Errorf("failed to build the stateless-load-balancer-controller-manager: %w", err)
@@ -20,13 +20,20 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/log"
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/template"
 	appsv1 "k8s.io/api/apps/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	appsv1ac "k8s.io/client-go/applyconfigurations/apps/v1"
+	corev1ac "k8s.io/client-go/applyconfigurations/core/v1"
+	metav1ac "k8s.io/client-go/applyconfigurations/meta/v1"
 	"k8s.io/kubernetes/pkg/proxy/apis"
-	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 	gatewayapiv1 "sigs.k8s.io/gateway-api/apis/v1"
 )
 
@@ -34,15 +41,26 @@ const (
 	label                              = "app"
 	routerContainerName                = "router"
 	statelessLoadBalancerContainerName = "stateless-load-balancer"
+
+	// controllerManagerName identifies the resources and fields managed by this controller.
+	controllerManagerName = "stateless-load-balancer-controller-manager"
+	// replicasFieldOwner owns the replicas of the deployment set at creation, so they are kept
+	// once released by controllerManagerName.
+	replicasFieldOwner = controllerManagerName + "-replicas"
 )
 
+// reconcileStatelessLoadBalancerDeployment creates and keeps the stateless-load-balancer deployment of the
+// gateway in sync with the template and the gateway via server-side apply: any drift of the fields owned by
+// this controller is reverted, while the fields owned by other managers are preserved. The replicas of the
+// template are only applied at creation, they are released afterwards so they can be scaled by others
+// (e.g. HPA).
 func (c *Controller) reconcileStatelessLoadBalancerDeployment(
 	ctx context.Context,
 	gateway *gatewayapiv1.Gateway,
 ) error {
 	statelessLoadBalancerDeployment := &appsv1.Deployment{}
 
-	knpgDeploymentLatestState, err := c.getStatelessLoadBalancerDeployment(gateway)
+	statelessLoadBalancerDeploymentLatestState, err := c.getStatelessLoadBalancerDeployment(gateway)
 	if err != nil {
 		return err
 	}
@@ -52,32 +70,85 @@ func (c *Controller) reconcileStatelessLoadBalancerDeployment(
 		Namespace: gateway.Namespace,
 	}, statelessLoadBalancerDeployment)
 	if err != nil {
-		if apierrors.IsNotFound(err) {
-			// Create
-			err = c.Create(ctx, knpgDeploymentLatestState)
-			if err != nil {
-				return fmt.Errorf("failed to create the stateless-load-balancer deployment: %w", err)
-			}
-
-			return nil
+		if !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to get the stateless-load-balancer deployment: %w", err)
 		}
 
-		return fmt.Errorf("failed to get the stateless-load-balancer deployment: %w", err)
+		return c.createStatelessLoadBalancerDeployment(ctx, statelessLoadBalancerDeploymentLatestState)
 	}
 
-	// Update: TODO
-	// err = c.Update(ctx, knpgDeploymentLatestState)
-	// if err != nil {
-	// 	return fmt.Errorf("failed to update the stateless-load-balancer deployment: %w", err)
-	// }
+	statelessLoadBalancerDeploymentLatestState.Spec.Replicas = nil
+
+	obj, err := c.apply(ctx, statelessLoadBalancerDeploymentLatestState, controllerManagerName)
+	if err != nil {
+		return err
+	}
+
+	if obj.GetGeneration() != statelessLoadBalancerDeployment.GetGeneration() {
+		log.FromContextOrGlobal(ctx).Info("stateless-load-balancer deployment applied",
+			"deployment", obj.GetName(),
+			"generation", obj.GetGeneration())
+	}
 
 	return nil
 }
 
+// createStatelessLoadBalancerDeployment creates the deployment via server-side apply with the replicas
+// of the template. The replicas are applied a second time by replicasFieldOwner which then shares their
+// ownership, so they are not removed when the later applies of this controller release them.
+func (c *Controller) createStatelessLoadBalancerDeployment(
+	ctx context.Context,
+	deployment *appsv1ac.DeploymentApplyConfiguration,
+) error {
+	obj, err := c.apply(ctx, deployment, controllerManagerName)
+	if err != nil {
+		return err
+	}
+
+	log.FromContextOrGlobal(ctx).Info("stateless-load-balancer deployment created", "deployment", obj.GetName())
+
+	if deployment.Spec == nil || deployment.Spec.Replicas == nil {
+		return nil
+	}
+
+	replicas := appsv1ac.Deployment(*deployment.Name, *deployment.Namespace).
+		WithSpec(appsv1ac.DeploymentSpec().WithReplicas(*deployment.Spec.Replicas))
+
+	_, err = c.apply(ctx, replicas, replicasFieldOwner)
+
+	return err
+}
+
+// apply applies the deployment with the field owner and returns the deployment returned by the API server.
+func (c *Controller) apply(
+	ctx context.Context,
+	deployment *appsv1ac.DeploymentApplyConfiguration,
+	fieldOwner string,
+) (*unstructured.Unstructured, error) {
+	obj, err := toUnstructured(deployment)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.Patch(ctx,
+		obj,
+		client.Apply,
+		client.FieldOwner(fieldOwner),
+		client.ForceOwnership,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply the stateless-load-balancer deployment: %w", err)
+	}
+
+	return obj, nil
+}
+
+// getStatelessLoadBalancerDeployment returns the apply configuration of the stateless-load-balancer deployment
+// of the gateway: only the fields set in the template and by this controller are owned.
 func (c *Controller) getStatelessLoadBalancerDeployment(
 	gateway *gatewayapiv1.Gateway,
-) (*appsv1.Deployment, error) {
-	deployment := &appsv1.Deployment{}
+) (*appsv1ac.DeploymentApplyConfiguration, error) {
+	deployment := &appsv1ac.DeploymentApplyConfiguration{}
 
 	err := template.Read("/templates/stateless-load-balancer.yaml", deployment)
 	if err != nil {
@@ -86,72 +157,102 @@ func (c *Controller) getStatelessLoadBalancerDeployment(
 
 	name := getStatelessLoadBalancerDeploymentName(gateway)
 
-	deployment.ObjectMeta.Name = name
-	deployment.ObjectMeta.Namespace = gateway.Namespace
+	// the type is required by server-side apply.
+	deployment.
+		WithAPIVersion(appsv1.SchemeGroupVersion.String()).
+		WithKind("Deployment").
+		WithName(name).
+		WithNamespace(gateway.Namespace)
 
-	if deployment.Labels == nil {
-		deployment.Labels = map[string]string{}
+	if deployment.Spec == nil {
+		deployment.WithSpec(appsv1ac.DeploymentSpec())
 	}
 
-	deployment.Labels[label] = name
-
-	deployment.Spec.Selector = &metav1.LabelSelector{
-		MatchLabels: map[string]string{
-			label: name,
-		},
+	if deployment.Spec.Template == nil {
+		deployment.Spec.WithTemplate(corev1ac.PodTemplateSpec())
 	}
 
-	if deployment.Spec.Template.Labels == nil {
-		deployment.Spec.Template.Labels = map[string]string{}
-	}
-
-	deployment.Spec.Template.Labels[label] = name
+	deployment.WithLabels(map[string]string{label: name})
+	deployment.Spec.WithSelector(metav1ac.LabelSelector().WithMatchLabels(map[string]string{label: name}))
+	deployment.Spec.Template.WithLabels(map[string]string{label: name})
 
 	for key, value := range gateway.Spec.Infrastructure.Labels {
-		deployment.Labels[string(key)] = string(value)
-		deployment.Spec.Template.Labels[string(key)] = string(value)
+		deployment.WithLabels(map[string]string{string(key): string(value)})
+		deployment.Spec.Template.WithLabels(map[string]string{string(key): string(value)})
 	}
 
-	if deployment.Annotations == nil {
-		deployment.Annotations = map[string]string{}
+	for key, value := range gateway.Spec.Infrastructure.Annotations {
+		deployment.WithAnnotations(map[string]string{string(key): string(value)})
+		deployment.Spec.Template.WithAnnotations(map[string]string{string(key): string(value)})
 	}
 
-	if deployment.Spec.Template.Annotations == nil {
-		deployment.Spec.Template.Annotations = map[string]string{}
-	}
+	setRolloutStrategy(deployment.Spec)
 
-	for key, value := range gateway.Spec.Infrastructure.Annotations {
-		deployment.Annotations[string(key)] = string(value)
-		deployment.Spec.Template.Annotations[string(key)] = string(value)
-	}
-
-	deployment.Labels[apis.LabelServiceProxyName] = gateway.GetName()
-	deployment.Spec.Template.Labels[apis.LabelServiceProxyName] = gateway.GetName()
-
-	for index, container := range deployment.Spec.Template.Spec.Containers {
-		switch container.Name {
-		case statelessLoadBalancerContainerName:
-			deployment.Spec.Template.Spec.Containers[index].Args = append(deployment.Spec.Template.Spec.Containers[index].Args,
-				fmt.Sprintf("--gateway-class-name=%s", gateway.Spec.GatewayClassName),
-				fmt.Sprintf("--name=%s", gateway.Name),
-				fmt.Sprintf("--namespace=%s", gateway.Namespace),
-			)
-		case routerContainerName:
-			deployment.Spec.Template.Spec.Containers[index].Args = append(deployment.Spec.Template.Spec.Containers[index].Args,
-				fmt.Sprintf("--name=%s", gateway.Name),
-				fmt.Sprintf("--namespace=%s", gateway.Namespace),
-			)
+	deployment.WithLabels(map[string]string{apis.LabelServiceProxyName: gateway.GetName()})
+	deployment.Spec.Template.WithLabels(map[string]string{apis.LabelServiceProxyName: gateway.GetName()})
+
+	if deployment.Spec.Template.Spec != nil {
+		for index, container := range deployment.Spec.Template.Spec.Containers {
+			if container.Name == nil {
+				continue
+			}
+
+			switch *container.Name {
+			case statelessLoadBalancerContainerName:
+				deployment.Spec.Template.Spec.Containers[index].WithArgs(
+					fmt.Sprintf("--gateway-class-name=%s", gateway.Spec.GatewayClassName),
+					fmt.Sprintf("--name=%s", gateway.Name),
+					fmt.Sprintf("--namespace=%s", gateway.Namespace),
+				)
+			case routerContainerName:
+				deployment.Spec.Template.Spec.Containers[index].WithArgs(
+					fmt.Sprintf("--name=%s", gateway.Name),
+					fmt.Sprintf("--namespace=%s", gateway.Namespace),
+				)
+			}
 		}
 	}
 
-	err = ctrl.SetControllerReference(gateway, deployment, c.Scheme)
+	gvk, err := apiutil.GVKForObject(gateway, c.Scheme)
 	if err != nil {
-		return nil, fmt.Errorf("failed to SetControllerReference on stateless-load-balancer deployment: %w", err)
+		return nil, fmt.Errorf("failed to get the GVK of the gateway: %w", err)
 	}
 
+	deployment.WithOwnerReferences(metav1ac.OwnerReference().
+		WithAPIVersion(gvk.GroupVersion().String()).
+		WithKind(gvk.Kind).
+		WithName(gateway.GetName()).
+		WithUID(gateway.GetUID()).
+		WithController(true).
+		WithBlockOwnerDeletion(true))
+
 	return deployment, nil
 }
 
 func getStatelessLoadBalancerDeploymentName(gateway *gatewayapiv1.Gateway) string {
 	return fmt.Sprintf("stateless-load-balancer-%s", gateway.Name)
 }
+
+// toUnstructured converts the apply configuration of the deployment so it can be sent by the client.
+func toUnstructured(deployment *appsv1ac.DeploymentApplyConfiguration) (*unstructured.Unstructured, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(deployment)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert the stateless-load-balancer deployment: %w", err)
+	}
+
+	return &unstructured.Unstructured{Object: content}, nil
+}
+
+// setRolloutStrategy sets, if none is defined in the template, a rolling update where a new instance is ready
+// before an old one is removed, so a BGP peer is always established before another one is withdrawn.
+func setRolloutStrategy(spec *appsv1ac.DeploymentSpecApplyConfiguration) {
+	if spec.Strategy != nil && spec.Strategy.Type != nil {
+		return
+	}
+
+	spec.WithStrategy(appsv1ac.DeploymentStrategy().
+		WithType(appsv1.RollingUpdateDeploymentStrategyType).
+		WithRollingUpdate(appsv1ac.RollingUpdateDeployment().
+			WithMaxSurge(intstr.FromInt32(1)).
+			WithMaxUnavailable(intstr.FromInt32(0))))
+}
@@ -0,0 +1,151 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllermanager
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	appsv1ac "k8s.io/client-go/applyconfigurations/apps/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+// appliedDeployment is a server-side apply of a deployment sent to the API server.
+type appliedDeployment struct {
+	fieldOwner string
+	force      bool
+	object     map[string]any
+}
+
+// newApplyRecorder returns a client recording the server-side applies, the fake client does not
+// support them.
+func newApplyRecorder(applied *[]appliedDeployment) client.Client {
+	return fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+		Patch: func(
+			_ context.Context,
+			_ client.WithWatch,
+			obj client.Object,
+			patch client.Patch,
+			opts ...client.PatchOption,
+		) error {
+			if patch.Type() != types.ApplyPatchType {
+				return nil
+			}
+
+			patchOptions := &client.PatchOptions{}
+			patchOptions.ApplyOptions(opts)
+
+			//nolint:forcetypeassert
+			*applied = append(*applied, appliedDeployment{
+				fieldOwner: patchOptions.FieldManager,
+				force:      patchOptions.Force != nil && *patchOptions.Force,
+				object:     obj.(*unstructured.Unstructured).DeepCopy().Object,
+			})
+
+			return nil
+		},
+	}).Build()
+}
+
+func TestCreateStatelessLoadBalancerDeployment(t *testing.T) {
+	tests := []struct {
+		name         string
+		replicas     *int32
+		wantOwners   []string
+		wantReplicas []any
+	}{
+		{
+			name:         "replicas",
+			replicas:     ptr(int32(3)),
+			wantOwners:   []string{controllerManagerName, replicasFieldOwner},
+			wantReplicas: []any{int64(3), int64(3)},
+		},
+		{
+			name:         "no replicas",
+			wantOwners:   []string{controllerManagerName},
+			wantReplicas: []any{nil},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			applied := []appliedDeployment{}
+			c := &Controller{Client: newApplyRecorder(&applied)}
+
+			deployment := appsv1ac.Deployment("stateless-load-balancer-gateway", "default").
+				WithLabels(map[string]string{label: "stateless-load-balancer-gateway"}).
+				WithSpec(appsv1ac.DeploymentSpec())
+			if tt.replicas != nil {
+				deployment.Spec.WithReplicas(*tt.replicas)
+			}
+
+			err := c.createStatelessLoadBalancerDeployment(context.Background(), deployment)
+			if err != nil {
+				t.Fatalf("createStatelessLoadBalancerDeployment() error = %v", err)
+			}
+
+			owners := []string{}
+			replicas := []any{}
+
+			for _, apply := range applied {
+				if !apply.force {
+					t.Errorf("createStatelessLoadBalancerDeployment() apply by %s is not forced", apply.fieldOwner)
+				}
+
+				owners = append(owners, apply.fieldOwner)
+				replicas = append(replicas, getField(apply.object, "spec", "replicas"))
+			}
+
+			if !reflect.DeepEqual(owners, tt.wantOwners) {
+				t.Errorf("createStatelessLoadBalancerDeployment() field owners = %v, want %v", owners, tt.wantOwners)
+			}
+
+			if !reflect.DeepEqual(replicas, tt.wantReplicas) {
+				t.Errorf("createStatelessLoadBalancerDeployment() replicas = %v, want %v", replicas, tt.wantReplicas)
+			}
+
+			// the replicas field owner must only own the replicas, the other fields are released
+			// with the later applies of the controller.
+			if len(applied) > 1 {
+				if labels := getField(applied[1].object, "metadata", "labels"); labels != nil {
+					t.Errorf("createStatelessLoadBalancerDeployment() replicas apply labels = %v, want none", labels)
+				}
+
+				if spec, _ := getField(applied[1].object, "spec").(map[string]any); len(spec) != 1 {
+					t.Errorf("createStatelessLoadBalancerDeployment() replicas apply spec = %v, want replicas only", spec)
+				}
+			}
+		})
+	}
+}
+
+func getField(object map[string]any, fields ...string) any {
+	value, found, err := unstructured.NestedFieldNoCopy(object, fields...)
+	if err != nil || !found {
+		return nil
+	}
+
+	return value
+}
+
+func ptr[T any](value T) *T {
+	return &value
+}
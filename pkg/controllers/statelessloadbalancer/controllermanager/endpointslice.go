@@ -29,6 +29,7 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	gatewayapiv1 "sigs.k8s.io/gateway-api/apis/v1"
 )
 
 type createUpdateEndpointSliceFunc func(
@@ -40,6 +41,7 @@ type createUpdateEndpointSliceFunc func(
 // reconcileEndpointSlices reconciles the EndpointSlices for IPv4 and IPv6 for a specific service.
 func (c *Controller) reconcileEndpointSlices(
 	ctx context.Context,
+	gateway *gatewayapiv1.Gateway,
 	service *v1.Service,
 	pods *v1.PodList,
 	networks []*v1alpha1.Network,
@@ -98,6 +100,9 @@ func (c *Controller) reconcileEndpointSlices(
 		return fmt.Errorf("failed to reconcile %v EndpointSlice: %w", v1discovery.AddressTypeIPv6, err)
 	}
 
+	endpointslice.SetOwnership(newIPV4EndpointSlice, controllerManagerName, gateway)
+	endpointslice.SetOwnership(newIPV6EndpointSlice, controllerManagerName, gateway)
+
 	return c.reconcileEndpointSlice(
 		ctx,
 		service,
@@ -20,17 +20,21 @@ import (
 	"context"
 
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/api/v1alpha1"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/controllers/gatewayclass"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/l34route"
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/log"
 	v1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/types"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/kubernetes/pkg/proxy/apis"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	gatewayapiv1 "sigs.k8s.io/gateway-api/apis/v1"
 )
 
-func serviceEnqueue(
-	_ context.Context,
+// serviceEnqueue enqueues the gateways the service is labelled with. The service can
+// belong to a gateway in another namespace if an attached route references it.
+func (c *Controller) serviceEnqueue(
+	ctx context.Context,
 	object client.Object,
 ) []reconcile.Request {
 	gatewayName, exists := object.GetLabels()[apis.LabelServiceProxyName]
@@ -38,16 +42,19 @@ func serviceEnqueue(
 		return []reconcile.Request{}
 	}
 
-	// todo: check if parent is the right class
+	reconcileRequests := []reconcile.Request{}
 
-	return []reconcile.Request{
-		{
-			NamespacedName: types.NamespacedName{
-				Name:      gatewayName,
-				Namespace: object.GetNamespace(),
-			},
-		},
+	for _, gateway := range c.getGatewaysForGatewayClass(ctx) {
+		if gateway.GetName() != gatewayName {
+			continue
+		}
+
+		reconcileRequests = append(reconcileRequests, reconcile.Request{
+			NamespacedName: client.ObjectKeyFromObject(&gateway),
+		})
 	}
+
+	return reconcileRequests
 }
 
 func l34RouteEnqueue(
@@ -59,20 +66,39 @@ func l34RouteEnqueue(
 		return []reconcile.Request{}
 	}
 
-	if len(l34Route.Spec.ParentRefs) == 0 {
-		return []reconcile.Request{}
-	}
+	reconcileRequests := []reconcile.Request{}
 
 	// todo: check if parent is the right class
+	for _, parentRef := range l34Route.Spec.ParentRefs {
+		gateway, ok := l34route.ParentGateway(l34Route, parentRef)
+		if !ok {
+			continue
+		}
+
+		reconcileRequests = append(reconcileRequests, reconcile.Request{
+			NamespacedName: gateway,
+		})
+	}
+
+	return reconcileRequests
+}
+
+// gatewayClassEnqueue enqueues all gateways of the gateway class. This is used for the
+// objects (ReferenceGrant, Namespace, GatewayClass) which can change which routes and
+// services are attached to any gateway, or which gateways belong to the class.
+func (c *Controller) gatewayClassEnqueue(
+	ctx context.Context,
+	_ client.Object,
+) []reconcile.Request {
+	reconcileRequests := []reconcile.Request{}
 
-	return []reconcile.Request{
-		{
-			NamespacedName: types.NamespacedName{
-				Name:      string(l34Route.Spec.ParentRefs[0].Name),
-				Namespace: object.GetNamespace(),
-			},
-		},
+	for _, gateway := range c.getGatewaysForGatewayClass(ctx) {
+		reconcileRequests = append(reconcileRequests, reconcile.Request{
+			NamespacedName: client.ObjectKeyFromObject(&gateway),
+		})
 	}
+
+	return reconcileRequests
 }
 
 func (c *Controller) podEnqueue(
@@ -81,59 +107,49 @@ func (c *Controller) podEnqueue(
 ) []reconcile.Request {
 	reconcileRequests := []reconcile.Request{}
 
-	services := c.getServicesForGateways(ctx, c.getGatewaysForGatewayClass(ctx))
+	for _, gateway := range c.getGatewaysForGatewayClass(ctx) {
+		services, err := l34route.Services(ctx, c, &gateway)
+		if err != nil {
+			log.FromContextOrGlobal(ctx).Error(err, "failed listing the services during the pod enqueue")
 
-items:
-	for _, service := range services {
-		for labelSelectorKey, labelSelectorValue := range service.Spec.Selector {
-			if labelSelectorKey == v1alpha1.LabelDummmySericeSelector {
+			continue
+		}
+
+		for _, service := range services {
+			// the services select the pods of their namespace.
+			if service.GetNamespace() != object.GetNamespace() || !selects(service, object) {
 				continue
 			}
 
-			value, exists := object.GetLabels()[labelSelectorKey]
-			if !exists || value != labelSelectorValue {
-				continue items
-			}
-		}
+			reconcileRequests = append(reconcileRequests,
+				reconcile.Request{
+					NamespacedName: client.ObjectKeyFromObject(&gateway),
+				})
 
-		gatewayName, exists := service.Labels[apis.LabelServiceProxyName]
-		if !exists {
-			continue
+			break
 		}
-
-		reconcileRequests = append(reconcileRequests,
-			reconcile.Request{
-				NamespacedName: types.NamespacedName{
-					Name:      gatewayName,
-					Namespace: service.GetNamespace(),
-				},
-			})
 	}
 
 	return reconcileRequests
 }
 
-func (c *Controller) getServicesForGateways(ctx context.Context, gateways []gatewayapiv1.Gateway) []v1.Service {
-	services := []v1.Service{}
-
-	for _, gateway := range gateways {
-		serviceList := &v1.ServiceList{}
-
-		err := c.List(ctx,
-			serviceList,
-			client.MatchingLabels{
-				apis.LabelServiceProxyName: gateway.Name,
-			}) // todo: filter namespace
-		if err != nil {
-			log.FromContextOrGlobal(ctx).Error(err, "failed listing the services during the pod enqueue")
+// selects returns true if the selector of the service selects the pod.
+func selects(service *v1.Service, pod client.Object) bool {
+	for labelSelectorKey, labelSelectorValue := range service.Spec.Selector {
+		if labelSelectorKey == v1alpha1.LabelDummmySericeSelector {
+			continue
 		}
 
-		services = append(services, serviceList.Items...)
+		value, exists := pod.GetLabels()[labelSelectorKey]
+		if !exists || value != labelSelectorValue {
+			return false
+		}
 	}
 
-	return services
+	return true
 }
 
+// getGatewaysForGatewayClass returns the gateways of the gateway class.
 func (c *Controller) getGatewaysForGatewayClass(ctx context.Context) []gatewayapiv1.Gateway {
 	gatewayList := &gatewayapiv1.GatewayList{}
 
@@ -143,9 +159,10 @@ func (c *Controller) getGatewaysForGatewayClass(ctx context.Context) []gatewayap
 	// 		"spec.gatewayClassName": c.GatewayClassName,
 	// 	})
 	err := c.List(ctx,
-		gatewayList) // todo: filter namespace
+		gatewayList,
+	)
 	if err != nil {
-		log.FromContextOrGlobal(ctx).Error(err, "failed listing the gateways during the pod enqueue")
+		log.FromContextOrGlobal(ctx).Error(err, "failed listing the gateways during the enqueue")
 
 		return nil
 	}
@@ -153,7 +170,12 @@ func (c *Controller) getGatewaysForGatewayClass(ctx context.Context) []gatewayap
 	gateways := []gatewayapiv1.Gateway{}
 
 	for _, gateway := range gatewayList.Items {
-		if string(gateway.Spec.GatewayClassName) != c.GatewayClassName {
+		manages, err := gatewayclass.Manages(ctx, c, c.GatewayClassName, &gateway)
+		if err != nil && !apierrors.IsNotFound(err) {
+			log.FromContextOrGlobal(ctx).Error(err, "failed to check the class of the gateway", "gateway", gateway.GetName())
+		}
+
+		if !manages {
 			continue
 		}
 
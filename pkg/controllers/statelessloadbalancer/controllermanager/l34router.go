@@ -19,24 +19,22 @@ package controllermanager
 import (
 	"context"
 	"fmt"
-	"net"
+	"slices"
 
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/api/v1alpha1"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/l34route"
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/log"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/networking"
 	gatewayapiv1 "sigs.k8s.io/gateway-api/apis/v1"
 )
 
-// reconcileL34Routes reconciles all l34routes managed by the gateway.
+// reconcileL34Routes reconciles all l34routes managed by the gateway: the status of the
+// routes referencing the gateway is updated, and the addresses and the listener statuses
+// of the gateway are set from the routes attached to it.
 func (c *Controller) reconcileL34Routes(ctx context.Context, gateway *gatewayapiv1.Gateway) error {
-	l34routeList := &v1alpha1.L34RouteList{}
-
-	// err := c.List(ctx,
-	// 	gatewayList,
-	// 	client.MatchingFields{
-	// 		"spec.ParentRefs[0].Name": c.GatewayClassName,
-	// 	})
-	err := c.List(ctx,
-		l34routeList) // todo: filter namespace
+	previousStatus := gateway.Status.DeepCopy()
+
+	referencingL34Routes, err := l34route.Referencing(ctx, c, gateway)
 	if err != nil {
 		log.FromContextOrGlobal(ctx).Error(err, "failed listing the L34routes while reconciling the L34Routes")
 
@@ -44,48 +42,94 @@ func (c *Controller) reconcileL34Routes(ctx context.Context, gateway *gatewayapi
 	}
 
 	l34Routes := []*v1alpha1.L34Route{}
+	attachedRoutes := map[gatewayapiv1.SectionName]int32{}
 
-	for _, l34Route := range l34routeList.Items {
-		if len(l34Route.Spec.ParentRefs) == 0 ||
-			string(l34Route.Spec.ParentRefs[0].Name) != gateway.GetName() ||
-			l34Route.GetNamespace() != gateway.GetNamespace() {
-			continue
+	for _, l34Route := range referencingL34Routes {
+		accepted, listeners, err := c.reconcileL34RouteStatus(ctx, gateway, l34Route)
+		if err != nil {
+			return err
 		}
 
-		l34r := l34Route
+		if accepted {
+			l34Routes = append(l34Routes, l34Route)
+		}
 
-		l34Routes = append(l34Routes, &l34r)
+		for _, listener := range listeners {
+			attachedRoutes[listener]++
+		}
 	}
 
-	// update gateway status IPs with service IPs
+	// update gateway status addresses with the destination CIDRs (the whole prefix
+	// is kept for the CIDRs which are not a single IP).
 	gateway.Status.Addresses = []gatewayapiv1.GatewayStatusAddress{}
 	vips := map[string]struct{}{}
 
 	for _, l34Route := range l34Routes {
 		for _, destinationCIDR := range l34Route.Spec.DestinationCIDRs {
-			_, ipNet, err := net.ParseCIDR(destinationCIDR)
-			if err != nil {
+			address, ok := networking.GatewayAddress(destinationCIDR)
+			if !ok {
 				continue
 			}
 
-			_, exists := vips[ipNet.IP.String()]
+			_, exists := vips[address.Value]
 			if exists {
 				continue
 			}
 
-			gateway.Status.Addresses = append(gateway.Status.Addresses, gatewayapiv1.GatewayStatusAddress{
-				Type:  ptrTo(gatewayapiv1.IPAddressType),
-				Value: ipNet.IP.String(),
-			})
+			gateway.Status.Addresses = append(gateway.Status.Addresses, address)
 
-			vips[ipNet.IP.String()] = struct{}{}
+			vips[address.Value] = struct{}{}
 		}
 	}
 
-	err = c.Status().Update(ctx, gateway)
-	if err != nil {
-		return fmt.Errorf("failed to update gateway status: %w", err)
+	return c.reconcileGatewayStatus(ctx, gateway, previousStatus, attachedRoutes)
+}
+
+// reconcileL34RouteStatus updates the status of the route for each of its parent references
+// to the gateway. It returns true if the route is accepted by the gateway via at least one
+// of them, and the listeners the route is attached to.
+func (c *Controller) reconcileL34RouteStatus(
+	ctx context.Context,
+	gateway *gatewayapiv1.Gateway,
+	l34Route *v1alpha1.L34Route,
+) (bool, []gatewayapiv1.SectionName, error) {
+	controllerName := gatewayapiv1.GatewayController(c.GatewayClassName)
+	accepted := false
+	listeners := []gatewayapiv1.SectionName{}
+	updated := l34route.RemoveStaleParentStatuses(l34Route, controllerName)
+
+	for _, parentRef := range l34route.ParentRefs(l34Route, gateway) {
+		attachment, err := l34route.Attach(ctx, c, gateway, l34Route, parentRef)
+		if err != nil {
+			return false, nil, fmt.Errorf("failed to attach the L34Route: %w", err)
+		}
+
+		parentStatus, err := l34route.ParentStatus(ctx, c, l34Route, parentRef, controllerName, attachment)
+		if err != nil {
+			return false, nil, fmt.Errorf("failed to compute the status of the L34Route: %w", err)
+		}
+
+		if l34route.SetParentStatus(l34Route, parentStatus) {
+			updated = true
+		}
+
+		if attachment.Accepted {
+			accepted = true
+		}
+
+		for _, listener := range attachment.Listeners {
+			if !slices.Contains(listeners, listener) {
+				listeners = append(listeners, listener)
+			}
+		}
+	}
+
+	if updated {
+		err := c.Status().Update(ctx, l34Route)
+		if err != nil {
+			return false, nil, fmt.Errorf("failed to update the L34Route status: %w", err)
+		}
 	}
 
-	return nil
+	return accepted, listeners, nil
 }
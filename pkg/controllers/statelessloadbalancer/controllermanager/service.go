@@ -21,9 +21,10 @@ import (
 	"fmt"
 
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/api/v1alpha1"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/endpointslice"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/l34route"
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/networkattachment"
 	v1 "k8s.io/api/core/v1"
-	"k8s.io/kubernetes/pkg/proxy/apis"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	gatewayapiv1 "sigs.k8s.io/gateway-api/apis/v1"
 )
@@ -32,34 +33,35 @@ import (
 func (c *Controller) reconcileServices(ctx context.Context, gateway *gatewayapiv1.Gateway) error {
 	networks := networkattachment.GetNetworksFromGateway(gateway)
 
-	services := &v1.ServiceList{}
-
-	// Get pods for this service so the endpointslices can be reconciled.
-	matchingLabels := client.MatchingLabels{
-		apis.LabelServiceProxyName: gateway.Name,
-	}
-
-	err := c.List(ctx, services, matchingLabels) // todo: filter namespace
+	// the services of the gateway namespace and the services of the other namespaces
+	// referenced by the routes attached to the gateway.
+	services, err := l34route.Services(ctx, c, gateway)
 	if err != nil {
-		return fmt.Errorf("failed to list services: %w", err)
+		return fmt.Errorf("failed to get the services: %w", err)
 	}
 
-	for _, service := range services.Items {
-		s := service
-
-		err = c.reconcileService(ctx, &s, networks)
+	for _, service := range services {
+		err = c.reconcileService(ctx, gateway, service, networks)
 		if err != nil {
 			return err
 		}
 	}
 
-	// todo: cleanup old endpointslices
+	err = endpointslice.DeleteStale(ctx, c.Client, controllerManagerName, gateway, services)
+	if err != nil {
+		return fmt.Errorf("failed to delete the stale endpointslices: %w", err)
+	}
 
 	return nil
 }
 
 // reconcileService reconciles a specific service.
-func (c *Controller) reconcileService(ctx context.Context, service *v1.Service, networks []*v1alpha1.Network) error {
+func (c *Controller) reconcileService(
+	ctx context.Context,
+	gateway *gatewayapiv1.Gateway,
+	service *v1.Service,
+	networks []*v1alpha1.Network,
+) error {
 	// Get pods for this service so the endpointslices can be reconciled.
 	var matchingLabels client.MatchingLabels = service.Spec.Selector
 
@@ -69,14 +71,12 @@ func (c *Controller) reconcileService(ctx context.Context, service *v1.Service,
 
 	err := c.List(ctx,
 		pods,
-		matchingLabels) // todo: filter namespace
+		matchingLabels,
+		client.InNamespace(service.GetNamespace()),
+	)
 	if err != nil {
 		return fmt.Errorf("failed to list the pods: %w", err)
 	}
 
-	return c.reconcileEndpointSlices(ctx, service, pods, networks)
-}
-
-func ptrTo[T any](a T) *T {
-	return &a
+	return c.reconcileEndpointSlices(ctx, gateway, service, pods, networks)
 }
@@ -0,0 +1,87 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllermanager
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/api/v1alpha1"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/gatewaystatus"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	gatewayapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+	"sigs.k8s.io/gateway-api/pkg/features"
+)
+
+// SupportedFeatures are the features supported by the stateless-load-balancer gateway class.
+var SupportedFeatures = []gatewayapiv1.SupportedFeature{
+	gatewayapiv1.SupportedFeature(features.SupportGateway),
+	gatewayapiv1.SupportedFeature(features.SupportReferenceGrant),
+}
+
+// reconcileGatewayStatus sets the conditions and the listener statuses of the gateway from
+// the readiness of the stateless-load-balancer deployment and the routes attached per listener.
+// The status is written only if it differs from previousStatus, so the gateway is not reconciled
+// again for nothing.
+func (c *Controller) reconcileGatewayStatus(
+	ctx context.Context,
+	gateway *gatewayapiv1.Gateway,
+	previousStatus *gatewayapiv1.GatewayStatus,
+	attachedRoutes map[gatewayapiv1.SectionName]int32,
+) error {
+	deployment := &appsv1.Deployment{}
+
+	err := c.Get(ctx, types.NamespacedName{
+		Name:      getStatelessLoadBalancerDeploymentName(gateway),
+		Namespace: gateway.Namespace,
+	}, deployment)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to get the stateless-load-balancer deployment: %w", err)
+		}
+
+		deployment = nil
+	}
+
+	l34RouteGroup := gatewayapiv1.Group(v1alpha1.GroupName)
+
+	gatewaystatus.Set(gateway, gatewaystatus.DeploymentWorkload(deployment), gatewaystatus.Options{
+		Protocols: []gatewayapiv1.ProtocolType{
+			gatewayapiv1.TCPProtocolType,
+			gatewayapiv1.UDPProtocolType,
+			gatewayapiv1.ProtocolType(v1alpha1.SCTP),
+		},
+		Kinds: []gatewayapiv1.RouteGroupKind{
+			{Group: &l34RouteGroup, Kind: "L34Route"},
+		},
+		AttachedRoutes: attachedRoutes,
+	})
+
+	if equality.Semantic.DeepEqual(previousStatus, &gateway.Status) {
+		return nil
+	}
+
+	err = c.Status().Update(ctx, gateway)
+	if err != nil {
+		return fmt.Errorf("failed to update gateway status: %w", err)
+	}
+
+	return nil
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/api/v1alpha1"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/l34route"
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/log"
 	v1 "k8s.io/api/core/v1"
 	v1discovery "k8s.io/api/discovery/v1"
@@ -30,25 +31,18 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
-func serviceEnqueue(
-	_ context.Context,
+// serviceEnqueue enqueues the gateway if the service is labelled with its name. The service
+// can be in another namespace than the gateway if an attached route references it.
+func (c *Controller) serviceEnqueue(
+	ctx context.Context,
 	object client.Object,
 ) []reconcile.Request {
 	gatewayName, exists := object.GetLabels()[apis.LabelServiceProxyName]
-	if !exists {
+	if !exists || gatewayName != c.Name {
 		return []reconcile.Request{}
 	}
 
-	// todo: check if parent is the right service proxy
-
-	return []reconcile.Request{
-		{
-			NamespacedName: types.NamespacedName{
-				Name:      gatewayName,
-				Namespace: object.GetNamespace(),
-			},
-		},
-	}
+	return c.gatewayEnqueue(ctx, object)
 }
 
 func (c *Controller) endpointSliceEnqueue(
@@ -75,25 +69,11 @@ func (c *Controller) endpointSliceEnqueue(
 		)
 	}
 
-	gatewayName, exists := service.GetLabels()[apis.LabelServiceProxyName]
-	if !exists {
-		return []reconcile.Request{}
-	}
-
-	// todo: check if parent is the right service proxy
-
-	return []reconcile.Request{
-		{
-			NamespacedName: types.NamespacedName{
-				Name:      gatewayName,
-				Namespace: object.GetNamespace(),
-			},
-		},
-	}
+	return c.serviceEnqueue(ctx, service)
 }
 
-func l34RouteEnqueue(
-	_ context.Context,
+func (c *Controller) l34RouteEnqueue(
+	ctx context.Context,
 	object client.Object,
 ) []reconcile.Request {
 	l34Route, ok := object.(*v1alpha1.L34Route)
@@ -101,17 +81,28 @@ func l34RouteEnqueue(
 		return []reconcile.Request{}
 	}
 
-	if len(l34Route.Spec.ParentRefs) == 0 {
-		return []reconcile.Request{}
+	for _, parentRef := range l34Route.Spec.ParentRefs {
+		gateway, ok := l34route.ParentGateway(l34Route, parentRef)
+		if ok && gateway.Name == c.Name && gateway.Namespace == c.Namespace {
+			return c.gatewayEnqueue(ctx, object)
+		}
 	}
 
-	// todo: check if parent is the right service proxy
+	return []reconcile.Request{}
+}
 
+// gatewayEnqueue enqueues the gateway served by the stateless-load-balancer. This is used
+// for the objects (ReferenceGrant) which can change which routes and services are attached
+// to the gateway.
+func (c *Controller) gatewayEnqueue(
+	_ context.Context,
+	_ client.Object,
+) []reconcile.Request {
 	return []reconcile.Request{
 		{
 			NamespacedName: types.NamespacedName{
-				Name:      string(l34Route.Spec.ParentRefs[0].Name),
-				Namespace: object.GetNamespace(),
+				Name:      c.Name,
+				Namespace: c.Namespace,
 			},
 		},
 	}
@@ -21,20 +21,16 @@ import (
 	"fmt"
 
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/api/v1alpha1"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/l34route"
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/log"
 	gatewayapiv1 "sigs.k8s.io/gateway-api/apis/v1"
 )
 
+// reconcileL34Routes sets the flows of the routes attached to the gateway, restricted to the
+// protocols and the ports of the listeners they are attached to (see l34route.Programmed).
+// The routes whose backend is a service they are not permitted to reference are ignored.
 func (c *Controller) reconcileL34Routes(ctx context.Context, gateway *gatewayapiv1.Gateway) error {
-	l34routeList := &v1alpha1.L34RouteList{}
-
-	// err := c.List(ctx,
-	// 	gatewayList,
-	// 	client.MatchingFields{
-	// 		"spec.ParentRefs[0].Name": c.GatewayClassName,
-	// 	})
-	err := c.List(ctx,
-		l34routeList) // todo: filter namespace
+	attachedL34Routes, err := l34route.Programmed(ctx, c, gateway)
 	if err != nil {
 		log.FromContextOrGlobal(ctx).Error(err, "failed listing the L34routes while reconciling the L34Routes")
 
@@ -43,16 +39,24 @@ func (c *Controller) reconcileL34Routes(ctx context.Context, gateway *gatewayapi
 
 	l34Routes := []*v1alpha1.L34Route{}
 
-	for _, l34Route := range l34routeList.Items {
-		if len(l34Route.Spec.ParentRefs) == 0 ||
-			string(l34Route.Spec.ParentRefs[0].Name) != gateway.GetName() ||
-			l34Route.GetNamespace() != gateway.GetNamespace() {
+	for _, l34Route := range attachedL34Routes {
+		if len(l34Route.Spec.BackendRefs) == 0 {
+			continue
+		}
+
+		service, ok := l34route.BackendService(l34Route, l34Route.Spec.BackendRefs[0])
+		if !ok {
 			continue
 		}
 
-		l34r := l34Route
+		permitted, err := l34route.BackendPermitted(ctx, c, l34Route, service)
+		if err != nil {
+			return fmt.Errorf("failed to check the backend of the L34Route: %w", err)
+		}
 
-		l34Routes = append(l34Routes, &l34r)
+		if permitted {
+			l34Routes = append(l34Routes, l34Route)
+		}
 	}
 
 	err = c.ServiceManager.SetFlows(ctx, l34Routes)
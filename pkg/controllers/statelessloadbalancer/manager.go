@@ -24,6 +24,7 @@ import (
 
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/api/v1alpha1"
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/controllers/statelessloadbalancer/endpoint"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/l34route"
 	v1 "k8s.io/api/core/v1"
 	v1discovery "k8s.io/api/discovery/v1"
 )
@@ -83,9 +84,9 @@ type Flow interface {
 // Manager is an helper structure to control a load balancer instance.
 type Manager struct {
 	LoadBalancer LoadBalancerInstance
-	services     map[string]ServiceInstance         // key: service name
-	flows        map[string]*flowImpl               // key: <l34Route-name>.<service.name>
-	endpoints    map[string][]*v1discovery.Endpoint // key: service name
+	services     map[string]ServiceInstance         // key: <service-namespace>.<service-name>
+	flows        map[string]*flowImpl               // key: <l34Route-namespace>.<l34Route-name>.<service>
+	endpoints    map[string][]*v1discovery.Endpoint // key: <service-namespace>.<service-name>
 	mu           sync.Mutex
 }
 
@@ -111,10 +112,10 @@ func (m *Manager) SetServices(
 
 	var errFinal error
 
-	newServices := map[string]*v1.Service{} // key: <l34Route-name>.<service.name>
+	newServices := map[string]*v1.Service{} // key: <service-namespace>.<service-name>
 
 	for _, service := range services {
-		newServices[service.GetName()] = service
+		newServices[serviceKey(service)] = service
 	}
 
 	// To delete
@@ -137,18 +138,18 @@ func (m *Manager) SetServices(
 
 	// To add
 	for _, service := range newServices {
-		_, exists := m.services[service.GetName()]
+		_, exists := m.services[serviceKey(service)]
 		if exists {
 			continue
 		}
 
-		lbService, err := m.LoadBalancer.AddService(ctx, service.GetName())
+		lbService, err := m.LoadBalancer.AddService(ctx, serviceKey(service))
 		if err != nil {
 			return fmt.Errorf("failed to AddService: %w", err)
 		}
 
-		m.services[service.GetName()] = lbService
-		m.endpoints[service.GetName()] = []*v1discovery.Endpoint{}
+		m.services[serviceKey(service)] = lbService
+		m.endpoints[serviceKey(service)] = []*v1discovery.Endpoint{}
 	}
 
 	// cleanup flows
@@ -174,7 +175,7 @@ func (m *Manager) SetFlows(ctx context.Context,
 
 	var errFinal error
 
-	newFlows := map[string]*flowImpl{} // key: <l34Route-name>.<service.name>
+	newFlows := map[string]*flowImpl{} // key: <l34Route-namespace>.<l34Route-name>.<service>
 
 	for _, l34Route := range l34Routes {
 		flowI := &flowImpl{
@@ -211,18 +212,46 @@ func (m *Manager) SetFlows(ctx context.Context,
 		err := flow.service.AddFlow(ctx, flow)
 		if err != nil {
 			errFinal = fmt.Errorf("failed to AddFlow ; %w; %w", err, errFinal)
+
+			continue
 		}
+
+		flow.programmed = true
 	}
 
 	return errFinal
 }
 
+// ReadyVIPs returns the destination CIDRs of the flows that are programmed
+// and whose service has at least one active target.
+func (m *Manager) ReadyVIPs() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	vips := []string{}
+
+	for _, flow := range m.flows {
+		if !flow.programmed || len(m.endpoints[flow.service.GetName()]) == 0 {
+			continue
+		}
+
+		vips = append(vips, flow.GetDestinationCIDRs()...)
+	}
+
+	return vips
+}
+
 func (m *Manager) getServiceForL34Route(l34Route *v1alpha1.L34Route) ServiceInstance {
 	if len(l34Route.Spec.BackendRefs) == 0 {
 		return nil
 	}
 
-	serviceInstance, exists := m.services[string(l34Route.Spec.BackendRefs[0].Name)]
+	service, ok := l34route.BackendService(l34Route, l34Route.Spec.BackendRefs[0])
+	if !ok {
+		return nil
+	}
+
+	serviceInstance, exists := m.services[fmt.Sprintf("%s.%s", service.Namespace, service.Name)]
 	if !exists {
 		return nil
 	}
@@ -240,12 +269,12 @@ func (m *Manager) SetEndpoints(
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	serviceInstance, exists := m.services[service.GetName()]
+	serviceInstance, exists := m.services[serviceKey(service)]
 	if !exists {
 		return errServiceNotExisting
 	}
 
-	previousEndpoints, exists := m.endpoints[service.GetName()]
+	previousEndpoints, exists := m.endpoints[serviceKey(service)]
 	if !exists {
 		return errServiceNotExisting
 	}
@@ -276,7 +305,7 @@ func (m *Manager) SetEndpoints(
 
 	finalEndpoints = append(finalEndpoints, endpts...)
 
-	m.endpoints[service.GetName()] = finalEndpoints
+	m.endpoints[serviceKey(service)] = finalEndpoints
 
 	return errFinal
 }
@@ -296,9 +325,13 @@ func addEndpoints(
 			continue
 		}
 
+		// the endpoint is only recorded once its target is added, so it is not counted as
+		// active (see ReadyVIPs) and is added again at the next reconciliation.
 		err := serviceInstance.AddTarget(ctx, endpnt.Addresses, *id)
 		if err != nil {
 			errFinal = fmt.Errorf("failed to AddTarget ; %w; %w", err, errFinal)
+
+			continue
 		}
 
 		finalEndpoints = append(finalEndpoints, endpnt)
@@ -383,13 +416,21 @@ func sameStringSlice(sliceA []string, sliceB []string) bool {
 	return len(diff) == 0
 }
 
+// serviceKey returns the name of the load-balancer service of the service. The namespace is
+// part of it since the routes can reference services in other namespaces.
+func serviceKey(service *v1.Service) string {
+	return fmt.Sprintf("%s.%s", service.GetNamespace(), service.GetName())
+}
+
 type flowImpl struct {
 	*v1alpha1.L34Route
 	service ServiceInstance
+	// programmed is true once the flow has been added to the load balancer instance.
+	programmed bool
 }
 
 func (f *flowImpl) GetName() string {
-	return fmt.Sprintf("%s.%s", f.L34Route.GetName(), f.service.GetName())
+	return fmt.Sprintf("%s.%s.%s", f.L34Route.GetNamespace(), f.L34Route.GetName(), f.service.GetName())
 }
 
 func (f *flowImpl) GetSourceCIDRs() []string {
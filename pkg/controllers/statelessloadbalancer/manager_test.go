@@ -0,0 +1,131 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statelessloadbalancer_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/api/v1alpha1"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/controllers/statelessloadbalancer"
+	v1 "k8s.io/api/core/v1"
+	v1discovery "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	gatewayapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+var errAddTarget = errors.New("add target failed")
+
+type fakeLoadBalancer struct {
+	addTargetErr error
+}
+
+func (lb *fakeLoadBalancer) AddService(_ context.Context, name string) (statelessloadbalancer.ServiceInstance, error) {
+	return &fakeService{name: name, loadBalancer: lb}, nil
+}
+
+func (lb *fakeLoadBalancer) DeleteService(_ context.Context, _ string) error {
+	return nil
+}
+
+type fakeService struct {
+	name         string
+	loadBalancer *fakeLoadBalancer
+}
+
+func (s *fakeService) GetName() string {
+	return s.name
+}
+
+func (s *fakeService) AddFlow(_ context.Context, _ statelessloadbalancer.Flow) error {
+	return nil
+}
+
+func (s *fakeService) DeleteFlow(_ context.Context, _ statelessloadbalancer.Flow) error {
+	return nil
+}
+
+func (s *fakeService) AddTarget(_ context.Context, _ []string, _ int) error {
+	return s.loadBalancer.addTargetErr
+}
+
+func (s *fakeService) DeleteTarget(_ context.Context, _ []string, _ int) error {
+	return nil
+}
+
+func TestManagerReadyVIPs(t *testing.T) {
+	tests := []struct {
+		name         string
+		addTargetErr error
+		want         []string
+	}{
+		{
+			name: "target added",
+			want: []string{"20.0.0.1/32"},
+		},
+		{
+			name:         "target not added",
+			addTargetErr: errAddTarget,
+			want:         []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			manager := statelessloadbalancer.NewManager(&fakeLoadBalancer{addTargetErr: tt.addTargetErr})
+
+			service := &v1.Service{ObjectMeta: metav1.ObjectMeta{Name: "service", Namespace: "default"}}
+
+			err := manager.SetServices(ctx, []*v1.Service{service})
+			if err != nil {
+				t.Fatalf("SetServices() error = %v", err)
+			}
+
+			err = manager.SetFlows(ctx, []*v1alpha1.L34Route{{
+				ObjectMeta: metav1.ObjectMeta{Name: "route", Namespace: "default"},
+				Spec: v1alpha1.L34RouteSpec{
+					BackendRefs: []gatewayapiv1.BackendRef{{
+						BackendObjectReference: gatewayapiv1.BackendObjectReference{Name: "service"},
+					}},
+					DestinationCIDRs: []string{"20.0.0.1/32"},
+				},
+			}})
+			if err != nil {
+				t.Fatalf("SetFlows() error = %v", err)
+			}
+
+			ready := true
+			zone := "1"
+
+			err = manager.SetEndpoints(ctx, service, []v1discovery.Endpoint{{
+				Addresses:  []string{"10.0.0.1"},
+				Conditions: v1discovery.EndpointConditions{Ready: &ready},
+				Zone:       &zone,
+				TargetRef:  &v1.ObjectReference{UID: "pod-uid"},
+			}})
+			if !errors.Is(err, tt.addTargetErr) {
+				t.Errorf("SetEndpoints() error = %v, want %v", err, tt.addTargetErr)
+			}
+
+			if got := manager.ReadyVIPs(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadyVIPs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -21,12 +21,13 @@ import (
 	"fmt"
 
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/api/v1alpha1"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/l34route"
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/log"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/kubernetes/pkg/proxy/apis"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	gatewayapiv1 "sigs.k8s.io/gateway-api/apis/v1"
 )
 
 func (c *Controller) serviceEnqueue(
@@ -63,22 +64,19 @@ func (c *Controller) gatewayEnqueue(
 	ctx context.Context,
 	object client.Object,
 ) []reconcile.Request {
-	serviceList := &v1.ServiceList{}
+	gateway, ok := object.(*gatewayapiv1.Gateway)
+	if !ok {
+		return []reconcile.Request{}
+	}
 
-	err := c.List(ctx,
-		serviceList,
-		client.MatchingLabels{
-			apis.LabelServiceProxyName: object.GetName(),
-		},
-		client.InNamespace(object.GetNamespace()),
-	)
+	services, err := l34route.Services(ctx, c, gateway)
 	if err != nil {
 		log.FromContextOrGlobal(ctx).Error(err, "failed listing the services during the pod enqueue")
 
 		return []reconcile.Request{}
 	}
 
-	pods, err := c.getPodsForServices(ctx, serviceList)
+	pods, err := c.getPodsForServices(ctx, services)
 	if err != nil {
 		return []reconcile.Request{}
 	}
@@ -97,12 +95,12 @@ func (c *Controller) gatewayEnqueue(
 	return reconcileRequests
 }
 
-func (c *Controller) getPodsForServices(ctx context.Context, services *v1.ServiceList) ([]*v1.Pod, error) {
+func (c *Controller) getPodsForServices(ctx context.Context, services []*v1.Service) ([]*v1.Pod, error) {
 	pods := []*v1.Pod{}
 	podsMap := map[types.NamespacedName]struct{}{}
 
-	for _, service := range services.Items {
-		podList, err := c.getPodsForService(ctx, &service)
+	for _, service := range services {
+		podList, err := c.getPodsForService(ctx, service)
 		if err != nil {
 			return nil, err
 		}
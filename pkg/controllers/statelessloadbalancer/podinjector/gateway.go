@@ -22,16 +22,22 @@ import (
 	"net"
 
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/api/v1alpha1"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/controllers/gatewayclass"
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/controllers/statelessloadbalancer/podinjector/network"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/l34route"
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/log"
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/networkattachment"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/networking"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/kubernetes/pkg/proxy/apis"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	gatewayapiv1 "sigs.k8s.io/gateway-api/apis/v1"
 )
 
 func (c *Controller) reconcileGateways(ctx context.Context, pod *v1.Pod) error {
+	// the gateways of all namespaces since their attached routes can reference the
+	// services of other namespaces.
 	gateways, err := c.getGatewaysForGatewayClass(ctx)
 	if err != nil {
 		return err
@@ -55,20 +61,12 @@ func (c *Controller) reconcileGateways(ctx context.Context, pod *v1.Pod) error {
 }
 
 func (c *Controller) reconcileGateway(ctx context.Context, gateway *gatewayapiv1.Gateway, pod *v1.Pod) (*v1.Pod, error) {
-	serviceList := &v1.ServiceList{}
-
-	err := c.List(ctx,
-		serviceList,
-		client.MatchingLabels{
-			apis.LabelServiceProxyName: gateway.Name,
-		},
-		client.InNamespace(pod.GetNamespace()),
-	)
+	services, err := l34route.Services(ctx, c, gateway)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list the services: %w", err)
+		return nil, fmt.Errorf("failed to get the services: %w", err)
 	}
 
-	if len(filterServices(pod, serviceList.Items)) == 0 {
+	if len(filterServices(pod, services)) == 0 {
 		return pod, nil
 	}
 
@@ -82,6 +80,7 @@ func (c *Controller) reconcileGateway(ctx context.Context, gateway *gatewayapiv1
 
 	updatedPod, err := network.SetNetworkAttachmentAnnotation(
 		pod,
+		c.FirstTableID,
 		gateway.GetName(),
 		vipV4,
 		vipV6,
@@ -104,26 +103,17 @@ func getVIPs(
 	vipMap := map[string]struct{}{}
 
 	for _, address := range gateway.Status.Addresses {
-		ipAddr := net.ParseIP(address.Value)
-
-		if ipAddr == nil {
+		vip, ok := networking.GatewayAddressCIDR(address)
+		if !ok {
 			continue
 		}
 
-		v4 := true
-		vip := fmt.Sprintf("%s/32", ipAddr)
-
-		if ipAddr.To4() == nil {
-			vip = fmt.Sprintf("%s/128", ipAddr)
-			v4 = false
-		}
-
 		_, exists := vipMap[vip]
 		if exists {
 			continue
 		}
 
-		if v4 {
+		if ip, _, _ := net.ParseCIDR(vip); ip.To4() != nil {
 			vipsV4 = append(vipsV4, vip)
 		} else {
 			vipsV6 = append(vipsV6, vip)
@@ -146,7 +136,9 @@ func (c *Controller) getGatewayIPs(
 		pods,
 		client.MatchingLabels{
 			apis.LabelServiceProxyName: gateway.GetName(),
-		}) // todo: filter namespace
+		},
+		client.InNamespace(gateway.GetNamespace()),
+	)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed listing the service proxy data-plane pods: %w", err)
 	}
@@ -178,14 +170,20 @@ func (c *Controller) getGatewayIPs(
 	return ipv4, ipv6, nil
 }
 
+// filterServices returns the services selecting the pod. The services select the pods
+// of their namespace.
 func filterServices(
 	pod *v1.Pod,
-	services []v1.Service,
+	services []*v1.Service,
 ) []*v1.Service {
 	res := []*v1.Service{}
 
 items:
 	for _, service := range services {
+		if service.GetNamespace() != pod.GetNamespace() {
+			continue
+		}
+
 		for labelSelectorKey, labelSelectorValue := range service.Spec.Selector {
 			if labelSelectorKey == v1alpha1.LabelDummmySericeSelector {
 				continue
@@ -196,13 +194,13 @@ items:
 				continue items
 			}
 		}
-		ns := service
-		res = append(res, &ns)
+		res = append(res, service)
 	}
 
 	return res
 }
 
+// getGatewaysForGatewayClass returns the gateways of the gateway class.
 func (c *Controller) getGatewaysForGatewayClass(ctx context.Context) ([]gatewayapiv1.Gateway, error) {
 	gatewayList := &gatewayapiv1.GatewayList{}
 
@@ -212,7 +210,8 @@ func (c *Controller) getGatewaysForGatewayClass(ctx context.Context) ([]gatewaya
 	// 		"spec.gatewayClassName": c.GatewayClassName,
 	// 	})
 	err := c.List(ctx,
-		gatewayList) // todo: filter namespace
+		gatewayList,
+	)
 	if err != nil {
 		log.FromContextOrGlobal(ctx).Error(err, "failed listing the gateways during the pod enqueue")
 
@@ -222,7 +221,12 @@ func (c *Controller) getGatewaysForGatewayClass(ctx context.Context) ([]gatewaya
 	gateways := []gatewayapiv1.Gateway{}
 
 	for _, gateway := range gatewayList.Items {
-		if string(gateway.Spec.GatewayClassName) != c.GatewayClassName {
+		manages, err := gatewayclass.Manages(ctx, c, c.GatewayClassName, &gateway)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+
+		if !manages {
 			continue
 		}
 
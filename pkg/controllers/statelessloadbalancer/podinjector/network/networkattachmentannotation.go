@@ -30,12 +30,16 @@ import (
 const (
 	vipNADName         = "vip"
 	policyRouteNADName = "policy-route"
+	// DefaultFirstTableID is the default first kernel table used by the policy routes in the pods.
+	DefaultFirstTableID = 5000
 )
 
 // SetNetworkAttachmentAnnotation modifies the network attachment annotation of the pod to correspond to
 // the stateless load balancer requirement (VIP + policy routes) with the parameters (VIPs, Gateways).
+// The policy routes use a free kernel table starting from firstTableID.
 func SetNetworkAttachmentAnnotation(
 	pod *v1.Pod,
+	firstTableID int,
 	serviceProxy string,
 	vipV4 []string,
 	vipV6 []string,
@@ -60,6 +64,7 @@ func SetNetworkAttachmentAnnotation(
 
 	newNetworkSelectionElements := getNetworkAttachmentAnnotation(
 		networkSelectionElements,
+		firstTableID,
 		serviceProxy,
 		vipV4,
 		vipV6,
@@ -79,6 +84,7 @@ func SetNetworkAttachmentAnnotation(
 
 func getNetworkAttachmentAnnotation(
 	networkSelectionElements []netdefv1.NetworkSelectionElement,
+	firstTableID int,
 	serviceProxy string,
 	vipV4 []string,
 	vipV6 []string,
@@ -110,7 +116,7 @@ func getNetworkAttachmentAnnotation(
 		return networkSelectionElements
 	}
 
-	tableID := getFreeTableID(networkSelectionElements)
+	tableID := getFreeTableID(networkSelectionElements, firstTableID)
 	policyRoutesV4 := []cni.PolicyRoute{}
 	policyRoutesV6 := []cni.PolicyRoute{}
 
@@ -183,8 +189,13 @@ func getNetworkAttachmentAnnotation(
 
 func getFreeTableID(
 	networkSelectionElements []netdefv1.NetworkSelectionElement,
+	firstTableID int,
 ) int {
-	tableID := 5000
+	tableID := firstTableID
+	if tableID <= 0 {
+		tableID = DefaultFirstTableID
+	}
+
 	usedTableIDs := map[int]struct{}{}
 
 	for _, networkSelectionElement := range networkSelectionElements {
@@ -39,6 +39,9 @@ type Controller struct {
 	// GetIPsFunc is used when the endpointSlice will be reconciled to get the IPs
 	// of the pods attached to the service.
 	GetIPsFunc endpointslice.GetIPs
+	// FirstTableID is the first kernel table used by the policy routes in the pods
+	// (network.DefaultFirstTableID if not set).
+	FirstTableID int
 }
 
 // Reconcile implements the reconciliation of the pod object.
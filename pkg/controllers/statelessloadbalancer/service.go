@@ -20,30 +20,16 @@ import (
 	"context"
 	"fmt"
 
-	v1 "k8s.io/api/core/v1"
-	"k8s.io/kubernetes/pkg/proxy/apis"
-	"sigs.k8s.io/controller-runtime/pkg/client"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/l34route"
 	gatewayapiv1 "sigs.k8s.io/gateway-api/apis/v1"
 )
 
 func (c *Controller) reconcileServices(ctx context.Context, gateway *gatewayapiv1.Gateway) error {
-	serviceList := &v1.ServiceList{}
-
-	// Get pods for this service so the endpointslices can be reconciled.
-	matchingLabels := client.MatchingLabels{
-		apis.LabelServiceProxyName: gateway.Name,
-	}
-
-	err := c.List(ctx, serviceList, matchingLabels) // todo: filter namespace
+	// the services of the gateway namespace and the services of the other namespaces
+	// referenced by the routes attached to the gateway.
+	services, err := l34route.Services(ctx, c, gateway)
 	if err != nil {
-		return fmt.Errorf("failed to list services: %w", err)
-	}
-
-	services := []*v1.Service{}
-
-	for _, service := range serviceList.Items {
-		s := service
-		services = append(services, &s)
+		return fmt.Errorf("failed to get the services: %w", err)
 	}
 
 	err = c.ServiceManager.SetServices(ctx, services)
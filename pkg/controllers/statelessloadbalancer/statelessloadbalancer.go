@@ -21,6 +21,7 @@ import (
 	"fmt"
 
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/api/v1alpha1"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/readiness"
 	v1 "k8s.io/api/core/v1"
 	v1discovery "k8s.io/api/discovery/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -29,12 +30,14 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	gatewayapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayapiv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
 )
 
 type serviceManager interface {
 	SetServices(ctx context.Context, services []*v1.Service) error
 	SetFlows(ctx context.Context, l34Routes []*v1alpha1.L34Route) error
 	SetEndpoints(ctx context.Context, service *v1.Service, endpoints []v1discovery.Endpoint) error
+	ReadyVIPs() []string
 }
 
 // Controller reconciles the Gateway Object to run the stateless-load-balancer.
@@ -47,6 +50,9 @@ type Controller struct {
 	Namespace        string
 	ServiceManager   serviceManager
 	GatewayClassName string
+	// ReadinessFile is the file shared with the router in which the VIPs ready
+	// to be announced are written. Nothing is written if empty.
+	ReadinessFile string
 }
 
 // Reconcile implements the reconciliation of the Gateway of Stateless-load-balancer class.
@@ -78,27 +84,49 @@ func (c *Controller) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 	}
 
 	err = c.reconcileServices(ctx, gateway)
-	if err != nil {
-		return ctrl.Result{}, err
+	if err == nil {
+		err = c.reconcileL34Routes(ctx, gateway)
 	}
 
-	err = c.reconcileL34Routes(ctx, gateway)
+	// The readiness is written even if the reconciliation partially failed
+	// so the router withdraws the VIPs that are no longer served.
+	errReadiness := c.writeReadiness()
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 
+	if errReadiness != nil {
+		return ctrl.Result{}, errReadiness
+	}
+
 	return ctrl.Result{}, nil
 }
 
+// writeReadiness writes the VIPs ready to be announced into the readiness file.
+func (c *Controller) writeReadiness() error {
+	if c.ReadinessFile == "" {
+		return nil
+	}
+
+	err := readiness.Write(c.ReadinessFile, c.ServiceManager.ReadyVIPs())
+	if err != nil {
+		return fmt.Errorf("failed to write the readiness: %w", err)
+	}
+
+	return nil
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (c *Controller) SetupWithManager(mgr ctrl.Manager) error {
 	err := ctrl.NewControllerManagedBy(mgr).
 		For(&gatewayapiv1.Gateway{}).
 		// With EnqueueRequestsFromMapFunc, on an update the func is called twice
 		// (1 time for old and 1 time for new object)
-		Watches(&v1.Service{}, handler.EnqueueRequestsFromMapFunc(serviceEnqueue)).
+		Watches(&v1.Service{}, handler.EnqueueRequestsFromMapFunc(c.serviceEnqueue)).
 		Watches(&v1discovery.EndpointSlice{}, handler.EnqueueRequestsFromMapFunc(c.endpointSliceEnqueue)).
-		Watches(&v1alpha1.L34Route{}, handler.EnqueueRequestsFromMapFunc(l34RouteEnqueue)).
+		Watches(&v1alpha1.L34Route{}, handler.EnqueueRequestsFromMapFunc(c.l34RouteEnqueue)).
+		Watches(&gatewayapiv1beta1.ReferenceGrant{}, handler.EnqueueRequestsFromMapFunc(c.gatewayEnqueue)).
+		Watches(&v1.Namespace{}, handler.EnqueueRequestsFromMapFunc(c.gatewayEnqueue)).
 		Complete(c)
 	if err != nil {
 		return fmt.Errorf("failed to build the stateless-load-balancer: %w", err)
@@ -0,0 +1,200 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package endpointslice
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/api/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	v1discovery "k8s.io/api/discovery/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/kubernetes/pkg/proxy/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	gatewayapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+// SetOwnership labels the EndpointSlice with the controller managing it and
+// the gateway it has been generated for, so it can be found once stale.
+func SetOwnership(endpointSlice *v1discovery.EndpointSlice, managedBy string, gateway *gatewayapiv1.Gateway) {
+	if endpointSlice.Labels == nil {
+		endpointSlice.Labels = map[string]string{}
+	}
+
+	endpointSlice.Labels[v1discovery.LabelManagedBy] = managedBy
+	endpointSlice.Labels[v1alpha1.LabelGatewayName] = gateway.GetName()
+	endpointSlice.Labels[v1alpha1.LabelGatewayNamespace] = gateway.GetNamespace()
+}
+
+// DeleteStale deletes the EndpointSlices managed by managedBy for the gateway which do not
+// belong to any of the services (e.g. the service lost its service-proxy-name label).
+// All of them are deleted if services is empty (e.g. the gateway is deleted or changed class).
+// The EndpointSlices created before they were labelled with their manager and gateway are
+// deleted too if they are stale (see isStaleUnlabelled), the others get the labels once
+// their service is reconciled.
+func DeleteStale(
+	ctx context.Context,
+	c client.Client,
+	managedBy string,
+	gateway *gatewayapiv1.Gateway,
+	services []*v1.Service,
+) error {
+	endpointSlices := &v1discovery.EndpointSliceList{}
+
+	err := c.List(ctx, endpointSlices, client.MatchingLabels{
+		v1discovery.LabelManagedBy:     managedBy,
+		v1alpha1.LabelGatewayName:      gateway.GetName(),
+		v1alpha1.LabelGatewayNamespace: gateway.GetNamespace(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list the endpointslices: %w", err)
+	}
+
+	serviceSet := map[types.NamespacedName]struct{}{}
+
+	for _, service := range services {
+		serviceSet[types.NamespacedName{Name: service.GetName(), Namespace: service.GetNamespace()}] = struct{}{}
+	}
+
+	for index := range endpointSlices.Items {
+		endpointSlice := &endpointSlices.Items[index]
+
+		_, exists := serviceSet[types.NamespacedName{
+			Name:      endpointSlice.GetLabels()[v1discovery.LabelServiceName],
+			Namespace: endpointSlice.GetNamespace(),
+		}]
+		if exists {
+			continue
+		}
+
+		err = deleteEndpointSlice(ctx, c, endpointSlice)
+		if err != nil {
+			return err
+		}
+	}
+
+	return deleteStaleUnlabelled(ctx, c, gateway, serviceSet)
+}
+
+// deleteStaleUnlabelled deletes the stale EndpointSlices which have no manager label.
+func deleteStaleUnlabelled(
+	ctx context.Context,
+	c client.Client,
+	gateway *gatewayapiv1.Gateway,
+	serviceSet map[types.NamespacedName]struct{},
+) error {
+	unmanaged, err := labels.NewRequirement(v1discovery.LabelManagedBy, selection.DoesNotExist, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create the endpointslice selector: %w", err)
+	}
+
+	withService, err := labels.NewRequirement(v1discovery.LabelServiceName, selection.Exists, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create the endpointslice selector: %w", err)
+	}
+
+	endpointSlices := &v1discovery.EndpointSliceList{}
+
+	err = c.List(ctx, endpointSlices, client.MatchingLabelsSelector{
+		Selector: labels.NewSelector().Add(*unmanaged, *withService),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list the unlabelled endpointslices: %w", err)
+	}
+
+	for index := range endpointSlices.Items {
+		endpointSlice := &endpointSlices.Items[index]
+
+		stale, err := isStaleUnlabelled(ctx, c, gateway, serviceSet, endpointSlice)
+		if err != nil {
+			return err
+		}
+
+		if !stale {
+			continue
+		}
+
+		err = deleteEndpointSlice(ctx, c, endpointSlice)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isStaleUnlabelled returns true if the EndpointSlice without manager label has been generated
+// for a service (it is controlled by the service and named after it) which is not served by
+// the gateway anymore and is not served by another gateway either: the service has no
+// service-proxy-name label, or the label still names the gateway in the namespace of the
+// gateway. The EndpointSlices of the services served by another gateway are left to it.
+func isStaleUnlabelled(
+	ctx context.Context,
+	c client.Client,
+	gateway *gatewayapiv1.Gateway,
+	serviceSet map[types.NamespacedName]struct{},
+	endpointSlice *v1discovery.EndpointSlice,
+) (bool, error) {
+	serviceName := types.NamespacedName{
+		Name:      endpointSlice.GetLabels()[v1discovery.LabelServiceName],
+		Namespace: endpointSlice.GetNamespace(),
+	}
+
+	if _, exists := serviceSet[serviceName]; exists {
+		return false, nil
+	}
+
+	owner := metav1.GetControllerOf(endpointSlice)
+	if owner == nil || owner.Kind != "Service" || owner.Name != serviceName.Name {
+		return false, nil
+	}
+
+	service := &v1.Service{}
+
+	err := c.Get(ctx, serviceName, service)
+	if err != nil {
+		if apierrors.IsNotFound(err) { // the endpointslice is garbage-collected with the service.
+			return false, nil
+		}
+
+		return false, fmt.Errorf("failed to get the service %s: %w", serviceName, err)
+	}
+
+	if service.GetUID() != owner.UID ||
+		endpointSlice.GetName() != GetEndpointSliceName(service, endpointSlice.AddressType) {
+		return false, nil
+	}
+
+	gatewayName, exists := service.GetLabels()[apis.LabelServiceProxyName]
+
+	return !exists || (gatewayName == gateway.GetName() && service.GetNamespace() == gateway.GetNamespace()), nil
+}
+
+func deleteEndpointSlice(ctx context.Context, c client.Client, endpointSlice *v1discovery.EndpointSlice) error {
+	err := c.Delete(ctx, endpointSlice)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete the stale endpointslice %s/%s: %w",
+			endpointSlice.GetNamespace(), endpointSlice.GetName(), err)
+	}
+
+	return nil
+}
@@ -0,0 +1,214 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package endpointslice_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/endpointslice"
+	v1 "k8s.io/api/core/v1"
+	v1discovery "k8s.io/api/discovery/v1"
+	v1meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/kubernetes/pkg/proxy/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	gatewayapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+func TestDeleteStale(t *testing.T) {
+	gateway := &gatewayapiv1.Gateway{ObjectMeta: v1meta.ObjectMeta{Name: "gw", Namespace: "ns-gw"}}
+	otherGateway := &gatewayapiv1.Gateway{ObjectMeta: v1meta.ObjectMeta{Name: "gw", Namespace: "ns-other"}}
+
+	tests := []struct {
+		name     string
+		services []*v1.Service
+		want     []string
+	}{
+		{
+			name:     "all services still belong to the gateway",
+			services: []*v1.Service{getNamespacedService("a", "ns-gw"), getNamespacedService("b", "ns-b")},
+			want:     []string{"a-ipv4", "b-ipv4", "c-ipv4", "d-ipv4", "unmanaged-ipv4"},
+		},
+		{
+			name:     "service removed from the gateway",
+			services: []*v1.Service{getNamespacedService("a", "ns-gw")},
+			want:     []string{"a-ipv4", "c-ipv4", "d-ipv4", "unmanaged-ipv4"},
+		},
+		{
+			name:     "service with the same name in another namespace",
+			services: []*v1.Service{getNamespacedService("a", "ns-gw"), getNamespacedService("b", "ns-gw")},
+			want:     []string{"a-ipv4", "c-ipv4", "d-ipv4", "unmanaged-ipv4"},
+		},
+		{
+			name:     "gateway removed",
+			services: nil,
+			want:     []string{"c-ipv4", "d-ipv4", "unmanaged-ipv4"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithObjects(
+				getManagedEndpointSlice("a", "ns-gw", "manager", gateway),
+				getManagedEndpointSlice("b", "ns-b", "manager", gateway),
+				getManagedEndpointSlice("c", "ns-gw", "other-manager", gateway),
+				getManagedEndpointSlice("d", "ns-gw", "manager", otherGateway),
+				&v1discovery.EndpointSlice{ObjectMeta: v1meta.ObjectMeta{
+					Name:      "unmanaged-ipv4",
+					Namespace: "ns-gw",
+					Labels:    map[string]string{v1discovery.LabelServiceName: "unmanaged"},
+				}},
+			).Build()
+
+			err := endpointslice.DeleteStale(context.TODO(), c, "manager", gateway, tt.services)
+			if err != nil {
+				t.Fatalf("DeleteStale() error = %v", err)
+			}
+
+			endpointSlices := &v1discovery.EndpointSliceList{}
+
+			err = c.List(context.TODO(), endpointSlices, &client.ListOptions{})
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+
+			got := map[string]struct{}{}
+			for _, endpointSlice := range endpointSlices.Items {
+				got[endpointSlice.GetName()] = struct{}{}
+			}
+
+			if len(got) != len(tt.want) {
+				t.Errorf("DeleteStale() remaining = %v, want %v", got, tt.want)
+			}
+
+			for _, name := range tt.want {
+				if _, exists := got[name]; !exists {
+					t.Errorf("DeleteStale() remaining = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestDeleteStaleUnlabelled(t *testing.T) {
+	gateway := &gatewayapiv1.Gateway{ObjectMeta: v1meta.ObjectMeta{Name: "gw", Namespace: "ns-gw"}}
+	served := getLabelledService("served", "ns-gw", "gw")
+
+	c := fake.NewClientBuilder().WithObjects(
+		served,
+		getLabelledService("unserved", "ns-gw", ""),
+		getLabelledService("removed", "ns-gw", "gw"),
+		getLabelledService("other", "ns-gw", "other-gw"),
+		getLabelledService("cross-namespace", "ns-b", "gw"),
+		getUnlabelledEndpointSlice("served", "ns-gw"),
+		getUnlabelledEndpointSlice("unserved", "ns-gw"),
+		getUnlabelledEndpointSlice("removed", "ns-gw"),
+		getUnlabelledEndpointSlice("other", "ns-gw"),
+		getUnlabelledEndpointSlice("cross-namespace", "ns-b"),
+		&v1discovery.EndpointSlice{ObjectMeta: v1meta.ObjectMeta{
+			Name:      "unmanaged-ipv4",
+			Namespace: "ns-gw",
+			Labels:    map[string]string{v1discovery.LabelServiceName: "unserved"},
+		}},
+	).Build()
+
+	err := endpointslice.DeleteStale(context.TODO(), c, "manager", gateway, []*v1.Service{served})
+	if err != nil {
+		t.Fatalf("DeleteStale() error = %v", err)
+	}
+
+	endpointSlices := &v1discovery.EndpointSliceList{}
+
+	err = c.List(context.TODO(), endpointSlices, &client.ListOptions{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+
+	got := []string{}
+	for _, endpointSlice := range endpointSlices.Items {
+		got = append(got, endpointSlice.GetName())
+	}
+
+	want := []string{"cross-namespace-ipv4", "other-ipv4", "served-ipv4", "unmanaged-ipv4"}
+	if !slices.Equal(got, want) {
+		t.Errorf("DeleteStale() remaining = %v, want %v", got, want)
+	}
+}
+
+func getLabelledService(name string, namespace string, gatewayName string) *v1.Service {
+	service := getNamespacedService(name, namespace)
+	service.UID = types.UID(namespace + "/" + name)
+
+	if gatewayName != "" {
+		service.Labels = map[string]string{apis.LabelServiceProxyName: gatewayName}
+	}
+
+	return service
+}
+
+// getUnlabelledEndpointSlice returns an EndpointSlice as generated before the manager
+// and gateway labels existed.
+func getUnlabelledEndpointSlice(serviceName string, namespace string) *v1discovery.EndpointSlice {
+	service := getLabelledService(serviceName, namespace, "")
+	controller := true
+
+	return &v1discovery.EndpointSlice{
+		ObjectMeta: v1meta.ObjectMeta{
+			Name:      endpointslice.GetEndpointSliceName(service, v1discovery.AddressTypeIPv4),
+			Namespace: namespace,
+			Labels:    map[string]string{v1discovery.LabelServiceName: serviceName},
+			OwnerReferences: []v1meta.OwnerReference{{
+				APIVersion: "v1",
+				Kind:       "Service",
+				Name:       serviceName,
+				UID:        service.UID,
+				Controller: &controller,
+			}},
+		},
+		AddressType: v1discovery.AddressTypeIPv4,
+	}
+}
+
+func getNamespacedService(name string, namespace string) *v1.Service {
+	service := getService(name)
+	service.Namespace = namespace
+
+	return service
+}
+
+func getManagedEndpointSlice(
+	serviceName string,
+	namespace string,
+	managedBy string,
+	gateway *gatewayapiv1.Gateway,
+) *v1discovery.EndpointSlice {
+	service := getNamespacedService(serviceName, namespace)
+	endpointSlice := &v1discovery.EndpointSlice{
+		ObjectMeta: v1meta.ObjectMeta{
+			Name:      endpointslice.GetEndpointSliceName(service, v1discovery.AddressTypeIPv4),
+			Namespace: namespace,
+			Labels:    map[string]string{v1discovery.LabelServiceName: serviceName},
+		},
+		AddressType: v1discovery.AddressTypeIPv4,
+	}
+
+	endpointslice.SetOwnership(endpointSlice, managedBy, gateway)
+
+	return endpointSlice
+}
@@ -0,0 +1,670 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frr
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/api/v1alpha1"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/bird"
+)
+
+// ipFamily contains the FRR keywords of an IP family.
+type ipFamily struct {
+	// suffix of the names of the prefix lists and route maps.
+	name          string
+	addressFamily string
+	prefixList    string
+	match         string
+	nextHop       string
+	defaultRoute  string
+	maxLength     int
+	ipv4          bool
+}
+
+//nolint:gochecknoglobals
+var ipFamilies = []*ipFamily{
+	{
+		name:          "v4",
+		addressFamily: "ipv4 unicast",
+		prefixList:    "ip prefix-list",
+		match:         "ip address prefix-list",
+		nextHop:       "ip next-hop",
+		defaultRoute:  "0.0.0.0/0",
+		maxLength:     net.IPv4len * 8, //nolint:gomnd
+		ipv4:          true,
+	},
+	{
+		name:          "v6",
+		addressFamily: "ipv6 unicast",
+		prefixList:    "ipv6 prefix-list",
+		match:         "ipv6 address prefix-list",
+		nextHop:       "ipv6 next-hop global",
+		defaultRoute:  "::/0",
+		maxLength:     net.IPv6len * 8, //nolint:gomnd
+		ipv4:          false,
+	},
+}
+
+// containsCIDR returns true if the CIDR is valid and belongs to the IP family.
+func (ipf *ipFamily) containsCIDR(cidr string) bool {
+	ip, _, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return false
+	}
+
+	return (ip.To4() != nil) == ipf.ipv4
+}
+
+// containsIP returns true if the IP is valid and belongs to the IP family.
+func (ipf *ipFamily) containsIP(address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+
+	return (ip.To4() != nil) == ipf.ipv4
+}
+
+// getConfig returns the FRR configuration announcing the VIPs to the BGP gateways. The BGP
+// passwords are only part of the configuration if withPasswords is true, so they are never
+// written in the configuration file (see FRR.apply).
+func (f *FRR) getConfig(vips []bird.VIP, gateways []bird.Gateway, withPasswords bool) string {
+	gateways = bgpGateways(gateways)
+
+	var conf strings.Builder
+
+	conf.WriteString(baseConfig)
+
+	for _, family := range ipFamilies {
+		conf.WriteString(prefixListsConfig(family, vips, gateways))
+	}
+
+	conf.WriteString(fmt.Sprintf(gatewayRoutesTemplate, f.TableID))
+	conf.WriteString(fmt.Sprintf(blackholeRoutesTemplate, f.TableID, f.TableID))
+	conf.WriteString(vipRouteMapsConfig(vips))
+
+	for _, gateway := range gateways {
+		for _, family := range ipFamilies {
+			if !family.containsIP(gateway.GetAddress()) {
+				continue
+			}
+
+			conf.WriteString(importRouteMapConfig(gateway, family))
+			conf.WriteString(exportRouteMapConfig(gateway, family, vips, f.localASN(gateway)))
+		}
+	}
+
+	conf.WriteString(f.routerBgpConfig(vips, gateways, withPasswords))
+	conf.WriteString(bfdConfig(gateways))
+
+	return conf.String()
+}
+
+// prefixListsConfig returns the prefix lists of the announced VIPs, of the VIPs with an
+// AS path prepend, of the VIPs exported to the gateways not receiving all of them and of
+// the routes imported from the gateways with an import policy.
+func prefixListsConfig(family *ipFamily, vips []bird.VIP, gateways []bird.Gateway) string {
+	conf := ""
+	announced := []string{}
+
+	for _, vip := range vips {
+		if !family.containsCIDR(vip.GetCIDR()) {
+			continue
+		}
+
+		announced = append(announced, vip.GetCIDR())
+
+		if asPathPrepend(vip) > 0 {
+			conf += prefixListConfig(family, vipName(vip.GetCIDR()), []string{vip.GetCIDR()})
+		}
+	}
+
+	if len(announced) > 0 {
+		conf = prefixListConfig(family, announcedRoutesName(family), announced) + conf
+	}
+
+	for _, gateway := range gateways {
+		exported := exportedCIDRs(gateway, family, vips)
+		if family.containsIP(gateway.GetAddress()) && len(exported) > 0 && len(exported) < len(announced) {
+			conf += prefixListConfig(family, exportName(gateway, family), exported)
+		}
+	}
+
+	for _, gateway := range gateways {
+		if gateway.GetImportPolicy() == nil || !family.containsIP(gateway.GetAddress()) {
+			continue
+		}
+
+		prefixes := importPrefixes(gateway.GetImportPolicy(), family)
+		if len(prefixes) > 0 {
+			conf += prefixListConfig(family, importName(gateway, family), prefixes)
+		}
+	}
+
+	return conf
+}
+
+func prefixListConfig(family *ipFamily, name string, prefixes []string) string {
+	conf := ""
+
+	for i, prefix := range prefixes {
+		conf += fmt.Sprintf(prefixListTemplate, family.prefixList, name, (i+1)*10, prefix) //nolint:gomnd
+	}
+
+	return conf + "!\n"
+}
+
+// importPrefixes returns the prefixes (with the more specific ones) imported from a gateway.
+func importPrefixes(importPolicy bird.ImportPolicy, family *ipFamily) []string {
+	prefixes := []string{}
+
+	if importPolicy.GetDefaultRoute() == nil || *importPolicy.GetDefaultRoute() {
+		prefixes = append(prefixes, family.defaultRoute)
+	}
+
+	for _, cidr := range importPolicy.GetPrefixes() {
+		if !family.containsCIDR(cidr) {
+			continue
+		}
+
+		_, ipNet, _ := net.ParseCIDR(cidr)
+		ones, _ := ipNet.Mask.Size()
+
+		if ones == family.maxLength {
+			prefixes = append(prefixes, ipNet.String())
+
+			continue
+		}
+
+		prefixes = append(prefixes, fmt.Sprintf("%s le %d", ipNet.String(), family.maxLength))
+	}
+
+	return prefixes
+}
+
+// vipRouteMapsConfig returns the route maps setting the BGP attributes (communities, large
+// communities, MED and local preference) of the VIPs.
+func vipRouteMapsConfig(vips []bird.VIP) string {
+	conf := ""
+
+	for _, vip := range vips {
+		statements := bgpAttributesStatements(vip.GetBgpAttributes())
+		if len(statements) == 0 {
+			continue
+		}
+
+		conf += fmt.Sprintf(routeMapPermitTemplate, vipName(vip.GetCIDR()), 10) //nolint:gomnd
+
+		for _, statement := range statements {
+			conf += fmt.Sprintf(routeMapSetTemplate, statement)
+		}
+
+		conf += routeMapEnd
+	}
+
+	return conf
+}
+
+func bgpAttributesStatements(attributes bird.BgpAttributes) []string {
+	statements := []string{}
+
+	if attributes == nil {
+		return statements
+	}
+
+	communities := validCommunities(attributes.GetCommunities(), communityParts, communityPartSize)
+	if len(communities) > 0 {
+		statements = append(statements, fmt.Sprintf("community %s additive", strings.Join(communities, " ")))
+	}
+
+	largeCommunities := validCommunities(attributes.GetLargeCommunities(), largeCommunityParts, largeCommunityPartSize)
+	if len(largeCommunities) > 0 {
+		statements = append(statements,
+			fmt.Sprintf("large-community %s additive", strings.Join(largeCommunities, " ")))
+	}
+
+	if attributes.GetMED() != nil {
+		statements = append(statements, fmt.Sprintf("metric %d", *attributes.GetMED()))
+	}
+
+	if attributes.GetLocalPreference() != nil {
+		statements = append(statements, fmt.Sprintf("local-preference %d", *attributes.GetLocalPreference()))
+	}
+
+	return statements
+}
+
+// validCommunities returns the communities made of the given number of unsigned
+// integers of the given size separated by colons. The invalid ones are skipped.
+func validCommunities(communities []string, parts int, bitSize int) []string {
+	valid := []string{}
+
+	for _, community := range communities {
+		values := strings.Split(community, ":")
+		if len(values) != parts {
+			continue
+		}
+
+		ok := true
+
+		for _, value := range values {
+			if _, err := strconv.ParseUint(value, 10, bitSize); err != nil {
+				ok = false
+
+				break
+			}
+		}
+
+		if ok {
+			valid = append(valid, community)
+		}
+	}
+
+	return valid
+}
+
+// importRouteMapConfig returns the import route map of a gateway accepting only the prefixes
+// of its import policy. The gateways without import policy have no import route map, so the
+// default routes and all the routes received over BGP are imported.
+func importRouteMapConfig(gateway bird.Gateway, family *ipFamily) string {
+	if gateway.GetImportPolicy() == nil {
+		return ""
+	}
+
+	name := importName(gateway, family)
+
+	if len(importPrefixes(gateway.GetImportPolicy(), family)) == 0 {
+		return fmt.Sprintf(routeMapDenyTemplate, name)
+	}
+
+	conf := fmt.Sprintf(routeMapPermitTemplate, name, 10) //nolint:gomnd
+	conf += fmt.Sprintf(routeMapMatchTemplate, family.match, name)
+	conf += routeMapEnd
+
+	return conf
+}
+
+// exportRouteMapConfig returns the export route map of a gateway. Only the VIPs exported to
+// the gateway are announced, the local ASN is prepended to the AS path of the VIPs requesting
+// it, and the next hop is overridden if the gateway has a next hop of the same IP family.
+func exportRouteMapConfig(gateway bird.Gateway, family *ipFamily, vips []bird.VIP, localASN uint32) string {
+	name := exportName(gateway, family)
+
+	// the gateway receives all the announced routes, or only those of its own prefix list.
+	announcedRoutes := announcedRoutesName(family)
+	if len(exportedCIDRs(gateway, family, vips)) < len(exportedCIDRs(nil, family, vips)) {
+		announcedRoutes = name
+	}
+
+	announced := false
+	conf := ""
+	sequence := 0
+
+	nextHop := ""
+	if family.containsIP(gateway.GetBgpSpec().GetNextHop()) {
+		nextHop = fmt.Sprintf(routeMapSetTemplate, family.nextHop+" "+gateway.GetBgpSpec().GetNextHop())
+	}
+
+	for _, vip := range bird.ExportedVIPs(vips, gateway) {
+		if !family.containsCIDR(vip.GetCIDR()) {
+			continue
+		}
+
+		announced = true
+
+		prepend := asPathPrepend(vip)
+		if prepend == 0 {
+			continue
+		}
+
+		sequence += 10
+		asPath := strings.TrimSpace(strings.Repeat(fmt.Sprintf(" %d", localASN), int(prepend)))
+
+		conf += fmt.Sprintf(routeMapPermitTemplate, name, sequence)
+		conf += fmt.Sprintf(routeMapMatchTemplate, family.match, vipName(vip.GetCIDR()))
+		conf += fmt.Sprintf(routeMapSetTemplate, "as-path prepend "+asPath)
+		conf += nextHop
+		conf += routeMapEnd
+	}
+
+	if !announced {
+		return fmt.Sprintf(routeMapDenyTemplate, name)
+	}
+
+	sequence += 10
+
+	conf += fmt.Sprintf(routeMapPermitTemplate, name, sequence)
+	conf += fmt.Sprintf(routeMapMatchTemplate, family.match, announcedRoutes)
+	conf += nextHop
+	conf += routeMapEnd
+
+	return conf
+}
+
+// exportedCIDRs returns the CIDRs of the VIPs of the IP family exported to the gateway
+// (all of them if the gateway is nil).
+func exportedCIDRs(gateway bird.Gateway, family *ipFamily, vips []bird.VIP) []string {
+	if gateway != nil {
+		vips = bird.ExportedVIPs(vips, gateway)
+	}
+
+	cidrs := []string{}
+
+	for _, vip := range vips {
+		if family.containsCIDR(vip.GetCIDR()) {
+			cidrs = append(cidrs, vip.GetCIDR())
+		}
+	}
+
+	return cidrs
+}
+
+// routerBgpConfig returns the BGP instance with a neighbor per gateway. The gateways with a
+// local ASN different from the one of the BGP instance use it with the local-as option.
+func (f *FRR) routerBgpConfig(vips []bird.VIP, gateways []bird.Gateway, withPasswords bool) string {
+	conf := fmt.Sprintf(routerBgpTemplate, f.ASN)
+	conf += globalGracefulRestartConfig(gateways)
+
+	for _, gateway := range gateways {
+		conf += f.neighborConfig(gateway, withPasswords)
+	}
+
+	for _, family := range ipFamilies {
+		addressFamily := ""
+
+		for _, vip := range vips {
+			if !family.containsCIDR(vip.GetCIDR()) {
+				continue
+			}
+
+			option := ""
+			if len(bgpAttributesStatements(vip.GetBgpAttributes())) > 0 {
+				option = " route-map " + vipName(vip.GetCIDR())
+			}
+
+			addressFamily += fmt.Sprintf(networkTemplate, vip.GetCIDR(), option)
+		}
+
+		for _, gateway := range gateways {
+			if family.containsIP(gateway.GetAddress()) {
+				addressFamily += addressFamilyNeighborConfig(gateway, family)
+			}
+		}
+
+		conf += fmt.Sprintf(addressFamilyTemplate, family.addressFamily, addressFamily, maximumPaths)
+	}
+
+	return conf + routerBgpEnd
+}
+
+func (f *FRR) neighborConfig(gateway bird.Gateway, withPasswords bool) string {
+	bgpSpec := gateway.GetBgpSpec()
+	address := gateway.GetAddress()
+
+	remoteASN := defaultRemoteASN
+	if bgpSpec.GetRemoteASN() != nil {
+		remoteASN = *bgpSpec.GetRemoteASN()
+	}
+
+	remotePort := defaultRemotePort
+	if bgpSpec.GetRemotePort() != nil {
+		remotePort = *bgpSpec.GetRemotePort()
+	}
+
+	// a hold time of 0 turns off the hold timer and the keepalives.
+	holdTime := seconds(bgpSpec.GetHoldTime(), defaultHoldTime, 0, 0)
+	if holdTime != 0 {
+		holdTime = max(holdTime, minHoldTime)
+	}
+
+	options := []string{
+		fmt.Sprintf("remote-as %d", remoteASN),
+		"description " + gateway.GetName(),
+	}
+
+	if localASN := f.localASN(gateway); localASN != f.ASN {
+		options = append(options, fmt.Sprintf("local-as %d no-prepend replace-as", localASN))
+	}
+
+	options = append(options,
+		fmt.Sprintf("port %d", remotePort),
+		fmt.Sprintf("timers %d %d", holdTime/keepaliveRatio, holdTime))
+
+	if withPasswords && bgpSpec.GetPassword() != "" {
+		options = append(options, "password "+bgpSpec.GetPassword())
+	}
+
+	if bgpSpec.GetMultihop() != nil {
+		options = append(options, fmt.Sprintf("ebgp-multihop %d", *bgpSpec.GetMultihop()))
+	}
+
+	if net.ParseIP(bgpSpec.GetSourceAddress()) != nil {
+		options = append(options, "update-source "+bgpSpec.GetSourceAddress())
+	} else if bgpSpec.GetMultihop() == nil && gateway.GetInterface() != "" {
+		options = append(options, "update-source "+gateway.GetInterface())
+	}
+
+	if bfdEnabled(bgpSpec.GetBfdSpec()) {
+		options = append(options, "bfd profile "+gateway.GetName())
+	}
+
+	if gracefulRestartEnabled(bgpSpec.GetGracefulRestart()) {
+		options = append(options, "graceful-restart")
+	}
+
+	conf := ""
+
+	for _, option := range options {
+		conf += fmt.Sprintf(neighborTemplate, address, option)
+	}
+
+	return conf
+}
+
+func addressFamilyNeighborConfig(gateway bird.Gateway, family *ipFamily) string {
+	address := gateway.GetAddress()
+
+	options := []string{"activate"}
+
+	if gateway.GetImportPolicy() != nil {
+		options = append(options, fmt.Sprintf("route-map %s in", importName(gateway, family)))
+	}
+
+	options = append(options, fmt.Sprintf("route-map %s out", exportName(gateway, family)))
+
+	if gateway.GetImportPolicy() != nil && gateway.GetImportPolicy().GetMaxPrefixes() != nil {
+		options = append(options, fmt.Sprintf("maximum-prefix %d", *gateway.GetImportPolicy().GetMaxPrefixes()))
+	}
+
+	conf := ""
+
+	for _, option := range options {
+		conf += fmt.Sprintf(addressFamilyNeighborTemplate, address, option)
+	}
+
+	return conf
+}
+
+// globalGracefulRestartConfig returns the graceful restart timers of the BGP instance. FRR
+// has a single restart time and stale time for all the neighbors, the highest ones of the
+// gateways with graceful restart are used.
+func globalGracefulRestartConfig(gateways []bird.Gateway) string {
+	enabled := false
+
+	var restartTime, staleTime uint64
+
+	for _, gateway := range gateways {
+		gracefulRestart := gateway.GetBgpSpec().GetGracefulRestart()
+		if !gracefulRestartEnabled(gracefulRestart) {
+			continue
+		}
+
+		enabled = true
+		restartTime = max(restartTime, seconds(gracefulRestart.GetRestartTime(),
+			defaultGracefulRestartTime, 0, maxGracefulRestartTime))
+		staleTime = max(staleTime, seconds(gracefulRestart.GetLongLivedStaleTime(), 0, 0, maxLongLivedStaleTime))
+	}
+
+	if !enabled {
+		return ""
+	}
+
+	conf := fmt.Sprintf(bgpGracefulRestartTemplate, restartTime)
+
+	if staleTime > 0 {
+		conf += fmt.Sprintf(bgpLongLivedGracefulRestartTemplate, staleTime)
+	}
+
+	return conf
+}
+
+// bfdConfig returns a BFD profile per gateway with BFD turned on.
+func bfdConfig(gateways []bird.Gateway) string {
+	profiles := ""
+
+	for _, gateway := range gateways {
+		bfd := gateway.GetBgpSpec().GetBfdSpec()
+		if !bfdEnabled(bfd) {
+			continue
+		}
+
+		options := ""
+
+		if bfd.GetMultiplier() != nil {
+			options += fmt.Sprintf("  detect-multiplier %d\n", *bfd.GetMultiplier())
+		}
+
+		if minRx := milliseconds(bfd.GetMinRx()); minRx > 0 {
+			options += fmt.Sprintf("  receive-interval %d\n", minRx)
+		}
+
+		if minTx := milliseconds(bfd.GetMinTx()); minTx > 0 {
+			options += fmt.Sprintf("  transmit-interval %d\n", minTx)
+		}
+
+		profiles += fmt.Sprintf(bfdProfileTemplate, gateway.GetName(), options)
+	}
+
+	if profiles == "" {
+		return ""
+	}
+
+	return fmt.Sprintf(bfdTemplate, profiles)
+}
+
+func (f *FRR) localASN(gateway bird.Gateway) uint32 {
+	if gateway.GetBgpSpec().GetLocalASN() != nil {
+		return *gateway.GetBgpSpec().GetLocalASN()
+	}
+
+	return f.ASN
+}
+
+// bgpGateways returns the BGP gateways with a valid address sorted by name, so the
+// configuration does not depend on the order of the gateways.
+func bgpGateways(gateways []bird.Gateway) []bird.Gateway {
+	bgpGateways := []bird.Gateway{}
+
+	for _, gateway := range gateways {
+		if gateway.GetProtocol() != v1alpha1.BGP || gateway.GetBgpSpec() == nil ||
+			net.ParseIP(gateway.GetAddress()) == nil {
+			continue
+		}
+
+		bgpGateways = append(bgpGateways, gateway)
+	}
+
+	sort.SliceStable(bgpGateways, func(i, j int) bool {
+		return bgpGateways[i].GetName() < bgpGateways[j].GetName()
+	})
+
+	return bgpGateways
+}
+
+func asPathPrepend(vip bird.VIP) uint32 {
+	if vip.GetBgpAttributes() == nil || vip.GetBgpAttributes().GetASPathPrepend() == nil {
+		return 0
+	}
+
+	return *vip.GetBgpAttributes().GetASPathPrepend()
+}
+
+func bfdEnabled(bfd bird.BfdSpec) bool {
+	return bfd != nil && bfd.GetSwitch() != nil && *bfd.GetSwitch()
+}
+
+func gracefulRestartEnabled(gracefulRestart bird.GracefulRestartSpec) bool {
+	return gracefulRestart != nil && gracefulRestart.GetSwitch() != nil && *gracefulRestart.GetSwitch()
+}
+
+func announcedRoutesName(family *ipFamily) string {
+	return "announced-routes-" + family.name
+}
+
+func importName(gateway bird.Gateway, family *ipFamily) string {
+	return fmt.Sprintf("%s-import-%s", gateway.GetName(), family.name)
+}
+
+func exportName(gateway bird.Gateway, family *ipFamily) string {
+	return fmt.Sprintf("%s-export-%s", gateway.GetName(), family.name)
+}
+
+// vipName returns the name of the prefix list and route map of a VIP (e.g. vip-20.0.0.1_32).
+func vipName(cidr string) string {
+	return "vip-" + strings.NewReplacer("/", "_", ":", "-").Replace(cidr)
+}
+
+// seconds parses a duration and rounds it by second. The default value is returned
+// if the value is empty or invalid, the value is bounded by min and max (if not 0).
+func seconds(duration string, defaultValue uint64, minValue uint64, maxValue uint64) uint64 {
+	if duration == "" {
+		return defaultValue
+	}
+
+	parsed, err := time.ParseDuration(duration)
+	if err != nil || parsed < 0 {
+		return defaultValue
+	}
+
+	value := uint64(parsed.Round(time.Second) / time.Second)
+
+	switch {
+	case value < minValue:
+		return minValue
+	case maxValue > 0 && value > maxValue:
+		return maxValue
+	}
+
+	return value
+}
+
+// milliseconds parses a BFD timer and rounds it by millisecond. 0 is returned if
+// the value is empty or invalid so the FRR default is used.
+func milliseconds(interval string) int64 {
+	duration, err := time.ParseDuration(interval)
+	if err != nil || duration <= 0 {
+		return 0
+	}
+
+	return int64(duration.Round(time.Millisecond) / time.Millisecond)
+}
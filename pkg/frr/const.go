@@ -0,0 +1,136 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frr
+
+const (
+	defaultHoldTime                   = 3
+	minHoldTime                       = 3
+	keepaliveRatio                    = 3
+	defaultGracefulRestartTime        = 120
+	maxGracefulRestartTime            = 4095
+	maxLongLivedStaleTime             = 16777215
+	defaultLocalASN            uint32 = 8103
+	defaultRemoteASN           uint32 = 4248829953
+	defaultRemotePort          uint16 = 10179
+	maximumPaths                      = 64
+	communityParts                    = 2
+	largeCommunityParts               = 3
+	communityPartSize                 = 16
+	largeCommunityPartSize            = 32
+)
+
+const baseConfig = `frr defaults traditional
+log stdout informational
+service integrated-vtysh-config
+!
+`
+
+// The routes received from the gateways are installed into the kernel table used by
+// the policy routes of the VIPs.
+// 0: kernel table ID
+const gatewayRoutesTemplate = `route-map gateway-routes permit 10
+ set table %d
+exit
+!
+`
+
+// The blackhole default routes drop the traffic from the VIPs when no default route is
+// received from the gateways (the administrative distance 255 is never installed by zebra).
+// 0: kernel table ID
+// 1: kernel table ID
+const blackholeRoutesTemplate = `ip route 0.0.0.0/0 blackhole 254 table %d
+ipv6 route ::/0 blackhole 254 table %d
+!
+`
+
+// 0: prefix list command (ip/ipv6 prefix-list)
+// 1: name of the prefix list
+// 2: sequence number
+// 3: prefix (with the le option)
+const prefixListTemplate = "%s %s seq %d permit %s\n"
+
+// 0: name of the route map
+// 1: sequence number
+const routeMapPermitTemplate = "route-map %s permit %d\n"
+
+// Route map rejecting all the routes.
+// 0: name of the route map
+const routeMapDenyTemplate = `route-map %s deny 10
+exit
+!
+`
+
+// 0: match command (ip/ipv6 address prefix-list)
+// 1: name of the prefix list
+const routeMapMatchTemplate = " match %s %s\n"
+
+// 0: set statement
+const routeMapSetTemplate = " set %s\n"
+
+const routeMapEnd = "exit\n!\n"
+
+// Only the VIPs are announced to the gateways. The default routes and the routes
+// received from the other gateways are never announced.
+// 0: local ASN
+const routerBgpTemplate = `router bgp %d
+ no bgp ebgp-requires-policy
+ no bgp default ipv4-unicast
+ no bgp network import-check
+ bgp bestpath as-path multipath-relax
+`
+
+// 0: restart time (in seconds)
+const bgpGracefulRestartTemplate = " bgp graceful-restart restart-time %d\n"
+
+// 0: stale time (in seconds)
+const bgpLongLivedGracefulRestartTemplate = " bgp long-lived-graceful-restart stale-time %d\n"
+
+// 0: neighbor address
+// 1: neighbor option
+const neighborTemplate = " neighbor %s %s\n"
+
+// 0: address family (ipv4 unicast/ipv6 unicast)
+// 1: networks and neighbors
+const addressFamilyTemplate = ` !
+ address-family %s
+%s  maximum-paths %d
+  table-map gateway-routes
+ exit-address-family
+`
+
+// 0: network (VIP)
+// 1: network option (route map)
+const networkTemplate = "  network %s%s\n"
+
+// 0: neighbor address
+// 1: neighbor option
+const addressFamilyNeighborTemplate = "  neighbor %s %s\n"
+
+const routerBgpEnd = "exit\n!\n"
+
+// 0: profiles
+const bfdTemplate = `bfd
+%sexit
+!
+`
+
+// 0: name of the profile
+// 1: profile options
+const bfdProfileTemplate = ` profile %s
+%s exit
+ !
+`
@@ -0,0 +1,29 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frr
+
+import "github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/bird"
+
+// GetConfig returns the FRR configuration of the VIPs and gateways written in the configuration file.
+func (f *FRR) GetConfig(vips []bird.VIP, gateways []bird.Gateway) string {
+	return f.getConfig(vips, gateways, false)
+}
+
+// GetAppliedConfig returns the FRR configuration of the VIPs and gateways applied to the daemons.
+func (f *FRR) GetAppliedConfig(vips []bird.VIP, gateways []bird.Gateway) string {
+	return f.getConfig(vips, gateways, true)
+}
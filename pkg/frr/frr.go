@@ -0,0 +1,345 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/bird"
+)
+
+var errFRRRunning = errors.New("frr is already running")
+
+// frrStopTimeout is the time given to the FRR daemons to stop.
+const frrStopTimeout = 10 * time.Second
+
+// FRR is a routing suite based on the FRR daemons (bgpd, bfdd and zebra). The configuration
+// (frr.conf) is rendered from the VIPs and the gateways and applied with frr-reload. The VIPs
+// are announced to the gateways and the routes received from the gateways are installed into
+// the kernel table used by the policy routes of the VIPs, as with bird.
+//
+// The BGP passwords are never written in the configuration file, the configuration with the
+// passwords is only applied from a temporary file readable by its owner.
+//
+// The local port of the gateways is ignored since it is set by the bgpd options (-p) in the
+// FRR daemons file. The import limit of a gateway closes the BGP session when exceeded, and
+// the routes rejected by the import policies are not counted by the import statistics.
+type FRR struct {
+	// ConfigFile is the FRR configuration file (with path).
+	ConfigFile string
+	// InitScript is the script starting and stopping the FRR daemons.
+	InitScript string
+	// ReloadScript is the script applying the configuration file to the running daemons.
+	ReloadScript string
+	// Vtysh is the FRR shell used to validate the configuration and to get the statistics.
+	Vtysh string
+	// ASN is the ASN of the BGP instance, used for the gateways without local ASN.
+	ASN uint32
+	// TableID is the ID of the kernel table the received routes are installed into.
+	TableID int
+	// RulePriority is the priority of the policy rules routing the traffic from the VIPs via TableID.
+	RulePriority int
+
+	running bool
+	// configuration (with the BGP passwords) currently applied in FRR.
+	appliedConfig string
+	// last configuration (with the BGP passwords) and its version written in the configuration file.
+	config     string
+	fileConfig string
+	// gateways by neighbor address, used to name the import statistics.
+	gatewaysByAddress map[string]bird.Gateway
+	// source addresses of the BGP sessions added to the loopback interface.
+	sourceAddresses []string
+	mu              sync.Mutex
+}
+
+// New is the FRR constructor.
+func New() *FRR {
+	return &FRR{
+		ConfigFile:        "/etc/frr/frr.conf",
+		InitScript:        "/usr/lib/frr/frrinit.sh",
+		ReloadScript:      "/usr/lib/frr/frr-reload.py",
+		Vtysh:             "vtysh",
+		ASN:               defaultLocalASN,
+		TableID:           bird.DefaultRoutingTables().TableID,
+		RulePriority:      bird.DefaultRoutingTables().RulePriority,
+		gatewaysByAddress: map[string]bird.Gateway{},
+	}
+}
+
+// Run starts the FRR daemons with the current configuration. The daemons are stopped
+// when the context in parameter is cancelled.
+func (f *FRR) Run(ctx context.Context) error {
+	f.mu.Lock()
+
+	if f.running {
+		f.mu.Unlock()
+
+		return errFRRRunning
+	}
+
+	// Write empty config if config file does not exist
+	if _, err := os.Stat(f.ConfigFile); errors.Is(err, os.ErrNotExist) {
+		err := f.writeConfig(f.getConfig([]bird.VIP{}, []bird.Gateway{}, false))
+		if err != nil {
+			f.mu.Unlock()
+
+			return err
+		}
+	}
+
+	err := f.command(ctx, f.InitScript, "start")
+	if err != nil {
+		f.mu.Unlock()
+
+		return fmt.Errorf("failed starting frr ; %w", err)
+	}
+
+	// the configuration file does not contain the BGP passwords.
+	if f.config != f.fileConfig {
+		err = f.apply(ctx, f.config, f.fileConfig)
+		if err != nil {
+			f.mu.Unlock()
+
+			return errors.Join(fmt.Errorf("failed to apply the bgp passwords: %w", err), f.stop(ctx))
+		}
+	}
+
+	f.running = true
+
+	f.mu.Unlock()
+
+	<-ctx.Done()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.running = false
+
+	return f.stop(ctx)
+}
+
+// stop stops the FRR daemons, the context in parameter might already be cancelled.
+func (f *FRR) stop(ctx context.Context) error {
+	f.appliedConfig = ""
+
+	stopCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), frrStopTimeout)
+	defer cancel()
+
+	err := f.command(stopCtx, f.InitScript, "stop")
+	if err != nil {
+		return fmt.Errorf("failed stopping frr ; %w", err)
+	}
+
+	return nil
+}
+
+// Configure writes the FRR configuration file, adds the source addresses of the BGP sessions to the
+// loopback interface, sets the policy routes and reloads FRR if it is running.
+//
+// When FRR is running, the new configuration is validated by vtysh before being written and applied.
+// FRR is not reloaded if the configuration has not changed since the last one applied.
+func (f *FRR) Configure(ctx context.Context, vips []bird.VIP, gateways []bird.Gateway) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var err error
+
+	conf := f.getConfig(vips, gateways, true)
+	fileConf := f.getConfig(vips, gateways, false)
+
+	if f.running {
+		err = f.apply(ctx, conf, fileConf)
+	} else {
+		err = f.writeConfig(fileConf)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	f.config = conf
+	f.fileConfig = fileConf
+
+	f.gatewaysByAddress = map[string]bird.Gateway{}
+
+	for _, gateway := range bgpGateways(gateways) {
+		f.gatewaysByAddress[gateway.GetAddress()] = gateway
+	}
+
+	sourceAddresses := bird.SourceAddressCIDRs(gateways)
+
+	f.sourceAddresses, err = bird.SetSourceAddresses(sourceAddresses, f.sourceAddresses)
+	if err != nil {
+		return fmt.Errorf("failed to set the source addresses %v: %w", sourceAddresses, err)
+	}
+
+	policyRoutes := []string{}
+
+	for _, vip := range vips {
+		policyRoutes = append(policyRoutes, vip.GetCIDR())
+	}
+
+	policyRoutes = append(policyRoutes, sourceAddresses...)
+
+	err = bird.SetPolicyRoutes(policyRoutes, f.TableID, f.RulePriority)
+	if err != nil {
+		return fmt.Errorf("failed to set the policy routes %v: %w", policyRoutes, err)
+	}
+
+	return nil
+}
+
+// summary is the output of "show bgp summary json" (per address family).
+type summary map[string]struct {
+	Peers map[string]struct {
+		PfxRcd int `json:"pfxRcd"`
+	} `json:"peers"`
+}
+
+// GetImportStatistics returns the statistics of the routes imported from each
+// BGP gateway (key: name of the gateway).
+func (f *FRR) GetImportStatistics(ctx context.Context) (map[string]*bird.ImportStatistics, error) {
+	f.mu.Lock()
+	gatewaysByAddress := f.gatewaysByAddress
+	f.mu.Unlock()
+
+	output, err := exec.CommandContext(ctx, f.Vtysh, "-c", "show bgp summary json").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the bgp summary: %w", err)
+	}
+
+	bgpSummary := summary{}
+
+	err = json.Unmarshal(output, &bgpSummary)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the bgp summary: %w", err)
+	}
+
+	statistics := map[string]*bird.ImportStatistics{}
+
+	for _, addressFamily := range bgpSummary {
+		for address, peer := range addressFamily.Peers {
+			gateway, exists := gatewaysByAddress[address]
+			if !exists {
+				continue
+			}
+
+			gatewayStatistics, exists := statistics[gateway.GetName()]
+			if !exists {
+				gatewayStatistics = &bird.ImportStatistics{}
+				statistics[gateway.GetName()] = gatewayStatistics
+			}
+
+			gatewayStatistics.Imported += peer.PfxRcd
+		}
+	}
+
+	return statistics, nil
+}
+
+// apply validates the configuration (with the BGP passwords), reloads FRR with it and
+// writes the configuration file (without the BGP passwords).
+func (f *FRR) apply(ctx context.Context, conf string, fileConf string) error {
+	if conf == f.appliedConfig {
+		return nil
+	}
+
+	candidate, err := writeTempFile(f.ConfigFile, conf)
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(candidate)
+
+	err = f.command(ctx, f.Vtysh, "--dryrun", "--inputfile", candidate)
+	if err != nil {
+		return fmt.Errorf("the configuration is invalid: %w", err)
+	}
+
+	f.appliedConfig = ""
+
+	err = f.command(ctx, f.ReloadScript, "--reload", candidate)
+	if err != nil {
+		return fmt.Errorf("failed reloading frr ; %w", err)
+	}
+
+	f.appliedConfig = conf
+
+	return f.writeConfig(fileConf)
+}
+
+func (f *FRR) writeConfig(conf string) error {
+	// write into a temporary file and rename it so FRR never reads a partial file.
+	file, err := writeTempFile(f.ConfigFile, conf)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(file, f.ConfigFile)
+	if err != nil {
+		_ = os.Remove(file)
+
+		return fmt.Errorf("failed to rename %v to %v, err: %w", file, f.ConfigFile, err)
+	}
+
+	return nil
+}
+
+func (f *FRR) command(ctx context.Context, name string, args ...string) error {
+	stdoutStderr, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s %v: %w; %s", name, args, err, stdoutStderr)
+	}
+
+	return nil
+}
+
+// writeTempFile writes the configuration into a temporary file next to the
+// configuration file and returns its path. The file is only readable by its
+// owner since the configuration applied contains the BGP passwords.
+func writeTempFile(configFile string, conf string) (string, error) {
+	file, err := os.CreateTemp(filepath.Dir(configFile), filepath.Base(configFile)+".tmp")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file for %v, err: %w", configFile, err)
+	}
+
+	_, err = file.WriteString(conf)
+	if err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+
+		return "", fmt.Errorf("failed to write config to %v, err: %w", file.Name(), err)
+	}
+
+	err = file.Close()
+	if err != nil {
+		_ = os.Remove(file.Name())
+
+		return "", fmt.Errorf("failed to close %v, err: %w", file.Name(), err)
+	}
+
+	return file.Name(), nil
+}
@@ -0,0 +1,528 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frr_test
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/api/v1alpha1"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/bird"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/frr"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func TestFRR_GetConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		vips     []bird.VIP
+		gateways []bird.Gateway
+		// lines only part of the configuration applied to FRR.
+		wantAppliedOnly []string
+	}{
+		{
+			name:     "empty",
+			vips:     []bird.VIP{},
+			gateways: []bird.Gateway{},
+		},
+		{
+			name: "dual-stack",
+			vips: []bird.VIP{
+				&vip{cidr: "20.0.0.1/32"},
+				&vip{cidr: "2000::1/128"},
+			},
+			gateways: []bird.Gateway{
+				&gateway{
+					name:     "gateway-v6",
+					address:  "fd00:100::150",
+					intf:     "vlan-100",
+					protocol: v1alpha1.BGP,
+					bgp: &bgpSpec{
+						bfd: &bfdSpec{sw: newBool(false)},
+					},
+				},
+				&gateway{
+					name:     "gateway-v4",
+					address:  "169.254.100.150",
+					intf:     "vlan-100",
+					protocol: v1alpha1.BGP,
+					bgp: &bgpSpec{
+						remoteASN:  newUint32(4248829953),
+						localASN:   newUint32(8103),
+						holdTime:   "24s",
+						remotePort: newUint16(179),
+						password:   "secret",
+						bfd: &bfdSpec{
+							sw:         newBool(true),
+							minTx:      "300ms",
+							minRx:      "300ms",
+							multiplier: newUint16(5),
+						},
+					},
+				},
+				&gateway{
+					name:     "gateway-static",
+					address:  "169.254.100.151",
+					protocol: v1alpha1.Static,
+				},
+			},
+			wantAppliedOnly: []string{" neighbor 169.254.100.150 password secret\n"},
+		},
+		{
+			name: "import-policy",
+			vips: []bird.VIP{
+				&vip{cidr: "20.0.0.1/32"},
+			},
+			gateways: []bird.Gateway{
+				&gateway{
+					name:     "gateway-a",
+					address:  "169.254.100.150",
+					intf:     "vlan-100",
+					protocol: v1alpha1.BGP,
+					bgp: &bgpSpec{
+						bfd: &bfdSpec{},
+					},
+					importPolicy: &importPolicy{
+						prefixes:    []string{"10.0.0.0/8", "192.168.1.1/32", "fd00::/8"},
+						maxPrefixes: newUint32(100),
+					},
+				},
+				&gateway{
+					name:     "gateway-b",
+					address:  "169.254.100.151",
+					intf:     "vlan-100",
+					protocol: v1alpha1.BGP,
+					bgp: &bgpSpec{
+						holdTime: "0s",
+						bfd:      &bfdSpec{},
+					},
+					importPolicy: &importPolicy{
+						defaultRoute: newBool(false),
+					},
+				},
+			},
+		},
+		{
+			name: "attributes",
+			vips: []bird.VIP{
+				&vip{
+					cidr: "20.0.0.1/32",
+					attributes: &bgpAttributes{
+						communities:      []string{"65000:100", "invalid", "65000:200"},
+						largeCommunities: []string{"65000:1:2"},
+						med:              newUint32(10),
+						localPreference:  newUint32(200),
+						asPathPrepend:    newUint32(2),
+					},
+				},
+				&vip{cidr: "20.0.0.2/32"},
+				&vip{
+					cidr: "2000::1/128",
+					attributes: &bgpAttributes{
+						asPathPrepend: newUint32(1),
+					},
+				},
+			},
+			gateways: []bird.Gateway{
+				&gateway{
+					name:     "gateway-a",
+					address:  "169.254.100.150",
+					intf:     "vlan-100",
+					protocol: v1alpha1.BGP,
+					bgp: &bgpSpec{
+						bfd: &bfdSpec{},
+					},
+				},
+			},
+		},
+		{
+			name: "multihop",
+			vips: []bird.VIP{
+				&vip{cidr: "20.0.0.1/32"},
+			},
+			gateways: []bird.Gateway{
+				&gateway{
+					name:     "gateway-a",
+					address:  "10.0.0.1",
+					intf:     "vlan-100",
+					protocol: v1alpha1.BGP,
+					bgp: &bgpSpec{
+						localASN:      newUint32(65001),
+						multihop:      newUint8(4),
+						sourceAddress: "192.168.0.1",
+						nextHop:       "192.168.0.2",
+						bfd:           &bfdSpec{sw: newBool(true)},
+						gracefulRestart: &gracefulRestart{
+							sw:                 newBool(true),
+							restartTime:        "90s",
+							longLivedStaleTime: "1h",
+						},
+					},
+				},
+				&gateway{
+					name:     "gateway-b",
+					address:  "169.254.100.151",
+					intf:     "vlan-100",
+					protocol: v1alpha1.BGP,
+					bgp: &bgpSpec{
+						nextHop: "2000::2",
+						bfd:     &bfdSpec{},
+						gracefulRestart: &gracefulRestart{
+							sw: newBool(true),
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "export",
+			vips: []bird.VIP{
+				&vip{cidr: "20.0.0.1/32", exportedTo: []string{"gateway-a"}},
+				&vip{
+					cidr:       "20.0.0.2/32",
+					exportedTo: []string{"gateway-a", "gateway-b"},
+					attributes: &bgpAttributes{
+						asPathPrepend: newUint32(1),
+					},
+				},
+				&vip{cidr: "20.0.0.3/32"},
+				&vip{cidr: "2000::1/128", exportedTo: []string{"gateway-a"}},
+			},
+			gateways: []bird.Gateway{
+				&gateway{
+					name:     "gateway-a",
+					address:  "169.254.100.150",
+					intf:     "vlan-100",
+					protocol: v1alpha1.BGP,
+					bgp: &bgpSpec{
+						bfd: &bfdSpec{},
+					},
+				},
+				&gateway{
+					name:     "gateway-b",
+					address:  "169.254.101.150",
+					intf:     "vlan-101",
+					protocol: v1alpha1.BGP,
+					bgp: &bgpSpec{
+						bfd: &bfdSpec{},
+					},
+				},
+				&gateway{
+					name:     "gateway-b-v6",
+					address:  "fd00:101::150",
+					intf:     "vlan-101",
+					protocol: v1alpha1.BGP,
+					bgp: &bgpSpec{
+						bfd: &bfdSpec{},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := frr.New()
+
+			got := f.GetConfig(tt.vips, tt.gateways)
+
+			golden := filepath.Join("testdata", tt.name+".conf")
+
+			if *update {
+				err := os.WriteFile(golden, []byte(got), 0o600)
+				if err != nil {
+					t.Fatalf("failed to update %v: %v", golden, err)
+				}
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("failed to read %v: %v", golden, err)
+			}
+
+			if got != string(want) {
+				t.Errorf("FRR.GetConfig() = \n%v\nwant (%v):\n%v", got, golden, string(want))
+			}
+
+			if got != f.GetConfig(tt.vips, reverse(tt.gateways)) {
+				t.Errorf("FRR.GetConfig() depends on the order of the gateways")
+			}
+
+			applied := f.GetAppliedConfig(tt.vips, tt.gateways)
+			for _, line := range tt.wantAppliedOnly {
+				if !strings.Contains(applied, line) {
+					t.Errorf("FRR.GetAppliedConfig() = \n%v\nwant line %q", applied, line)
+				}
+
+				applied = strings.Replace(applied, line, "", 1)
+			}
+
+			if applied != got {
+				t.Errorf("FRR.GetAppliedConfig() = \n%v\nwant (%v without %q):\n%v", applied, golden, tt.wantAppliedOnly, got)
+			}
+		})
+	}
+}
+
+func reverse[T any](items []T) []T {
+	reversed := make([]T, 0, len(items))
+
+	for i := len(items) - 1; i >= 0; i-- {
+		reversed = append(reversed, items[i])
+	}
+
+	return reversed
+}
+
+type vip struct {
+	cidr       string
+	attributes *bgpAttributes
+	exportedTo []string
+}
+
+func (v *vip) GetCIDR() string {
+	return v.cidr
+}
+
+func (v *vip) GetBgpAttributes() bird.BgpAttributes {
+	if v.attributes == nil {
+		return nil
+	}
+
+	return v.attributes
+}
+
+func (v *vip) GetExportedTo() []string {
+	return v.exportedTo
+}
+
+type bgpAttributes struct {
+	communities      []string
+	largeCommunities []string
+	med              *uint32
+	localPreference  *uint32
+	asPathPrepend    *uint32
+}
+
+func (ba *bgpAttributes) GetCommunities() []string {
+	return ba.communities
+}
+
+func (ba *bgpAttributes) GetLargeCommunities() []string {
+	return ba.largeCommunities
+}
+
+func (ba *bgpAttributes) GetMED() *uint32 {
+	return ba.med
+}
+
+func (ba *bgpAttributes) GetLocalPreference() *uint32 {
+	return ba.localPreference
+}
+
+func (ba *bgpAttributes) GetASPathPrepend() *uint32 {
+	return ba.asPathPrepend
+}
+
+type gateway struct {
+	name         string
+	address      string
+	intf         string
+	protocol     v1alpha1.RoutingProtocol
+	bgp          *bgpSpec
+	importPolicy *importPolicy
+}
+
+func (gw *gateway) GetName() string {
+	return gw.name
+}
+
+func (gw *gateway) GetAddress() string {
+	return gw.address
+}
+
+func (gw *gateway) GetInterface() string {
+	return gw.intf
+}
+
+func (gw *gateway) GetProtocol() v1alpha1.RoutingProtocol {
+	return gw.protocol
+}
+
+func (gw *gateway) GetBgpSpec() bird.BgpSpec {
+	if gw.bgp == nil {
+		return nil
+	}
+
+	return gw.bgp
+}
+
+func (gw *gateway) GetStatic() bird.StaticSpec {
+	return nil
+}
+
+func (gw *gateway) GetOspfSpec() bird.OspfSpec {
+	return nil
+}
+
+func (gw *gateway) GetImportPolicy() bird.ImportPolicy {
+	if gw.importPolicy == nil {
+		return nil
+	}
+
+	return gw.importPolicy
+}
+
+type importPolicy struct {
+	prefixes     []string
+	defaultRoute *bool
+	maxPrefixes  *uint32
+}
+
+func (ip *importPolicy) GetPrefixes() []string {
+	return ip.prefixes
+}
+
+func (ip *importPolicy) GetDefaultRoute() *bool {
+	return ip.defaultRoute
+}
+
+func (ip *importPolicy) GetMaxPrefixes() *uint32 {
+	return ip.maxPrefixes
+}
+
+type bgpSpec struct {
+	remoteASN       *uint32
+	localASN        *uint32
+	bfd             *bfdSpec
+	holdTime        string
+	remotePort      *uint16
+	password        string
+	multihop        *uint8
+	sourceAddress   string
+	nextHop         string
+	gracefulRestart *gracefulRestart
+}
+
+func (bgps *bgpSpec) GetRemoteASN() *uint32 {
+	return bgps.remoteASN
+}
+
+func (bgps *bgpSpec) GetLocalASN() *uint32 {
+	return bgps.localASN
+}
+
+func (bgps *bgpSpec) GetBfdSpec() bird.BfdSpec {
+	return bgps.bfd
+}
+
+func (bgps *bgpSpec) GetHoldTime() string {
+	return bgps.holdTime
+}
+
+func (bgps *bgpSpec) GetRemotePort() *uint16 {
+	return bgps.remotePort
+}
+
+func (bgps *bgpSpec) GetLocalPort() *uint16 {
+	return nil
+}
+
+func (bgps *bgpSpec) GetPassword() string {
+	return bgps.password
+}
+
+func (bgps *bgpSpec) GetMultihop() *uint8 {
+	return bgps.multihop
+}
+
+func (bgps *bgpSpec) GetSourceAddress() string {
+	return bgps.sourceAddress
+}
+
+func (bgps *bgpSpec) GetNextHop() string {
+	return bgps.nextHop
+}
+
+func (bgps *bgpSpec) GetGracefulRestart() bird.GracefulRestartSpec {
+	if bgps.gracefulRestart == nil {
+		return nil
+	}
+
+	return bgps.gracefulRestart
+}
+
+type gracefulRestart struct {
+	sw                 *bool
+	restartTime        string
+	longLivedStaleTime string
+}
+
+func (gr *gracefulRestart) GetSwitch() *bool {
+	return gr.sw
+}
+
+func (gr *gracefulRestart) GetRestartTime() string {
+	return gr.restartTime
+}
+
+func (gr *gracefulRestart) GetLongLivedStaleTime() string {
+	return gr.longLivedStaleTime
+}
+
+type bfdSpec struct {
+	sw         *bool
+	minTx      string
+	minRx      string
+	multiplier *uint16
+}
+
+func (bfds *bfdSpec) GetSwitch() *bool {
+	return bfds.sw
+}
+
+func (bfds *bfdSpec) GetMinTx() string {
+	return bfds.minTx
+}
+
+func (bfds *bfdSpec) GetMinRx() string {
+	return bfds.minRx
+}
+
+func (bfds *bfdSpec) GetMultiplier() *uint16 {
+	return bfds.multiplier
+}
+
+func newBool(val bool) *bool {
+	return &val
+}
+
+func newUint8(val uint8) *uint8 {
+	return &val
+}
+
+func newUint16(val uint16) *uint16 {
+	return &val
+}
+
+func newUint32(val uint32) *uint32 {
+	return &val
+}
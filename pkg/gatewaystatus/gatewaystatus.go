@@ -0,0 +1,253 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gatewaystatus
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	gatewayapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+// Workload is the readiness of the workload (Deployment/DaemonSet) running the data plane of
+// a gateway. It is derived from the readiness of the data plane pods, which reflects the
+// readiness probes of their containers (e.g. the routing suite of the router running and
+// answering on its control socket).
+type Workload struct {
+	// Ready is true if the latest generation of the workload has been observed and at
+	// least one of its pods is ready.
+	Ready bool
+	// Message describes the readiness of the workload.
+	Message string
+}
+
+// DeploymentWorkload returns the readiness of a deployment: the deployment is ready once
+// the latest generation has been observed and at least one replica is ready.
+func DeploymentWorkload(deployment *appsv1.Deployment) Workload {
+	if deployment == nil {
+		return Workload{Message: "The deployment of the data plane does not exist"}
+	}
+
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+
+	return Workload{
+		Ready: deployment.Status.ObservedGeneration >= deployment.GetGeneration() &&
+			deployment.Status.ReadyReplicas > 0,
+		Message: fmt.Sprintf("%d/%d replicas of the deployment %s are ready",
+			deployment.Status.ReadyReplicas, replicas, deployment.GetName()),
+	}
+}
+
+// DaemonSetWorkload returns the readiness of a daemonset: the daemonset is ready once the
+// latest generation has been observed and at least one pod is ready.
+func DaemonSetWorkload(daemonSet *appsv1.DaemonSet) Workload {
+	if daemonSet == nil {
+		return Workload{Message: "The daemonset of the data plane does not exist"}
+	}
+
+	return Workload{
+		Ready: daemonSet.Status.ObservedGeneration >= daemonSet.GetGeneration() &&
+			daemonSet.Status.NumberReady > 0,
+		Message: fmt.Sprintf("%d/%d pods of the daemonset %s are ready",
+			daemonSet.Status.NumberReady, daemonSet.Status.DesiredNumberScheduled, daemonSet.GetName()),
+	}
+}
+
+// Options are the capabilities of the gateway class used to compute the status of the gateway.
+type Options struct {
+	// Protocols supported by the listeners (e.g. TCP, UDP, SCTP).
+	Protocols []gatewayapiv1.ProtocolType
+	// Kinds are the route kinds supported by the listeners. If the gateway class supports
+	// no route kind, the kinds allowed by the listeners are not checked.
+	Kinds []gatewayapiv1.RouteGroupKind
+	// AttachedRoutes is the number of routes attached per listener.
+	AttachedRoutes map[gatewayapiv1.SectionName]int32
+}
+
+// Set sets the Accepted and Programmed conditions of the gateway and the status of its
+// listeners. A listener is accepted if its protocol is supported and the gateway is
+// accepted if at least one listener is accepted (or if it has no listener). The gateway
+// and its accepted listeners are programmed if the workload is ready (see Workload).
+func Set(gateway *gatewayapiv1.Gateway, workload Workload, options Options) {
+	listenerStatuses := []gatewayapiv1.ListenerStatus{}
+	invalidListeners := []string{}
+
+	for _, listener := range gateway.Spec.Listeners {
+		listenerStatus := listenerStatusFor(gateway, listener, workload, options)
+		if !meta.IsStatusConditionTrue(listenerStatus.Conditions, string(gatewayapiv1.ListenerConditionAccepted)) {
+			invalidListeners = append(invalidListeners, string(listener.Name))
+		}
+
+		listenerStatuses = append(listenerStatuses, listenerStatus)
+	}
+
+	gateway.Status.Listeners = listenerStatuses
+
+	accepted := metav1.Condition{
+		Type:               string(gatewayapiv1.GatewayConditionAccepted),
+		Status:             metav1.ConditionTrue,
+		Reason:             string(gatewayapiv1.GatewayReasonAccepted),
+		Message:            "The gateway is accepted",
+		ObservedGeneration: gateway.GetGeneration(),
+	}
+
+	if len(invalidListeners) > 0 {
+		accepted.Reason = string(gatewayapiv1.GatewayReasonListenersNotValid)
+		accepted.Message = "Invalid listeners: " + strings.Join(invalidListeners, ", ")
+
+		if len(invalidListeners) == len(gateway.Spec.Listeners) {
+			accepted.Status = metav1.ConditionFalse
+		}
+	}
+
+	programmed := metav1.Condition{
+		Type:               string(gatewayapiv1.GatewayConditionProgrammed),
+		Status:             metav1.ConditionTrue,
+		Reason:             string(gatewayapiv1.GatewayReasonProgrammed),
+		Message:            workload.Message,
+		ObservedGeneration: gateway.GetGeneration(),
+	}
+
+	switch {
+	case accepted.Status == metav1.ConditionFalse:
+		programmed.Status = metav1.ConditionFalse
+		programmed.Reason = string(gatewayapiv1.GatewayReasonInvalid)
+		programmed.Message = "The gateway is not accepted"
+	case !workload.Ready:
+		programmed.Status = metav1.ConditionFalse
+		programmed.Reason = string(gatewayapiv1.GatewayReasonPending)
+	}
+
+	meta.SetStatusCondition(&gateway.Status.Conditions, accepted)
+	meta.SetStatusCondition(&gateway.Status.Conditions, programmed)
+}
+
+func listenerStatusFor(
+	gateway *gatewayapiv1.Gateway,
+	listener gatewayapiv1.Listener,
+	workload Workload,
+	options Options,
+) gatewayapiv1.ListenerStatus {
+	listenerStatus := gatewayapiv1.ListenerStatus{
+		Name:           listener.Name,
+		SupportedKinds: supportedKinds(listener, options.Kinds),
+		AttachedRoutes: options.AttachedRoutes[listener.Name],
+		Conditions:     existingListenerConditions(gateway, listener.Name),
+	}
+
+	accepted := listenerCondition(gateway, gatewayapiv1.ListenerConditionAccepted, true,
+		gatewayapiv1.ListenerReasonAccepted, "The listener is accepted")
+
+	if !slices.ContainsFunc(options.Protocols, func(protocol gatewayapiv1.ProtocolType) bool {
+		return strings.EqualFold(string(protocol), string(listener.Protocol))
+	}) {
+		accepted = listenerCondition(gateway, gatewayapiv1.ListenerConditionAccepted, false,
+			gatewayapiv1.ListenerReasonUnsupportedProtocol,
+			fmt.Sprintf("The protocol %s is not supported", listener.Protocol))
+	}
+
+	resolvedRefs := listenerCondition(gateway, gatewayapiv1.ListenerConditionResolvedRefs, true,
+		gatewayapiv1.ListenerReasonResolvedRefs, "The references of the listener are resolved")
+
+	if len(options.Kinds) > 0 && listener.AllowedRoutes != nil && len(listener.AllowedRoutes.Kinds) > 0 &&
+		len(listener.AllowedRoutes.Kinds) != len(listenerStatus.SupportedKinds) {
+		resolvedRefs = listenerCondition(gateway, gatewayapiv1.ListenerConditionResolvedRefs, false,
+			gatewayapiv1.ListenerReasonInvalidRouteKinds, "Some of the allowed route kinds are not supported")
+	}
+
+	programmed := listenerCondition(gateway, gatewayapiv1.ListenerConditionProgrammed, true,
+		gatewayapiv1.ListenerReasonProgrammed, workload.Message)
+
+	switch {
+	case accepted.Status == metav1.ConditionFalse:
+		programmed = listenerCondition(gateway, gatewayapiv1.ListenerConditionProgrammed, false,
+			gatewayapiv1.ListenerReasonInvalid, "The listener is not accepted")
+	case !workload.Ready:
+		programmed = listenerCondition(gateway, gatewayapiv1.ListenerConditionProgrammed, false,
+			gatewayapiv1.ListenerReasonPending, workload.Message)
+	}
+
+	meta.SetStatusCondition(&listenerStatus.Conditions, accepted)
+	meta.SetStatusCondition(&listenerStatus.Conditions, resolvedRefs)
+	meta.SetStatusCondition(&listenerStatus.Conditions, programmed)
+
+	return listenerStatus
+}
+
+// supportedKinds returns the route kinds allowed by the listener and supported by the
+// gateway class (all supported kinds if the listener does not restrict them).
+func supportedKinds(
+	listener gatewayapiv1.Listener,
+	kinds []gatewayapiv1.RouteGroupKind,
+) []gatewayapiv1.RouteGroupKind {
+	if listener.AllowedRoutes == nil || len(listener.AllowedRoutes.Kinds) == 0 {
+		return slices.Clone(kinds)
+	}
+
+	supported := []gatewayapiv1.RouteGroupKind{}
+
+	for _, allowedKind := range listener.AllowedRoutes.Kinds {
+		if slices.ContainsFunc(kinds, func(kind gatewayapiv1.RouteGroupKind) bool {
+			return kind.Kind == allowedKind.Kind &&
+				allowedKind.Group != nil && kind.Group != nil && *allowedKind.Group == *kind.Group
+		}) {
+			supported = append(supported, allowedKind)
+		}
+	}
+
+	return supported
+}
+
+// existingListenerConditions returns a copy of the conditions of the listener in the
+// status of the gateway, so the last transition times are kept.
+func existingListenerConditions(gateway *gatewayapiv1.Gateway, name gatewayapiv1.SectionName) []metav1.Condition {
+	for _, listenerStatus := range gateway.Status.Listeners {
+		if listenerStatus.Name == name {
+			return slices.Clone(listenerStatus.Conditions)
+		}
+	}
+
+	return []metav1.Condition{}
+}
+
+func listenerCondition(
+	gateway *gatewayapiv1.Gateway,
+	conditionType gatewayapiv1.ListenerConditionType,
+	status bool,
+	reason gatewayapiv1.ListenerConditionReason,
+	message string,
+) metav1.Condition {
+	conditionStatus := metav1.ConditionTrue
+	if !status {
+		conditionStatus = metav1.ConditionFalse
+	}
+
+	return metav1.Condition{
+		Type:               string(conditionType),
+		Status:             conditionStatus,
+		Reason:             string(reason),
+		Message:            message,
+		ObservedGeneration: gateway.GetGeneration(),
+	}
+}
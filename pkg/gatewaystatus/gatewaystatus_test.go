@@ -0,0 +1,201 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gatewaystatus_test
+
+import (
+	"testing"
+
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/gatewaystatus"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	gatewayapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+func TestDeploymentWorkload(t *testing.T) {
+	tests := []struct {
+		name       string
+		deployment *appsv1.Deployment
+		want       bool
+	}{
+		{
+			name:       "no deployment",
+			deployment: nil,
+			want:       false,
+		},
+		{
+			name: "ready",
+			deployment: &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Generation: 2},
+				Status:     appsv1.DeploymentStatus{ObservedGeneration: 2, ReadyReplicas: 1},
+			},
+			want: true,
+		},
+		{
+			name: "generation not observed",
+			deployment: &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Generation: 3},
+				Status:     appsv1.DeploymentStatus{ObservedGeneration: 2, ReadyReplicas: 1},
+			},
+			want: false,
+		},
+		{
+			name: "no replica ready",
+			deployment: &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Generation: 2},
+				Status:     appsv1.DeploymentStatus{ObservedGeneration: 2},
+			},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := gatewaystatus.DeploymentWorkload(tt.deployment); got.Ready != tt.want {
+				t.Errorf("DeploymentWorkload() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSet(t *testing.T) {
+	group := gatewayapiv1.Group("l34.gateway.api.poc")
+	options := gatewaystatus.Options{
+		Protocols: []gatewayapiv1.ProtocolType{gatewayapiv1.TCPProtocolType},
+		Kinds:     []gatewayapiv1.RouteGroupKind{{Group: &group, Kind: "L34Route"}},
+		AttachedRoutes: map[gatewayapiv1.SectionName]int32{
+			"tcp": 2,
+		},
+	}
+
+	tests := []struct {
+		name           string
+		listeners      []gatewayapiv1.Listener
+		workload       gatewaystatus.Workload
+		wantAccepted   metav1.ConditionStatus
+		wantProgrammed metav1.ConditionStatus
+		wantReason     gatewayapiv1.GatewayConditionReason
+	}{
+		{
+			name:           "ready",
+			listeners:      []gatewayapiv1.Listener{{Name: "tcp", Protocol: gatewayapiv1.TCPProtocolType}},
+			workload:       gatewaystatus.Workload{Ready: true},
+			wantAccepted:   metav1.ConditionTrue,
+			wantProgrammed: metav1.ConditionTrue,
+			wantReason:     gatewayapiv1.GatewayReasonAccepted,
+		},
+		{
+			name:           "not ready",
+			listeners:      []gatewayapiv1.Listener{{Name: "tcp", Protocol: gatewayapiv1.TCPProtocolType}},
+			workload:       gatewaystatus.Workload{Ready: false},
+			wantAccepted:   metav1.ConditionTrue,
+			wantProgrammed: metav1.ConditionFalse,
+			wantReason:     gatewayapiv1.GatewayReasonAccepted,
+		},
+		{
+			name: "some listeners invalid",
+			listeners: []gatewayapiv1.Listener{
+				{Name: "tcp", Protocol: gatewayapiv1.TCPProtocolType},
+				{Name: "http", Protocol: gatewayapiv1.HTTPProtocolType},
+			},
+			workload:       gatewaystatus.Workload{Ready: true},
+			wantAccepted:   metav1.ConditionTrue,
+			wantProgrammed: metav1.ConditionTrue,
+			wantReason:     gatewayapiv1.GatewayReasonListenersNotValid,
+		},
+		{
+			name:           "all listeners invalid",
+			listeners:      []gatewayapiv1.Listener{{Name: "http", Protocol: gatewayapiv1.HTTPProtocolType}},
+			workload:       gatewaystatus.Workload{Ready: true},
+			wantAccepted:   metav1.ConditionFalse,
+			wantProgrammed: metav1.ConditionFalse,
+			wantReason:     gatewayapiv1.GatewayReasonListenersNotValid,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gateway := &gatewayapiv1.Gateway{
+				Spec: gatewayapiv1.GatewaySpec{Listeners: tt.listeners},
+			}
+
+			gatewaystatus.Set(gateway, tt.workload, options)
+
+			accepted := meta.FindStatusCondition(gateway.Status.Conditions, string(gatewayapiv1.GatewayConditionAccepted))
+			if accepted == nil || accepted.Status != tt.wantAccepted || accepted.Reason != string(tt.wantReason) {
+				t.Errorf("Set() accepted = %v, want %v (%v)", accepted, tt.wantAccepted, tt.wantReason)
+			}
+
+			programmed := meta.FindStatusCondition(gateway.Status.Conditions, string(gatewayapiv1.GatewayConditionProgrammed))
+			if programmed == nil || programmed.Status != tt.wantProgrammed {
+				t.Errorf("Set() programmed = %v, want %v", programmed, tt.wantProgrammed)
+			}
+
+			if len(gateway.Status.Listeners) != len(tt.listeners) {
+				t.Fatalf("Set() listeners = %v, want %d listeners", gateway.Status.Listeners, len(tt.listeners))
+			}
+
+			if gateway.Status.Listeners[0].Name == "tcp" && gateway.Status.Listeners[0].AttachedRoutes != 2 {
+				t.Errorf("Set() attached routes = %d, want 2", gateway.Status.Listeners[0].AttachedRoutes)
+			}
+		})
+	}
+}
+
+func TestSetResolvedRefs(t *testing.T) {
+	group := gatewayapiv1.Group("l34.gateway.api.poc")
+	httpRouteGroup := gatewayapiv1.Group(gatewayapiv1.GroupName)
+
+	tests := []struct {
+		name  string
+		kinds []gatewayapiv1.RouteGroupKind
+		want  metav1.ConditionStatus
+	}{
+		{
+			name:  "allowed kind not supported",
+			kinds: []gatewayapiv1.RouteGroupKind{{Group: &group, Kind: "L34Route"}},
+			want:  metav1.ConditionFalse,
+		},
+		{
+			name:  "no route kind supported",
+			kinds: nil,
+			want:  metav1.ConditionTrue,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gateway := &gatewayapiv1.Gateway{
+				Spec: gatewayapiv1.GatewaySpec{Listeners: []gatewayapiv1.Listener{{
+					Name:     "tcp",
+					Protocol: gatewayapiv1.TCPProtocolType,
+					AllowedRoutes: &gatewayapiv1.AllowedRoutes{
+						Kinds: []gatewayapiv1.RouteGroupKind{{Group: &httpRouteGroup, Kind: "HTTPRoute"}},
+					},
+				}}},
+			}
+
+			gatewaystatus.Set(gateway, gatewaystatus.Workload{Ready: true}, gatewaystatus.Options{
+				Protocols: []gatewayapiv1.ProtocolType{gatewayapiv1.TCPProtocolType},
+				Kinds:     tt.kinds,
+			})
+
+			resolvedRefs := meta.FindStatusCondition(gateway.Status.Listeners[0].Conditions,
+				string(gatewayapiv1.ListenerConditionResolvedRefs))
+			if resolvedRefs == nil || resolvedRefs.Status != tt.want {
+				t.Errorf("Set() resolved refs = %v, want %v", resolvedRefs, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,466 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gobgp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/go-logr/logr"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/api/v1alpha1"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/bird"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/log"
+	api "github.com/osrg/gobgp/v3/api"
+	"github.com/osrg/gobgp/v3/pkg/server"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	errGoBGPRunning = errors.New("gobgp is already running")
+	errNoRouterID   = errors.New("no IPv4 address found to be used as router ID")
+	// ErrBFDNotSupported is returned by Configure for the gateways with BFD turned on.
+	ErrBFDNotSupported = errors.New("BFD is not supported by gobgp")
+)
+
+// GoBGP is a routing suite based on an embedded GoBGP server. It announces the VIPs
+// to the gateways over BGP and writes the routes received from the gateways into the
+// kernel table used by the policy routes of the VIPs.
+//
+// BFD is not supported by GoBGP. The gateways with BFD turned on are rejected (not
+// configured) and reported by Configure, the failures of the other gateways are detected
+// by the BGP hold timer only.
+type GoBGP struct {
+	// RouterID is the BGP router ID. When empty, the first IPv4 address of the host is used.
+	RouterID string
+	// ASN is the default local ASN, used for the gateways without local ASN.
+	ASN uint32
+	// ListenPort is the port the BGP server is listening on. The local port of the
+	// gateways is ignored since a single port is used by the BGP server.
+	ListenPort int32
+	// ListenAddresses are the addresses the BGP server is listening on (all if empty).
+	ListenAddresses []string
+	// TableID is the ID of the kernel table the received routes are written to.
+	TableID int
+	// RulePriority is the priority of the policy rules routing the traffic from the VIPs via TableID.
+	RulePriority int
+
+	server *server.BgpServer
+	// running is true once the BGP server has been started.
+	running bool
+	// desired configuration (last one received by Configure).
+	vips     []bird.VIP
+	gateways []bird.Gateway
+	// applied configuration.
+	peers           map[string]*api.Peer
+	paths           map[string]*api.Path
+	policies        *api.SetPoliciesRequest
+	sourceAddresses []string
+	// gateways by neighbor address, used to filter the received routes.
+	gatewaysByAddress map[string]bird.Gateway
+	statistics        map[string]*bird.ImportStatistics
+	// changes is notified when the routes received from the gateways have changed.
+	changes chan struct{}
+	mu      sync.Mutex
+}
+
+// New is the GoBGP constructor.
+func New() *GoBGP {
+	return &GoBGP{
+		ASN:               defaultLocalASN,
+		ListenPort:        defaultListenPort,
+		TableID:           bird.DefaultRoutingTables().TableID,
+		RulePriority:      bird.DefaultRoutingTables().RulePriority,
+		peers:             map[string]*api.Peer{},
+		paths:             map[string]*api.Path{},
+		gatewaysByAddress: map[string]bird.Gateway{},
+		statistics:        map[string]*bird.ImportStatistics{},
+		changes:           make(chan struct{}, 1),
+	}
+}
+
+// Run starts the BGP server and applies the last configuration received. The BGP
+// sessions are closed and the routes are removed from the kernel table when the
+// context in parameter is cancelled.
+func (g *GoBGP) Run(ctx context.Context) error {
+	logger := log.FromContextOrGlobal(ctx).WithName("gobgp")
+
+	g.mu.Lock()
+
+	if g.running {
+		g.mu.Unlock()
+
+		return errGoBGPRunning
+	}
+
+	err := g.start(ctx, logger)
+	if err != nil {
+		g.mu.Unlock()
+
+		return err
+	}
+
+	defer g.stop(logger)
+
+	err = g.apply(ctx)
+	if err != nil {
+		logger.Error(err, "failed to apply the configuration")
+	}
+
+	g.mu.Unlock()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-g.changes:
+			err := g.syncRoutes(ctx)
+			if err != nil {
+				logger.Error(err, "failed to write the received routes into the kernel")
+			}
+		}
+	}
+}
+
+// Configure announces the VIPs to the gateways, and sets the policy routes of the VIPs.
+// The configuration is applied when the BGP server is started if it is not running.
+// The gateways with BFD turned on are not configured and ErrBFDNotSupported is returned.
+func (g *GoBGP) Configure(ctx context.Context, vips []bird.VIP, gateways []bird.Gateway) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var errFinal error
+
+	g.vips = vips
+	g.gateways, errFinal = supportedGateways(gateways)
+
+	if !g.running {
+		return errFinal
+	}
+
+	return errors.Join(errFinal, g.apply(ctx))
+}
+
+// GetImportStatistics returns the statistics of the routes imported from each gateway.
+func (g *GoBGP) GetImportStatistics(_ context.Context) (map[string]*bird.ImportStatistics, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	statistics := map[string]*bird.ImportStatistics{}
+
+	for name, stats := range g.statistics {
+		statistics[name] = &bird.ImportStatistics{
+			Imported: stats.Imported,
+			Rejected: stats.Rejected,
+		}
+	}
+
+	return statistics, nil
+}
+
+func (g *GoBGP) start(ctx context.Context, logger logr.Logger) error {
+	routerID := g.RouterID
+	if routerID == "" {
+		var err error
+
+		routerID, err = hostRouterID()
+		if err != nil {
+			return err
+		}
+	}
+
+	g.server = server.NewBgpServer(server.LoggerOption(newLogger(logger)))
+
+	go g.server.Serve()
+
+	err := g.server.StartBgp(ctx, &api.StartBgpRequest{
+		Global: &api.Global{
+			Asn:             g.ASN,
+			RouterId:        routerID,
+			ListenPort:      g.ListenPort,
+			ListenAddresses: g.ListenAddresses,
+		},
+	})
+	if err != nil {
+		g.server.Stop()
+
+		return fmt.Errorf("failed to start the BGP server: %w", err)
+	}
+
+	err = g.startPolicies(ctx)
+	if err != nil {
+		g.server.Stop()
+
+		return err
+	}
+
+	err = g.server.WatchEvent(ctx, &api.WatchEventRequest{
+		Table: &api.WatchEventRequest_Table{
+			Filters: []*api.WatchEventRequest_Table_Filter{
+				{
+					Type: api.WatchEventRequest_Table_Filter_BEST,
+				},
+			},
+		},
+	}, func(*api.WatchEventResponse) {
+		g.notifyChanges()
+	})
+	if err != nil {
+		g.server.Stop()
+
+		return fmt.Errorf("failed to watch the BGP table: %w", err)
+	}
+
+	g.running = true
+
+	return nil
+}
+
+func (g *GoBGP) stop(logger logr.Logger) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.running = false
+
+	// The peers are notified so they withdraw the VIPs.
+	err := g.server.StopBgp(context.Background(), &api.StopBgpRequest{})
+	if err != nil {
+		logger.Error(err, "failed to stop the BGP server")
+	}
+
+	g.server.Stop()
+
+	err = setKernelRoutes(g.TableID, nil)
+	if err != nil {
+		logger.Error(err, "failed to remove the received routes from the kernel")
+	}
+
+	g.peers = map[string]*api.Peer{}
+	g.paths = map[string]*api.Path{}
+	g.policies = nil
+}
+
+// apply applies the desired configuration to the BGP server. It must be called with the lock held.
+func (g *GoBGP) apply(ctx context.Context) error {
+	var errFinal error
+
+	peers := map[string]*api.Peer{}
+	gatewaysByAddress := map[string]bird.Gateway{}
+
+	for _, gateway := range bgpGateways(g.gateways) {
+		peer := newPeer(gateway, g.ASN)
+		peers[peer.GetConf().GetNeighborAddress()] = peer
+		gatewaysByAddress[peer.GetConf().GetNeighborAddress()] = gateway
+	}
+
+	err := g.setPeers(ctx, peers)
+	if err != nil {
+		errFinal = errors.Join(errFinal, err)
+	}
+
+	g.gatewaysByAddress = gatewaysByAddress
+
+	err = g.setPolicies(ctx, newExportPolicies(g.vips, bgpGateways(g.gateways), g.ASN))
+	if err != nil {
+		errFinal = errors.Join(errFinal, err)
+	}
+
+	err = g.setPaths(ctx, newPaths(g.vips))
+	if err != nil {
+		errFinal = errors.Join(errFinal, err)
+	}
+
+	sourceAddresses := bird.SourceAddressCIDRs(g.gateways)
+
+	g.sourceAddresses, err = bird.SetSourceAddresses(sourceAddresses, g.sourceAddresses)
+	if err != nil {
+		errFinal = errors.Join(errFinal, fmt.Errorf("failed to set the source addresses %v: %w", sourceAddresses, err))
+	}
+
+	policyRoutes := vipCIDRs(g.vips)
+	policyRoutes = append(policyRoutes, sourceAddresses...)
+
+	err = bird.SetPolicyRoutes(policyRoutes, g.TableID, g.RulePriority)
+	if err != nil {
+		errFinal = errors.Join(errFinal, fmt.Errorf("failed to set the policy routes %v: %w", policyRoutes, err))
+	}
+
+	// the import policies might have changed.
+	g.notifyChanges()
+
+	return errFinal
+}
+
+// setPeers adds, updates and deletes the peers of the BGP server. It must be called with the lock held.
+func (g *GoBGP) setPeers(ctx context.Context, peers map[string]*api.Peer) error {
+	var errFinal error
+
+	for address, current := range g.peers {
+		desired, exists := peers[address]
+		if exists && proto.Equal(current, desired) {
+			continue
+		}
+
+		err := g.server.DeletePeer(ctx, &api.DeletePeerRequest{Address: address})
+		if err != nil {
+			errFinal = errors.Join(errFinal, fmt.Errorf("failed to delete the peer %s: %w", address, err))
+
+			continue
+		}
+
+		delete(g.peers, address)
+	}
+
+	for address, desired := range peers {
+		if _, exists := g.peers[address]; exists {
+			continue
+		}
+
+		err := g.server.AddPeer(ctx, &api.AddPeerRequest{Peer: desired})
+		if err != nil {
+			errFinal = errors.Join(errFinal, fmt.Errorf("failed to add the peer %s: %w", address, err))
+
+			continue
+		}
+
+		g.peers[address] = desired
+	}
+
+	return errFinal
+}
+
+// setPaths announces the new and updated VIPs and withdraws the removed ones. It must be called with the lock held.
+func (g *GoBGP) setPaths(ctx context.Context, paths map[string]*api.Path) error {
+	var errFinal error
+
+	for cidr, current := range g.paths {
+		if _, exists := paths[cidr]; exists {
+			continue
+		}
+
+		err := g.server.DeletePath(ctx, &api.DeletePathRequest{
+			TableType: api.TableType_GLOBAL,
+			Family:    current.GetFamily(),
+			Path:      current,
+		})
+		if err != nil {
+			errFinal = errors.Join(errFinal, fmt.Errorf("failed to withdraw %s: %w", cidr, err))
+
+			continue
+		}
+
+		delete(g.paths, cidr)
+	}
+
+	for cidr, desired := range paths {
+		if current, exists := g.paths[cidr]; exists && proto.Equal(current, desired) {
+			continue
+		}
+
+		// the path replaces the one previously announced for the same prefix.
+		_, err := g.server.AddPath(ctx, &api.AddPathRequest{
+			TableType: api.TableType_GLOBAL,
+			Path:      desired,
+		})
+		if err != nil {
+			errFinal = errors.Join(errFinal, fmt.Errorf("failed to announce %s: %w", cidr, err))
+
+			continue
+		}
+
+		g.paths[cidr] = desired
+	}
+
+	return errFinal
+}
+
+func (g *GoBGP) notifyChanges() {
+	select {
+	case g.changes <- struct{}{}:
+	default:
+	}
+}
+
+func bgpGateways(gateways []bird.Gateway) []bird.Gateway {
+	bgpGateways := []bird.Gateway{}
+
+	for _, gateway := range gateways {
+		if gateway.GetProtocol() != v1alpha1.BGP || net.ParseIP(gateway.GetAddress()) == nil {
+			continue
+		}
+
+		bgpGateways = append(bgpGateways, gateway)
+	}
+
+	return bgpGateways
+}
+
+// supportedGateways returns the gateways without the BGP gateways with BFD turned on,
+// and an error for each of them.
+func supportedGateways(gateways []bird.Gateway) ([]bird.Gateway, error) {
+	var errFinal error
+
+	supported := []bird.Gateway{}
+
+	for _, gateway := range gateways {
+		if gateway.GetProtocol() == v1alpha1.BGP && gateway.GetBgpSpec() != nil &&
+			bfdEnabled(gateway.GetBgpSpec().GetBfdSpec()) {
+			errFinal = errors.Join(errFinal, fmt.Errorf("gateway %s: %w", gateway.GetName(), ErrBFDNotSupported))
+
+			continue
+		}
+
+		supported = append(supported, gateway)
+	}
+
+	return supported, errFinal
+}
+
+func bfdEnabled(bfd bird.BfdSpec) bool {
+	return bfd != nil && bfd.GetSwitch() != nil && *bfd.GetSwitch()
+}
+
+func vipCIDRs(vips []bird.VIP) []string {
+	cidrs := []string{}
+
+	for _, vip := range vips {
+		cidrs = append(cidrs, vip.GetCIDR())
+	}
+
+	return cidrs
+}
+
+// hostRouterID returns the first global unicast IPv4 address of the host.
+func hostRouterID() (string, error) {
+	addresses, err := net.InterfaceAddrs()
+	if err != nil {
+		return "", fmt.Errorf("failed to list the addresses of the host: %w", err)
+	}
+
+	for _, address := range addresses {
+		ipNet, ok := address.(*net.IPNet)
+		if !ok || ipNet.IP.To4() == nil || !ipNet.IP.IsGlobalUnicast() {
+			continue
+		}
+
+		return ipNet.IP.String(), nil
+	}
+
+	return "", errNoRouterID
+}
@@ -0,0 +1,579 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gobgp_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/api/v1alpha1"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/bird"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/gobgp"
+	api "github.com/osrg/gobgp/v3/api"
+	"github.com/osrg/gobgp/v3/pkg/server"
+	"github.com/vishvananda/netlink"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+const (
+	testTableID  = 4196
+	localAddress = "127.0.0.1"
+	peerAddress  = "127.0.0.2"
+	peerASN      = 4248829953
+	localASN     = 8103
+)
+
+// TestGoBGP runs the GoBGP routing suite against a second in-process GoBGP
+// server playing the role of the gateway router over the loopback interface.
+func TestGoBGP(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	localPort := freePort(t, localAddress)
+	peerPort := freePort(t, peerAddress)
+
+	peer := startPeer(ctx, t, peerPort, localPort)
+
+	routingSuite := gobgp.New()
+	routingSuite.RouterID = "1.1.1.1"
+	routingSuite.ListenPort = int32(localPort)
+	routingSuite.ListenAddresses = []string{localAddress}
+	routingSuite.TableID = testTableID
+
+	errCh := make(chan error, 1)
+
+	go func() {
+		errCh <- routingSuite.Run(ctx)
+	}()
+
+	gateway := &gateway{
+		name:     "gateway-v4-a-1",
+		address:  peerAddress,
+		intf:     "lo",
+		protocol: v1alpha1.BGP,
+		bgp: &bgpSpec{
+			localASN:      newUint32(localASN),
+			remoteASN:     newUint32(peerASN),
+			remotePort:    newUint16(uint16(peerPort)),
+			holdTime:      "9s",
+			sourceAddress: localAddress,
+		},
+		imports: &importPolicy{
+			prefixes: []string{"10.100.0.0/16"},
+		},
+	}
+
+	vips := []bird.VIP{
+		&vip{
+			cidr: "20.0.0.1/32",
+			attributes: &bgpAttributes{
+				communities:   []string{"65000:100"},
+				med:           newUint32(10),
+				asPathPrepend: newUint32(2),
+			},
+		},
+		&vip{
+			cidr:       "20.0.0.2/32",
+			exportedTo: []string{"gateway-v4-b-1"},
+		},
+	}
+
+	err := routingSuite.Configure(ctx, vips, []bird.Gateway{gateway})
+	if err != nil {
+		t.Fatalf("GoBGP.Configure() error = %v", err)
+	}
+
+	t.Cleanup(func() {
+		_ = bird.SetPolicyRoutes([]string{}, routingSuite.TableID, routingSuite.RulePriority)
+	})
+
+	// GoBGP servers share some global state, the peer must not compute any route
+	// before the routing suite has been started.
+	eventually(t, "the routing suite to be started", func() bool {
+		statistics, err := routingSuite.GetImportStatistics(ctx)
+		if err != nil {
+			t.Fatalf("GoBGP.GetImportStatistics() error = %v", err)
+		}
+
+		return len(statistics) > 0
+	})
+
+	announce(ctx, t, peer, "10.100.0.0/24")
+	announce(ctx, t, peer, "10.200.0.0/24")
+
+	// The VIP is received by the peer with its attributes and the AS path prepended.
+	eventually(t, "the VIP to be received by the peer", func() bool {
+		path := receivedPath(ctx, t, peer, "20.0.0.1/32")
+		if path == nil {
+			return false
+		}
+
+		return asPathLength(t, path) == 3 && hasCommunity(t, path, 65000<<16|100)
+	})
+
+	// The VIP exported to another gateway is not received by the peer.
+	if receivedPath(ctx, t, peer, "20.0.0.2/32") != nil {
+		t.Errorf("GoBGP.Configure() the VIP not exported to the gateway has been received by the peer")
+	}
+
+	// Only the prefix accepted by the import policy is imported.
+	eventually(t, "the import statistics", func() bool {
+		statistics, err := routingSuite.GetImportStatistics(ctx)
+		if err != nil {
+			t.Fatalf("GoBGP.GetImportStatistics() error = %v", err)
+		}
+
+		stats := statistics[gateway.GetName()]
+
+		return stats != nil && stats.Imported == 1 && stats.Rejected == 1
+	})
+
+	eventually(t, "the imported route in the kernel table", func() bool {
+		return kernelRoute(t, "10.100.0.0/24") && !kernelRoute(t, "10.200.0.0/24")
+	})
+
+	// The VIP is withdrawn when removed.
+	err = routingSuite.Configure(ctx, []bird.VIP{}, []bird.Gateway{gateway})
+	if err != nil {
+		t.Fatalf("GoBGP.Configure() error = %v", err)
+	}
+
+	eventually(t, "the VIP to be withdrawn", func() bool {
+		return receivedPath(ctx, t, peer, "20.0.0.1/32") == nil
+	})
+
+	cancel()
+
+	if err := <-errCh; err != nil {
+		t.Fatalf("GoBGP.Run() error = %v", err)
+	}
+
+	if kernelRoute(t, "10.100.0.0/24") {
+		t.Errorf("GoBGP.Run() the received routes have not been removed from the kernel table")
+	}
+}
+
+func TestGoBGPConfigureBFD(t *testing.T) {
+	tests := []struct {
+		name    string
+		bfd     *bfdSpec
+		wantErr bool
+	}{
+		{
+			name: "no BFD",
+		},
+		{
+			name: "BFD turned off",
+			bfd:  &bfdSpec{sw: newBool(false)},
+		},
+		{
+			name:    "BFD turned on",
+			bfd:     &bfdSpec{sw: newBool(true)},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gateway := &gateway{
+				name:     "gateway-v4-a-1",
+				address:  peerAddress,
+				protocol: v1alpha1.BGP,
+				bgp:      &bgpSpec{bfd: tt.bfd},
+			}
+
+			err := gobgp.New().Configure(context.Background(), []bird.VIP{}, []bird.Gateway{gateway})
+			if errors.Is(err, gobgp.ErrBFDNotSupported) != tt.wantErr {
+				t.Errorf("GoBGP.Configure() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func startPeer(ctx context.Context, t *testing.T, peerPort int, localPort int) *server.BgpServer {
+	t.Helper()
+
+	peer := server.NewBgpServer()
+
+	go peer.Serve()
+
+	t.Cleanup(peer.Stop)
+
+	err := peer.StartBgp(ctx, &api.StartBgpRequest{
+		Global: &api.Global{
+			Asn:             peerASN,
+			RouterId:        "2.2.2.2",
+			ListenPort:      int32(peerPort),
+			ListenAddresses: []string{peerAddress},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to start the peer: %v", err)
+	}
+
+	err = peer.AddPeer(ctx, &api.AddPeerRequest{
+		Peer: &api.Peer{
+			Conf: &api.PeerConf{
+				NeighborAddress: localAddress,
+				PeerAsn:         localASN,
+			},
+			Transport: &api.Transport{
+				LocalAddress: peerAddress,
+				RemotePort:   uint32(localPort),
+				PassiveMode:  true,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to add the peer: %v", err)
+	}
+
+	return peer
+}
+
+func announce(ctx context.Context, t *testing.T, peer *server.BgpServer, cidr string) {
+	t.Helper()
+
+	_, ipNet, _ := net.ParseCIDR(cidr)
+	prefixLength, _ := ipNet.Mask.Size()
+
+	nlri, _ := anypb.New(&api.IPAddressPrefix{Prefix: ipNet.IP.String(), PrefixLen: uint32(prefixLength)})
+	origin, _ := anypb.New(&api.OriginAttribute{Origin: 0})
+	nextHop, _ := anypb.New(&api.NextHopAttribute{NextHop: peerAddress})
+
+	_, err := peer.AddPath(ctx, &api.AddPathRequest{
+		TableType: api.TableType_GLOBAL,
+		Path: &api.Path{
+			Family: &api.Family{Afi: api.Family_AFI_IP, Safi: api.Family_SAFI_UNICAST},
+			Nlri:   nlri,
+			Pattrs: []*anypb.Any{origin, nextHop},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to announce %s: %v", cidr, err)
+	}
+}
+
+func receivedPath(ctx context.Context, t *testing.T, peer *server.BgpServer, cidr string) *api.Path {
+	t.Helper()
+
+	var received *api.Path
+
+	err := peer.ListPath(ctx, &api.ListPathRequest{
+		TableType: api.TableType_GLOBAL,
+		Family:    &api.Family{Afi: api.Family_AFI_IP, Safi: api.Family_SAFI_UNICAST},
+	}, func(destination *api.Destination) {
+		if destination.GetPrefix() != cidr {
+			return
+		}
+
+		for _, path := range destination.GetPaths() {
+			if path.GetNeighborIp() == localAddress {
+				received = path
+			}
+		}
+	})
+	if err != nil {
+		t.Fatalf("failed to list the paths of the peer: %v", err)
+	}
+
+	return received
+}
+
+func asPathLength(t *testing.T, path *api.Path) int {
+	t.Helper()
+
+	length := 0
+
+	for _, pattr := range path.GetPattrs() {
+		asPath := &api.AsPathAttribute{}
+		if pattr.UnmarshalTo(asPath) != nil {
+			continue
+		}
+
+		for _, segment := range asPath.GetSegments() {
+			length += len(segment.GetNumbers())
+		}
+	}
+
+	return length
+}
+
+func hasCommunity(t *testing.T, path *api.Path, community uint32) bool {
+	t.Helper()
+
+	for _, pattr := range path.GetPattrs() {
+		communities := &api.CommunitiesAttribute{}
+		if pattr.UnmarshalTo(communities) != nil {
+			continue
+		}
+
+		for _, value := range communities.GetCommunities() {
+			if value == community {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func kernelRoute(t *testing.T, cidr string) bool {
+	t.Helper()
+
+	routes, err := netlink.RouteListFiltered(netlink.FAMILY_V4, &netlink.Route{
+		Table: testTableID,
+	}, netlink.RT_FILTER_TABLE)
+	if err != nil {
+		t.Fatalf("failed to list the routes: %v", err)
+	}
+
+	for _, route := range routes {
+		if route.Dst != nil && route.Dst.String() == cidr {
+			return true
+		}
+	}
+
+	return false
+}
+
+func eventually(t *testing.T, description string, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(30 * time.Second)
+
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s", description)
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func freePort(t *testing.T, address string) int {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(address, "0"))
+	if err != nil {
+		t.Fatalf("failed to find a free port: %v", err)
+	}
+
+	defer listener.Close()
+
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+type vip struct {
+	cidr       string
+	attributes *bgpAttributes
+	exportedTo []string
+}
+
+func (v *vip) GetCIDR() string {
+	return v.cidr
+}
+
+func (v *vip) GetBgpAttributes() bird.BgpAttributes {
+	if v.attributes == nil {
+		return nil
+	}
+
+	return v.attributes
+}
+
+func (v *vip) GetExportedTo() []string {
+	return v.exportedTo
+}
+
+type bgpAttributes struct {
+	communities   []string
+	med           *uint32
+	asPathPrepend *uint32
+}
+
+func (ba *bgpAttributes) GetCommunities() []string {
+	return ba.communities
+}
+
+func (ba *bgpAttributes) GetLargeCommunities() []string {
+	return nil
+}
+
+func (ba *bgpAttributes) GetMED() *uint32 {
+	return ba.med
+}
+
+func (ba *bgpAttributes) GetLocalPreference() *uint32 {
+	return nil
+}
+
+func (ba *bgpAttributes) GetASPathPrepend() *uint32 {
+	return ba.asPathPrepend
+}
+
+type gateway struct {
+	name     string
+	address  string
+	intf     string
+	protocol v1alpha1.RoutingProtocol
+	bgp      *bgpSpec
+	imports  *importPolicy
+}
+
+func (gw *gateway) GetName() string {
+	return gw.name
+}
+
+func (gw *gateway) GetAddress() string {
+	return gw.address
+}
+
+func (gw *gateway) GetInterface() string {
+	return gw.intf
+}
+
+func (gw *gateway) GetProtocol() v1alpha1.RoutingProtocol {
+	return gw.protocol
+}
+
+func (gw *gateway) GetBgpSpec() bird.BgpSpec {
+	return gw.bgp
+}
+
+func (gw *gateway) GetStatic() bird.StaticSpec {
+	return nil
+}
+
+func (gw *gateway) GetOspfSpec() bird.OspfSpec {
+	return nil
+}
+
+func (gw *gateway) GetImportPolicy() bird.ImportPolicy {
+	if gw.imports == nil {
+		return nil
+	}
+
+	return gw.imports
+}
+
+type importPolicy struct {
+	prefixes []string
+}
+
+func (ip *importPolicy) GetPrefixes() []string {
+	return ip.prefixes
+}
+
+func (ip *importPolicy) GetDefaultRoute() *bool {
+	return nil
+}
+
+func (ip *importPolicy) GetMaxPrefixes() *uint32 {
+	return nil
+}
+
+type bgpSpec struct {
+	remoteASN     *uint32
+	localASN      *uint32
+	bfd           *bfdSpec
+	holdTime      string
+	remotePort    *uint16
+	sourceAddress string
+}
+
+func (bgps *bgpSpec) GetRemoteASN() *uint32 {
+	return bgps.remoteASN
+}
+
+func (bgps *bgpSpec) GetLocalASN() *uint32 {
+	return bgps.localASN
+}
+
+func (bgps *bgpSpec) GetBfdSpec() bird.BfdSpec {
+	if bgps.bfd == nil {
+		return nil
+	}
+
+	return bgps.bfd
+}
+
+func (bgps *bgpSpec) GetHoldTime() string {
+	return bgps.holdTime
+}
+
+func (bgps *bgpSpec) GetRemotePort() *uint16 {
+	return bgps.remotePort
+}
+
+func (bgps *bgpSpec) GetLocalPort() *uint16 {
+	return nil
+}
+
+func (bgps *bgpSpec) GetPassword() string {
+	return ""
+}
+
+func (bgps *bgpSpec) GetMultihop() *uint8 {
+	return nil
+}
+
+func (bgps *bgpSpec) GetSourceAddress() string {
+	return bgps.sourceAddress
+}
+
+func (bgps *bgpSpec) GetNextHop() string {
+	return ""
+}
+
+func (bgps *bgpSpec) GetGracefulRestart() bird.GracefulRestartSpec {
+	return nil
+}
+
+type bfdSpec struct {
+	sw *bool
+}
+
+func (bfds *bfdSpec) GetSwitch() *bool {
+	return bfds.sw
+}
+
+func (bfds *bfdSpec) GetMinTx() string {
+	return ""
+}
+
+func (bfds *bfdSpec) GetMinRx() string {
+	return ""
+}
+
+func (bfds *bfdSpec) GetMultiplier() *uint16 {
+	return nil
+}
+
+func newBool(val bool) *bool {
+	return &val
+}
+
+func newUint16(val uint16) *uint16 {
+	return &val
+}
+
+func newUint32(val uint32) *uint32 {
+	return &val
+}
@@ -0,0 +1,341 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gobgp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/bird"
+	api "github.com/osrg/gobgp/v3/api"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// routeProtocol is the protocol of the routes written into the kernel table.
+const routeProtocol = netlink.RouteProtocol(unix.RTPROT_BGP)
+
+// receivedRoute is a route received from a gateway.
+type receivedRoute struct {
+	prefix       *net.IPNet
+	neighbor     string
+	nextHop      net.IP
+	asPathLength int
+}
+
+// kernelRoute is a route written into the kernel table. The traffic is
+// load-balanced over the next hops (ECMP).
+type kernelRoute struct {
+	prefix   *net.IPNet
+	nextHops []*nextHop
+}
+
+type nextHop struct {
+	address net.IP
+	// interface of the directly connected gateways, empty for multihop gateways.
+	intf string
+}
+
+// syncRoutes writes the routes received from the gateways and accepted by their import
+// policy into the kernel table, and updates the import statistics.
+func (g *GoBGP) syncRoutes(ctx context.Context) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if !g.running {
+		return nil
+	}
+
+	received := []*receivedRoute{}
+
+	for _, routeFamily := range []*api.Family{family("0.0.0.0"), family("::")} {
+		err := g.server.ListPath(ctx, &api.ListPathRequest{
+			TableType: api.TableType_GLOBAL,
+			Family:    routeFamily,
+		}, func(destination *api.Destination) {
+			for _, path := range destination.GetPaths() {
+				route, ok := newReceivedRoute(destination.GetPrefix(), path)
+				if ok {
+					received = append(received, route)
+				}
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list the received routes: %w", err)
+		}
+	}
+
+	routes, statistics := importRoutes(received, g.gatewaysByAddress)
+
+	g.statistics = statistics
+
+	return setKernelRoutes(g.TableID, routes)
+}
+
+// newReceivedRoute returns the route of a path received from a gateway. false is
+// returned for the local paths (VIPs), the withdrawn paths and the invalid paths.
+func newReceivedRoute(prefix string, path *api.Path) (*receivedRoute, bool) {
+	if path.GetNeighborIp() == "" || path.GetIsWithdraw() || path.GetIsNexthopInvalid() {
+		return nil, false
+	}
+
+	_, ipNet, err := net.ParseCIDR(prefix)
+	if err != nil {
+		return nil, false
+	}
+
+	route := &receivedRoute{
+		prefix:   ipNet,
+		neighbor: path.GetNeighborIp(),
+	}
+
+	for _, pattr := range path.GetPattrs() {
+		nextHopAttribute := &api.NextHopAttribute{}
+		mpReachAttribute := &api.MpReachNLRIAttribute{}
+		asPathAttribute := &api.AsPathAttribute{}
+
+		switch {
+		case pattr.UnmarshalTo(nextHopAttribute) == nil:
+			route.nextHop = net.ParseIP(nextHopAttribute.GetNextHop())
+		case pattr.UnmarshalTo(mpReachAttribute) == nil && len(mpReachAttribute.GetNextHops()) > 0:
+			route.nextHop = net.ParseIP(mpReachAttribute.GetNextHops()[0])
+		case pattr.UnmarshalTo(asPathAttribute) == nil:
+			for _, segment := range asPathAttribute.GetSegments() {
+				route.asPathLength += len(segment.GetNumbers())
+			}
+		}
+	}
+
+	if route.nextHop == nil {
+		return nil, false
+	}
+
+	return route, true
+}
+
+// importRoutes returns the kernel routes of the received routes accepted by the import
+// policy of their gateway, and the import statistics per gateway name. The routes with
+// the shortest AS path are merged (ECMP).
+func importRoutes(
+	received []*receivedRoute,
+	gateways map[string]bird.Gateway,
+) ([]*kernelRoute, map[string]*bird.ImportStatistics) {
+	sort.SliceStable(received, func(i, j int) bool {
+		if received[i].prefix.String() != received[j].prefix.String() {
+			return received[i].prefix.String() < received[j].prefix.String()
+		}
+
+		return received[i].neighbor < received[j].neighbor
+	})
+
+	statistics := map[string]*bird.ImportStatistics{}
+
+	for _, gateway := range gateways {
+		statistics[gateway.GetName()] = &bird.ImportStatistics{}
+	}
+
+	routes := map[string]*kernelRoute{}
+	asPathLengths := map[string]int{}
+	prefixes := []string{}
+
+	for _, route := range received {
+		gateway, exists := gateways[route.neighbor]
+		if !exists {
+			continue
+		}
+
+		stats := statistics[gateway.GetName()]
+
+		if !importAccepted(gateway.GetImportPolicy(), route.prefix, stats.Imported) {
+			stats.Rejected++
+
+			continue
+		}
+
+		stats.Imported++
+
+		key := route.prefix.String()
+
+		imported, exists := routes[key]
+		if !exists || route.asPathLength < asPathLengths[key] {
+			if !exists {
+				prefixes = append(prefixes, key)
+			}
+
+			imported = &kernelRoute{prefix: route.prefix}
+			routes[key] = imported
+			asPathLengths[key] = route.asPathLength
+		}
+
+		if route.asPathLength > asPathLengths[key] {
+			continue
+		}
+
+		imported.nextHops = append(imported.nextHops, &nextHop{
+			address: route.nextHop,
+			intf:    directInterface(gateway),
+		})
+	}
+
+	kernelRoutes := []*kernelRoute{}
+
+	for _, prefix := range prefixes {
+		kernelRoutes = append(kernelRoutes, routes[prefix])
+	}
+
+	return kernelRoutes, statistics
+}
+
+// importAccepted returns true if the prefix is accepted by the import policy: the default
+// routes (if enabled) and the prefixes within the ones of the policy, up to the maximum
+// number of prefixes. All the prefixes are accepted if there is no import policy.
+func importAccepted(importPolicy bird.ImportPolicy, prefix *net.IPNet, imported int) bool {
+	if importPolicy == nil {
+		return true
+	}
+
+	if importPolicy.GetMaxPrefixes() != nil && imported >= int(*importPolicy.GetMaxPrefixes()) {
+		return false
+	}
+
+	prefixLength, bits := prefix.Mask.Size()
+
+	if prefixLength == 0 {
+		return importPolicy.GetDefaultRoute() == nil || *importPolicy.GetDefaultRoute()
+	}
+
+	for _, cidr := range importPolicy.GetPrefixes() {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			continue
+		}
+
+		policyLength, policyBits := ipNet.Mask.Size()
+
+		if policyBits == bits && policyLength <= prefixLength && ipNet.Contains(prefix.IP) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// directInterface returns the interface of a directly connected gateway.
+func directInterface(gateway bird.Gateway) string {
+	if gateway.GetBgpSpec().GetMultihop() != nil {
+		return ""
+	}
+
+	return gateway.GetInterface()
+}
+
+// setKernelRoutes replaces the routes of the kernel table with the ones in parameter.
+func setKernelRoutes(tableID int, routes []*kernelRoute) error {
+	existing, err := netlink.RouteListFiltered(netlink.FAMILY_ALL, &netlink.Route{
+		Table:    tableID,
+		Protocol: routeProtocol,
+	}, netlink.RT_FILTER_TABLE|netlink.RT_FILTER_PROTOCOL)
+	if err != nil {
+		return fmt.Errorf("failed to list the routes of the table %d: %w", tableID, err)
+	}
+
+	var errFinal error
+
+	desired := map[string]struct{}{}
+
+	for _, route := range routes {
+		netlinkRoute, err := newNetlinkRoute(tableID, route)
+		if err != nil {
+			errFinal = errors.Join(errFinal, err)
+
+			continue
+		}
+
+		desired[route.prefix.String()] = struct{}{}
+
+		err = netlink.RouteReplace(netlinkRoute)
+		if err != nil {
+			errFinal = errors.Join(errFinal, fmt.Errorf("failed to RouteReplace %s: %w", route.prefix, err))
+		}
+	}
+
+	for _, route := range existing {
+		currentRoute := route
+
+		if _, exists := desired[routeDestination(&currentRoute)]; exists {
+			continue
+		}
+
+		err := netlink.RouteDel(&currentRoute)
+		if err != nil {
+			errFinal = errors.Join(errFinal, fmt.Errorf("failed to RouteDel %s: %w", routeDestination(&currentRoute), err))
+		}
+	}
+
+	return errFinal
+}
+
+func newNetlinkRoute(tableID int, route *kernelRoute) (*netlink.Route, error) {
+	netlinkRoute := &netlink.Route{
+		Table:    tableID,
+		Protocol: routeProtocol,
+		Dst:      route.prefix,
+	}
+
+	for _, nextHop := range route.nextHops {
+		linkIndex := 0
+
+		if nextHop.intf != "" {
+			link, err := netlink.LinkByName(nextHop.intf)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get the interface %s: %w", nextHop.intf, err)
+			}
+
+			linkIndex = link.Attrs().Index
+		}
+
+		netlinkRoute.MultiPath = append(netlinkRoute.MultiPath, &netlink.NexthopInfo{
+			Gw:        nextHop.address,
+			LinkIndex: linkIndex,
+		})
+	}
+
+	if len(netlinkRoute.MultiPath) == 1 {
+		netlinkRoute.Gw = netlinkRoute.MultiPath[0].Gw
+		netlinkRoute.LinkIndex = netlinkRoute.MultiPath[0].LinkIndex
+		netlinkRoute.MultiPath = nil
+	}
+
+	return netlinkRoute, nil
+}
+
+// routeDestination returns the destination of a kernel route, the default routes
+// have no destination in the netlink routes.
+func routeDestination(route *netlink.Route) string {
+	if route.Dst != nil {
+		return route.Dst.String()
+	}
+
+	if route.Family == netlink.FAMILY_V6 {
+		return "::/0"
+	}
+
+	return "0.0.0.0/0"
+}
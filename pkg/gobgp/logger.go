@@ -0,0 +1,77 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gobgp
+
+import (
+	"github.com/go-logr/logr"
+	gobgplog "github.com/osrg/gobgp/v3/pkg/log"
+)
+
+// logger forwards the logs of the BGP server to a logr logger.
+type logger struct {
+	logger logr.Logger
+	level  gobgplog.LogLevel
+}
+
+func newLogger(logrLogger logr.Logger) *logger {
+	return &logger{
+		logger: logrLogger,
+		level:  gobgplog.InfoLevel,
+	}
+}
+
+func (l *logger) Panic(msg string, fields gobgplog.Fields) {
+	l.logger.Error(nil, msg, keysAndValues(fields)...)
+}
+
+func (l *logger) Fatal(msg string, fields gobgplog.Fields) {
+	l.logger.Error(nil, msg, keysAndValues(fields)...)
+}
+
+func (l *logger) Error(msg string, fields gobgplog.Fields) {
+	l.logger.Error(nil, msg, keysAndValues(fields)...)
+}
+
+func (l *logger) Warn(msg string, fields gobgplog.Fields) {
+	l.logger.Info(msg, keysAndValues(fields)...)
+}
+
+func (l *logger) Info(msg string, fields gobgplog.Fields) {
+	l.logger.V(1).Info(msg, keysAndValues(fields)...)
+}
+
+func (l *logger) Debug(msg string, fields gobgplog.Fields) {
+	l.logger.V(2).Info(msg, keysAndValues(fields)...) //nolint:gomnd
+}
+
+func (l *logger) SetLevel(level gobgplog.LogLevel) {
+	l.level = level
+}
+
+func (l *logger) GetLevel() gobgplog.LogLevel {
+	return l.level
+}
+
+func keysAndValues(fields gobgplog.Fields) []any {
+	keysAndValues := make([]any, 0, len(fields)*2) //nolint:gomnd
+
+	for key, value := range fields {
+		keysAndValues = append(keysAndValues, key, value)
+	}
+
+	return keysAndValues
+}
@@ -0,0 +1,187 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gobgp
+
+import (
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/bird"
+	api "github.com/osrg/gobgp/v3/api"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+const (
+	communityParts      = 2
+	largeCommunityParts = 3
+	communityPartSize   = 16
+	largeCommunityPart  = 32
+	originIGP           = 0
+)
+
+// newPaths returns the paths announcing the VIPs by CIDR. The next hop is left
+// unspecified, so the local address of each BGP session is announced (next hop self).
+func newPaths(vips []bird.VIP) map[string]*api.Path {
+	paths := map[string]*api.Path{}
+
+	for _, vip := range vips {
+		_, ipNet, err := net.ParseCIDR(vip.GetCIDR())
+		if err != nil {
+			continue
+		}
+
+		path, err := newPath(ipNet, vip.GetBgpAttributes())
+		if err != nil {
+			continue
+		}
+
+		paths[ipNet.String()] = path
+	}
+
+	return paths
+}
+
+func newPath(ipNet *net.IPNet, attributes bird.BgpAttributes) (*api.Path, error) {
+	prefixLength, _ := ipNet.Mask.Size()
+
+	nlri, err := anypb.New(&api.IPAddressPrefix{
+		Prefix:    ipNet.IP.String(),
+		PrefixLen: uint32(prefixLength),
+	})
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	pathFamily := family(ipNet.IP.String())
+
+	origin, err := anypb.New(&api.OriginAttribute{Origin: originIGP})
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	var nextHop *anypb.Any
+
+	if pathFamily.GetAfi() == api.Family_AFI_IP {
+		nextHop, err = anypb.New(&api.NextHopAttribute{NextHop: "0.0.0.0"})
+	} else {
+		nextHop, err = anypb.New(&api.MpReachNLRIAttribute{
+			Family:   pathFamily,
+			NextHops: []string{"::"},
+			Nlris:    []*anypb.Any{nlri},
+		})
+	}
+
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	pattrs, err := attributesPattrs(attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.Path{
+		Family: pathFamily,
+		Nlri:   nlri,
+		Pattrs: append([]*anypb.Any{origin, nextHop}, pattrs...),
+	}, nil
+}
+
+// attributesPattrs returns the path attributes (communities, large communities, MED
+// and local preference) of a VIP. The AS path prepend is set by the export policy.
+func attributesPattrs(attributes bird.BgpAttributes) ([]*anypb.Any, error) {
+	pattrs := []*anypb.Any{}
+
+	if attributes == nil {
+		return pattrs, nil
+	}
+
+	communities := []uint32{}
+
+	for _, community := range attributes.GetCommunities() {
+		values, ok := parseCommunity(community, communityParts, communityPartSize)
+		if ok {
+			communities = append(communities, values[0]<<communityPartSize|values[1])
+		}
+	}
+
+	largeCommunities := []*api.LargeCommunity{}
+
+	for _, community := range attributes.GetLargeCommunities() {
+		values, ok := parseCommunity(community, largeCommunityParts, largeCommunityPart)
+		if ok {
+			largeCommunities = append(largeCommunities, &api.LargeCommunity{
+				GlobalAdmin: values[0],
+				LocalData1:  values[1],
+				LocalData2:  values[2],
+			})
+		}
+	}
+
+	messages := []proto.Message{}
+
+	if len(communities) > 0 {
+		messages = append(messages, &api.CommunitiesAttribute{Communities: communities})
+	}
+
+	if len(largeCommunities) > 0 {
+		messages = append(messages, &api.LargeCommunitiesAttribute{Communities: largeCommunities})
+	}
+
+	if attributes.GetMED() != nil {
+		messages = append(messages, &api.MultiExitDiscAttribute{Med: *attributes.GetMED()})
+	}
+
+	if attributes.GetLocalPreference() != nil {
+		messages = append(messages, &api.LocalPrefAttribute{LocalPref: *attributes.GetLocalPreference()})
+	}
+
+	for _, message := range messages {
+		pattr, err := anypb.New(message)
+		if err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+
+		pattrs = append(pattrs, pattr)
+	}
+
+	return pattrs, nil
+}
+
+// parseCommunity returns the values of a community (e.g. 65000:100).
+// false is returned if the community is invalid.
+func parseCommunity(community string, parts int, bitSize int) ([]uint32, bool) {
+	values := strings.Split(community, ":")
+	if len(values) != parts {
+		return nil, false
+	}
+
+	parsed := []uint32{}
+
+	for _, value := range values {
+		number, err := strconv.ParseUint(value, 10, bitSize)
+		if err != nil {
+			return nil, false
+		}
+
+		parsed = append(parsed, uint32(number))
+	}
+
+	return parsed, true
+}
@@ -0,0 +1,174 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gobgp
+
+import (
+	"net"
+	"time"
+
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/bird"
+	api "github.com/osrg/gobgp/v3/api"
+)
+
+const (
+	defaultHoldTime                   = 3
+	minHoldTime                       = 3
+	keepaliveRatio                    = 3
+	defaultGracefulRestartTime        = 120
+	maxGracefulRestartTime            = 4095
+	maxLongLivedStaleTime             = 16777215
+	defaultLocalASN            uint32 = 8103
+	defaultListenPort          int32  = 10179
+	defaultRemoteASN           uint32 = 4248829953
+	defaultRemotePort          uint16 = 10179
+)
+
+// newPeer returns the BGP peer of a gateway.
+func newPeer(gateway bird.Gateway, defaultASN uint32) *api.Peer {
+	bgpSpec := gateway.GetBgpSpec()
+
+	localASN := defaultASN
+	if bgpSpec.GetLocalASN() != nil {
+		localASN = *bgpSpec.GetLocalASN()
+	}
+
+	remoteASN := defaultRemoteASN
+	if bgpSpec.GetRemoteASN() != nil {
+		remoteASN = *bgpSpec.GetRemoteASN()
+	}
+
+	remotePort := defaultRemotePort
+	if bgpSpec.GetRemotePort() != nil {
+		remotePort = *bgpSpec.GetRemotePort()
+	}
+
+	holdTime := seconds(bgpSpec.GetHoldTime(), defaultHoldTime, minHoldTime, 0)
+
+	peer := &api.Peer{
+		Conf: &api.PeerConf{
+			NeighborAddress: gateway.GetAddress(),
+			PeerAsn:         remoteASN,
+			LocalAsn:        localASN,
+			AuthPassword:    bgpSpec.GetPassword(),
+			Description:     gateway.GetName(),
+		},
+		Timers: &api.Timers{
+			Config: &api.TimersConfig{
+				HoldTime:          holdTime,
+				KeepaliveInterval: holdTime / keepaliveRatio,
+			},
+		},
+		Transport: &api.Transport{
+			RemotePort: uint32(remotePort),
+		},
+		AfiSafis: []*api.AfiSafi{
+			{
+				Config: &api.AfiSafiConfig{
+					Family:  family(gateway.GetAddress()),
+					Enabled: true,
+				},
+			},
+		},
+	}
+
+	if net.ParseIP(bgpSpec.GetSourceAddress()) != nil {
+		peer.Transport.LocalAddress = bgpSpec.GetSourceAddress()
+	}
+
+	if bgpSpec.GetMultihop() != nil {
+		peer.EbgpMultihop = &api.EbgpMultihop{
+			Enabled:     true,
+			MultihopTtl: uint32(*bgpSpec.GetMultihop()),
+		}
+	} else if gateway.GetInterface() != "" {
+		peer.Transport.BindInterface = gateway.GetInterface()
+	}
+
+	setGracefulRestart(peer, bgpSpec.GetGracefulRestart())
+
+	return peer
+}
+
+// setGracefulRestart sets the graceful restart and long-lived graceful restart parameters of the peer.
+func setGracefulRestart(peer *api.Peer, gracefulRestart bird.GracefulRestartSpec) {
+	if gracefulRestart == nil || gracefulRestart.GetSwitch() == nil || !*gracefulRestart.GetSwitch() {
+		return
+	}
+
+	peer.GracefulRestart = &api.GracefulRestart{
+		Enabled: true,
+		RestartTime: uint32(seconds(gracefulRestart.GetRestartTime(),
+			defaultGracefulRestartTime, 0, maxGracefulRestartTime)),
+	}
+
+	for _, afiSafi := range peer.GetAfiSafis() {
+		afiSafi.MpGracefulRestart = &api.MpGracefulRestart{
+			Config: &api.MpGracefulRestartConfig{
+				Enabled: true,
+			},
+		}
+	}
+
+	staleTime := seconds(gracefulRestart.GetLongLivedStaleTime(), 0, 0, maxLongLivedStaleTime)
+	if staleTime == 0 {
+		return
+	}
+
+	peer.GracefulRestart.LonglivedEnabled = true
+
+	for _, afiSafi := range peer.GetAfiSafis() {
+		afiSafi.LongLivedGracefulRestart = &api.LongLivedGracefulRestart{
+			Config: &api.LongLivedGracefulRestartConfig{
+				Enabled:     true,
+				RestartTime: uint32(staleTime),
+			},
+		}
+	}
+}
+
+// seconds parses a duration and rounds it by second. The default value is returned
+// if the value is empty or invalid, the value is bounded by min and max (if not 0).
+func seconds(duration string, defaultValue uint64, minValue uint64, maxValue uint64) uint64 {
+	if duration == "" {
+		return defaultValue
+	}
+
+	parsed, err := time.ParseDuration(duration)
+	if err != nil || parsed < 0 {
+		return defaultValue
+	}
+
+	value := uint64(parsed.Round(time.Second) / time.Second)
+
+	switch {
+	case value < minValue:
+		return minValue
+	case maxValue > 0 && value > maxValue:
+		return maxValue
+	}
+
+	return value
+}
+
+func family(ip string) *api.Family {
+	ipAddr := net.ParseIP(ip)
+	if ipAddr != nil && ipAddr.To4() == nil {
+		return &api.Family{Afi: api.Family_AFI_IP6, Safi: api.Family_SAFI_UNICAST}
+	}
+
+	return &api.Family{Afi: api.Family_AFI_IP, Safi: api.Family_SAFI_UNICAST}
+}
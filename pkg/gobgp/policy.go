@@ -0,0 +1,238 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gobgp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/bird"
+	api "github.com/osrg/gobgp/v3/api"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// Name of the export policy, same as the export filter of bird.
+	exportPolicyName = "announced_routes"
+	// Name of the global RIB in GoBGP.
+	globalRIB = "global"
+)
+
+// startPolicies sets the export policy of the BGP server: only the local paths (the VIPs)
+// are announced to the gateways, the routes received from a gateway are never announced
+// to the others.
+func (g *GoBGP) startPolicies(ctx context.Context) error {
+	err := g.setPolicies(ctx, newExportPolicies(nil, nil, g.ASN))
+	if err != nil {
+		return err
+	}
+
+	err = g.server.SetPolicyAssignment(ctx, &api.SetPolicyAssignmentRequest{
+		Assignment: &api.PolicyAssignment{
+			Name:      globalRIB,
+			Direction: api.PolicyDirection_EXPORT,
+			Policies: []*api.Policy{
+				{Name: exportPolicyName},
+			},
+			DefaultAction: api.RouteAction_REJECT,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to assign the export policy: %w", err)
+	}
+
+	return nil
+}
+
+// setPolicies replaces the defined sets and the export policy of the BGP server, and
+// re-evaluates the paths announced to the peers. It must be called with the lock held.
+func (g *GoBGP) setPolicies(ctx context.Context, policies *api.SetPoliciesRequest) error {
+	if g.policies != nil && proto.Equal(g.policies, policies) {
+		return nil
+	}
+
+	err := g.server.SetPolicies(ctx, policies)
+	if err != nil {
+		return fmt.Errorf("failed to set the export policy: %w", err)
+	}
+
+	if g.policies != nil {
+		for address := range g.peers {
+			err := g.server.ResetPeer(ctx, &api.ResetPeerRequest{
+				Address:   address,
+				Soft:      true,
+				Direction: api.ResetPeerRequest_OUT,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to re-evaluate the paths announced to %s: %w", address, err)
+			}
+		}
+	}
+
+	g.policies = policies
+
+	return nil
+}
+
+// newExportPolicies returns the export policy (and its defined sets) setting the next hop
+// override of the gateways, rejecting the VIPs not exported to them, prepending the AS path
+// of the VIPs and accepting the local paths.
+func newExportPolicies(vips []bird.VIP, gateways []bird.Gateway, defaultASN uint32) *api.SetPoliciesRequest {
+	definedSets := []*api.DefinedSet{}
+	statements := []*api.Statement{}
+
+	for _, gateway := range gateways {
+		neighborSet := newNeighborSet(gateway)
+		definedSets = append(definedSets, neighborSet)
+
+		nextHop := net.ParseIP(gateway.GetBgpSpec().GetNextHop())
+		if nextHop != nil && (nextHop.To4() != nil) == (net.ParseIP(gateway.GetAddress()).To4() != nil) {
+			statements = append(statements, &api.Statement{
+				Name: fmt.Sprintf("next-hop-%s", gateway.GetName()),
+				Conditions: &api.Conditions{
+					NeighborSet: &api.MatchSet{Name: neighborSet.GetName()},
+				},
+				Actions: &api.Actions{
+					Nexthop: &api.NexthopAction{Address: nextHop.String()},
+				},
+			})
+		}
+
+		statements = append(statements, rejectStatements(vips, gateway, neighborSet)...)
+
+		localASN := defaultASN
+		if gateway.GetBgpSpec().GetLocalASN() != nil {
+			localASN = *gateway.GetBgpSpec().GetLocalASN()
+		}
+
+		for _, vip := range vips {
+			if vip.GetBgpAttributes() == nil || vip.GetBgpAttributes().GetASPathPrepend() == nil ||
+				*vip.GetBgpAttributes().GetASPathPrepend() == 0 {
+				continue
+			}
+
+			_, ipNet, err := net.ParseCIDR(vip.GetCIDR())
+			if err != nil {
+				continue
+			}
+
+			statements = append(statements, &api.Statement{
+				Name: fmt.Sprintf("as-path-prepend-%s-%s", gateway.GetName(), ipNet.String()),
+				Conditions: &api.Conditions{
+					NeighborSet: &api.MatchSet{Name: neighborSet.GetName()},
+					PrefixSet:   &api.MatchSet{Name: prefixSetName(ipNet)},
+				},
+				Actions: &api.Actions{
+					AsPrepend: &api.AsPrependAction{
+						Asn:    localASN,
+						Repeat: *vip.GetBgpAttributes().GetASPathPrepend(),
+					},
+				},
+			})
+		}
+	}
+
+	for _, vip := range vips {
+		if vip.GetExportedTo() == nil && (vip.GetBgpAttributes() == nil ||
+			vip.GetBgpAttributes().GetASPathPrepend() == nil || *vip.GetBgpAttributes().GetASPathPrepend() == 0) {
+			continue
+		}
+
+		_, ipNet, err := net.ParseCIDR(vip.GetCIDR())
+		if err != nil {
+			continue
+		}
+
+		prefixLength, _ := ipNet.Mask.Size()
+
+		definedSets = append(definedSets, &api.DefinedSet{
+			DefinedType: api.DefinedType_PREFIX,
+			Name:        prefixSetName(ipNet),
+			Prefixes: []*api.Prefix{
+				{
+					IpPrefix:      ipNet.String(),
+					MaskLengthMin: uint32(prefixLength),
+					MaskLengthMax: uint32(prefixLength),
+				},
+			},
+		})
+	}
+
+	statements = append(statements, &api.Statement{
+		Name: "local-routes",
+		Conditions: &api.Conditions{
+			RouteType: api.Conditions_ROUTE_TYPE_LOCAL,
+		},
+		Actions: &api.Actions{
+			RouteAction: api.RouteAction_ACCEPT,
+		},
+	})
+
+	return &api.SetPoliciesRequest{
+		DefinedSets: definedSets,
+		Policies: []*api.Policy{
+			{
+				Name:       exportPolicyName,
+				Statements: statements,
+			},
+		},
+	}
+}
+
+// rejectStatements returns the statements rejecting the VIPs not exported to the gateway.
+func rejectStatements(vips []bird.VIP, gateway bird.Gateway, neighborSet *api.DefinedSet) []*api.Statement {
+	statements := []*api.Statement{}
+	exported := bird.ExportedVIPs(vips, gateway)
+
+	for _, vip := range vips {
+		if slices.Contains(exported, vip) {
+			continue
+		}
+
+		_, ipNet, err := net.ParseCIDR(vip.GetCIDR())
+		if err != nil {
+			continue
+		}
+
+		statements = append(statements, &api.Statement{
+			Name: fmt.Sprintf("not-exported-%s-%s", gateway.GetName(), ipNet.String()),
+			Conditions: &api.Conditions{
+				NeighborSet: &api.MatchSet{Name: neighborSet.GetName()},
+				PrefixSet:   &api.MatchSet{Name: prefixSetName(ipNet)},
+			},
+			Actions: &api.Actions{
+				RouteAction: api.RouteAction_REJECT,
+			},
+		})
+	}
+
+	return statements
+}
+
+func newNeighborSet(gateway bird.Gateway) *api.DefinedSet {
+	return &api.DefinedSet{
+		DefinedType: api.DefinedType_NEIGHBOR,
+		Name:        fmt.Sprintf("gateway-%s", gateway.GetName()),
+		List:        []string{gateway.GetAddress()},
+	}
+}
+
+func prefixSetName(ipNet *net.IPNet) string {
+	return fmt.Sprintf("vip-%s", ipNet.String())
+}
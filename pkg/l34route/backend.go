@@ -0,0 +1,196 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package l34route
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/api/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/kubernetes/pkg/proxy/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	gatewayapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayapiv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+)
+
+const serviceKind = "Service"
+
+// BackendService returns the service referenced by the backend reference of the route. The
+// namespace of the service defaults to the namespace of the route. False is returned if the
+// backend is not a service.
+func BackendService(route *v1alpha1.L34Route, backendRef gatewayapiv1.BackendRef) (types.NamespacedName, bool) {
+	if backendRef.Group != nil && *backendRef.Group != "" {
+		return types.NamespacedName{}, false
+	}
+
+	if backendRef.Kind != nil && *backendRef.Kind != serviceKind {
+		return types.NamespacedName{}, false
+	}
+
+	service := types.NamespacedName{
+		Name:      string(backendRef.Name),
+		Namespace: route.GetNamespace(),
+	}
+
+	if backendRef.Namespace != nil && *backendRef.Namespace != "" {
+		service.Namespace = string(*backendRef.Namespace)
+	}
+
+	return service, true
+}
+
+// BackendPermitted returns true if the route is permitted to reference the service: the
+// service is in the namespace of the route or a ReferenceGrant in the namespace of the
+// service allows the L34Routes of the namespace of the route to reference it.
+func BackendPermitted(
+	ctx context.Context,
+	c client.Reader,
+	route *v1alpha1.L34Route,
+	service types.NamespacedName,
+) (bool, error) {
+	if service.Namespace == route.GetNamespace() {
+		return true, nil
+	}
+
+	referenceGrantList := &gatewayapiv1beta1.ReferenceGrantList{}
+
+	err := c.List(ctx, referenceGrantList, client.InNamespace(service.Namespace))
+	if err != nil {
+		return false, fmt.Errorf("failed to list the reference grants: %w", err)
+	}
+
+	for _, referenceGrant := range referenceGrantList.Items {
+		if referenceGrantFrom(&referenceGrant, route) && referenceGrantTo(&referenceGrant, service) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func referenceGrantFrom(referenceGrant *gatewayapiv1beta1.ReferenceGrant, route *v1alpha1.L34Route) bool {
+	for _, from := range referenceGrant.Spec.From {
+		if string(from.Group) == v1alpha1.GroupName &&
+			from.Kind == l34RouteKind &&
+			string(from.Namespace) == route.GetNamespace() {
+			return true
+		}
+	}
+
+	return false
+}
+
+func referenceGrantTo(referenceGrant *gatewayapiv1beta1.ReferenceGrant, service types.NamespacedName) bool {
+	for _, to := range referenceGrant.Spec.To {
+		if to.Group == "" && to.Kind == serviceKind && (to.Name == nil || string(*to.Name) == service.Name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Backends returns the services referenced by the route which the route is permitted to reference.
+func Backends(ctx context.Context, c client.Reader, route *v1alpha1.L34Route) ([]types.NamespacedName, error) {
+	services := []types.NamespacedName{}
+
+	for _, backendRef := range route.Spec.BackendRefs {
+		service, ok := BackendService(route, backendRef)
+		if !ok {
+			continue
+		}
+
+		permitted, err := BackendPermitted(ctx, c, route, service)
+		if err != nil {
+			return nil, err
+		}
+
+		if permitted {
+			services = append(services, service)
+		}
+	}
+
+	return services, nil
+}
+
+// Services returns the services of the gateway: the services with the service-proxy-name
+// label set to the name of the gateway, in the namespace of the gateway and in the other
+// namespaces where the routes attached to the gateway are permitted to reference them.
+func Services(ctx context.Context, c client.Reader, gateway *gatewayapiv1.Gateway) ([]*v1.Service, error) {
+	serviceList := &v1.ServiceList{}
+
+	err := c.List(ctx,
+		serviceList,
+		client.MatchingLabels{
+			apis.LabelServiceProxyName: gateway.GetName(),
+		},
+		client.InNamespace(gateway.GetNamespace()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the services: %w", err)
+	}
+
+	services := []*v1.Service{}
+	exists := map[types.NamespacedName]struct{}{}
+
+	for _, service := range serviceList.Items {
+		s := service
+		services = append(services, &s)
+		exists[client.ObjectKeyFromObject(&s)] = struct{}{}
+	}
+
+	l34Routes, err := Attached(ctx, c, gateway)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, l34Route := range l34Routes {
+		backends, err := Backends(ctx, c, l34Route)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, backend := range backends {
+			if _, ok := exists[backend]; ok || backend.Namespace == gateway.GetNamespace() {
+				continue
+			}
+
+			service := &v1.Service{}
+
+			err := c.Get(ctx, backend, service)
+			if err != nil {
+				if apierrors.IsNotFound(err) {
+					continue
+				}
+
+				return nil, fmt.Errorf("failed to get the service %s: %w", backend, err)
+			}
+
+			if service.GetLabels()[apis.LabelServiceProxyName] != gateway.GetName() {
+				continue
+			}
+
+			services = append(services, service)
+			exists[backend] = struct{}{}
+		}
+	}
+
+	return services, nil
+}
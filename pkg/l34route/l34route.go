@@ -0,0 +1,484 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package l34route
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/api/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	gatewayapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+const (
+	gatewayKind  = "Gateway"
+	l34RouteKind = "L34Route"
+	anyPort      = "any"
+)
+
+// ParentGateway returns the gateway referenced by the parent reference of the route. The
+// namespace of the gateway defaults to the namespace of the route. False is returned if the
+// parent is not a gateway.
+func ParentGateway(route *v1alpha1.L34Route, parentRef gatewayapiv1.ParentReference) (types.NamespacedName, bool) {
+	if parentRef.Group != nil && *parentRef.Group != gatewayapiv1.GroupName {
+		return types.NamespacedName{}, false
+	}
+
+	if parentRef.Kind != nil && *parentRef.Kind != gatewayKind {
+		return types.NamespacedName{}, false
+	}
+
+	gateway := types.NamespacedName{
+		Name:      string(parentRef.Name),
+		Namespace: route.GetNamespace(),
+	}
+
+	if parentRef.Namespace != nil && *parentRef.Namespace != "" {
+		gateway.Namespace = string(*parentRef.Namespace)
+	}
+
+	return gateway, true
+}
+
+// ParentRefs returns the parent references of the route to the gateway. A route can
+// reference a gateway several times, e.g. to bind to several listeners (sectionName).
+func ParentRefs(route *v1alpha1.L34Route, gateway client.Object) []gatewayapiv1.ParentReference {
+	parentRefs := []gatewayapiv1.ParentReference{}
+
+	for _, parentRef := range route.Spec.ParentRefs {
+		parentGateway, ok := ParentGateway(route, parentRef)
+		if !ok || parentGateway != client.ObjectKeyFromObject(gateway) {
+			continue
+		}
+
+		parentRefs = append(parentRefs, parentRef)
+	}
+
+	return parentRefs
+}
+
+// Attachment is the result of the attachment of a route to a gateway via a parent reference.
+type Attachment struct {
+	// Accepted is true if the route is attached to at least one listener.
+	Accepted bool
+	// Reason of the Accepted condition of the route.
+	Reason gatewayapiv1.RouteConditionReason
+	// Message of the Accepted condition of the route.
+	Message string
+	// Listeners are the names of the listeners the route is attached to.
+	Listeners []gatewayapiv1.SectionName
+}
+
+// Attach binds the route to the listeners of the gateway selected by the parent reference:
+//   - the listeners are selected by the sectionName and the port of the parent reference
+//     (all listeners if not set);
+//   - the listener must allow the route (allowedRoutes): the namespace of the route must be
+//     allowed (the namespace of the gateway by default) and the L34Route kind must be allowed
+//     (all kinds by default);
+//   - the protocol and the port of the listener must be one of the protocols and within the
+//     destination ports of the route.
+//
+// A gateway without listeners accepts the routes of its namespace.
+func Attach(
+	ctx context.Context,
+	c client.Reader,
+	gateway *gatewayapiv1.Gateway,
+	route *v1alpha1.L34Route,
+	parentRef gatewayapiv1.ParentReference,
+) (Attachment, error) {
+	if len(gateway.Spec.Listeners) == 0 {
+		if route.GetNamespace() != gateway.GetNamespace() {
+			return notAccepted(gatewayapiv1.RouteReasonNotAllowedByListeners,
+				"The gateway has no listener and does not allow the routes of other namespaces"), nil
+		}
+
+		return Attachment{
+			Accepted: true,
+			Reason:   gatewayapiv1.RouteReasonAccepted,
+			Message:  "The route is attached to the gateway",
+		}, nil
+	}
+
+	selected := selectListeners(gateway.Spec.Listeners, parentRef)
+	if len(selected) == 0 {
+		return notAccepted(gatewayapiv1.RouteReasonNoMatchingParent,
+			"No listener of the gateway matches the sectionName and port of the parent reference"), nil
+	}
+
+	allowed := []gatewayapiv1.Listener{}
+
+	for _, listener := range selected {
+		ok, err := allowedByListener(ctx, c, gateway, route, listener)
+		if err != nil {
+			return Attachment{}, err
+		}
+
+		if ok {
+			allowed = append(allowed, listener)
+		}
+	}
+
+	if len(allowed) == 0 {
+		return notAccepted(gatewayapiv1.RouteReasonNotAllowedByListeners,
+			"The route is not allowed by the listeners of the gateway"), nil
+	}
+
+	attachment := Attachment{
+		Accepted:  true,
+		Reason:    gatewayapiv1.RouteReasonAccepted,
+		Listeners: []gatewayapiv1.SectionName{},
+	}
+
+	for _, listener := range allowed {
+		if matchesListener(route, listener) {
+			attachment.Listeners = append(attachment.Listeners, listener.Name)
+		}
+	}
+
+	if len(attachment.Listeners) == 0 {
+		return notAccepted(gatewayapiv1.RouteReasonUnsupportedValue,
+			"The protocols and destination ports of the route do not match the listeners of the gateway"), nil
+	}
+
+	attachment.Message = fmt.Sprintf("The route is attached to the listeners %v", attachment.Listeners)
+
+	return attachment, nil
+}
+
+func notAccepted(reason gatewayapiv1.RouteConditionReason, message string) Attachment {
+	return Attachment{
+		Accepted: false,
+		Reason:   reason,
+		Message:  message,
+	}
+}
+
+// selectListeners returns the listeners selected by the sectionName and the port of the
+// parent reference.
+func selectListeners(
+	listeners []gatewayapiv1.Listener,
+	parentRef gatewayapiv1.ParentReference,
+) []gatewayapiv1.Listener {
+	selected := []gatewayapiv1.Listener{}
+
+	for _, listener := range listeners {
+		if parentRef.SectionName != nil && *parentRef.SectionName != listener.Name {
+			continue
+		}
+
+		if parentRef.Port != nil && *parentRef.Port != listener.Port {
+			continue
+		}
+
+		selected = append(selected, listener)
+	}
+
+	return selected
+}
+
+func allowedByListener(
+	ctx context.Context,
+	c client.Reader,
+	gateway *gatewayapiv1.Gateway,
+	route *v1alpha1.L34Route,
+	listener gatewayapiv1.Listener,
+) (bool, error) {
+	if listener.AllowedRoutes == nil {
+		return route.GetNamespace() == gateway.GetNamespace(), nil
+	}
+
+	if !kindAllowed(listener.AllowedRoutes.Kinds) {
+		return false, nil
+	}
+
+	return namespaceAllowed(ctx, c, gateway, route, listener.AllowedRoutes.Namespaces)
+}
+
+// matchesListener returns true if the protocol of the listener is one of the protocols of
+// the route and if the port of the listener is within the destination ports of the route.
+func matchesListener(route *v1alpha1.L34Route, listener gatewayapiv1.Listener) bool {
+	protocolMatches := slices.ContainsFunc(route.Spec.Protocols, func(protocol v1alpha1.TransportProtocol) bool {
+		return strings.EqualFold(string(protocol), string(listener.Protocol))
+	})
+	if !protocolMatches {
+		return false
+	}
+
+	if len(route.Spec.DestinationPorts) == 0 {
+		return true
+	}
+
+	return slices.ContainsFunc(route.Spec.DestinationPorts, func(portRange string) bool {
+		return portInRange(int(listener.Port), portRange)
+	})
+}
+
+// portInRange returns true if the port is within the port range (e.g. "3000",
+// "3000-4000" or "any").
+func portInRange(port int, portRange string) bool {
+	if portRange == anyPort {
+		return true
+	}
+
+	first, last, found := strings.Cut(portRange, "-")
+	if !found {
+		last = first
+	}
+
+	firstPort, err := strconv.Atoi(strings.TrimSpace(first))
+	if err != nil {
+		return false
+	}
+
+	lastPort, err := strconv.Atoi(strings.TrimSpace(last))
+	if err != nil {
+		return false
+	}
+
+	return firstPort <= port && port <= lastPort
+}
+
+func kindAllowed(kinds []gatewayapiv1.RouteGroupKind) bool {
+	if len(kinds) == 0 {
+		return true
+	}
+
+	return slices.ContainsFunc(kinds, func(kind gatewayapiv1.RouteGroupKind) bool {
+		// a kind without group is in the Gateway API group, the L34Routes are only allowed
+		// if the group of this API is set explicitly.
+		return kind.Group != nil && string(*kind.Group) == v1alpha1.GroupName && kind.Kind == l34RouteKind
+	})
+}
+
+func namespaceAllowed(
+	ctx context.Context,
+	c client.Reader,
+	gateway *gatewayapiv1.Gateway,
+	route *v1alpha1.L34Route,
+	namespaces *gatewayapiv1.RouteNamespaces,
+) (bool, error) {
+	from := gatewayapiv1.NamespacesFromSame
+	if namespaces != nil && namespaces.From != nil {
+		from = *namespaces.From
+	}
+
+	switch from {
+	case gatewayapiv1.NamespacesFromAll:
+		return true, nil
+	case gatewayapiv1.NamespacesFromSame:
+		return route.GetNamespace() == gateway.GetNamespace(), nil
+	case gatewayapiv1.NamespacesFromSelector:
+		if namespaces.Selector == nil {
+			return false, nil
+		}
+
+		selector, err := metav1.LabelSelectorAsSelector(namespaces.Selector)
+		if err != nil {
+			return false, fmt.Errorf("invalid namespace selector in the allowed routes: %w", err)
+		}
+
+		namespace := &v1.Namespace{}
+
+		err = c.Get(ctx, types.NamespacedName{Name: route.GetNamespace()}, namespace)
+		if err != nil {
+			return false, fmt.Errorf("failed to get the namespace of the route: %w", err)
+		}
+
+		return selector.Matches(labels.Set(namespace.GetLabels())), nil
+	default:
+		return false, nil
+	}
+}
+
+// Referencing returns the routes referencing the gateway (in all namespaces), whether they
+// are allowed by the listeners of the gateway or not.
+func Referencing(ctx context.Context, c client.Reader, gateway client.Object) ([]*v1alpha1.L34Route, error) {
+	l34RouteList := &v1alpha1.L34RouteList{}
+
+	err := c.List(ctx, l34RouteList)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the l34routes: %w", err)
+	}
+
+	l34Routes := []*v1alpha1.L34Route{}
+
+	for _, l34Route := range l34RouteList.Items {
+		if len(ParentRefs(&l34Route, gateway)) == 0 {
+			continue
+		}
+
+		l34r := l34Route
+
+		l34Routes = append(l34Routes, &l34r)
+	}
+
+	return l34Routes, nil
+}
+
+// Attached returns the routes attached to the gateway: the routes referencing the gateway
+// and accepted by at least one of its listeners via one of their parent references.
+func Attached(ctx context.Context, c client.Reader, gateway *gatewayapiv1.Gateway) ([]*v1alpha1.L34Route, error) {
+	l34Routes, err := Referencing(ctx, c, gateway)
+	if err != nil {
+		return nil, err
+	}
+
+	attached := []*v1alpha1.L34Route{}
+
+	for _, l34Route := range l34Routes {
+		for _, parentRef := range ParentRefs(l34Route, gateway) {
+			attachment, err := Attach(ctx, c, gateway, l34Route, parentRef)
+			if err != nil {
+				return nil, err
+			}
+
+			if attachment.Accepted {
+				attached = append(attached, l34Route)
+
+				break
+			}
+		}
+	}
+
+	return attached, nil
+}
+
+// Programmed returns the routes to program in the data plane of the gateway: the routes
+// attached to the gateway (see Attached) restricted to the protocols and the ports of the
+// listeners they are attached to (see RestrictToListeners). The routes are returned as they
+// are if the gateway has no listener.
+func Programmed(ctx context.Context, c client.Reader, gateway *gatewayapiv1.Gateway) ([]*v1alpha1.L34Route, error) {
+	l34Routes, err := Referencing(ctx, c, gateway)
+	if err != nil {
+		return nil, err
+	}
+
+	programmed := []*v1alpha1.L34Route{}
+
+	for _, l34Route := range l34Routes {
+		accepted := false
+		listenerNames := map[gatewayapiv1.SectionName]struct{}{}
+
+		for _, parentRef := range ParentRefs(l34Route, gateway) {
+			attachment, err := Attach(ctx, c, gateway, l34Route, parentRef)
+			if err != nil {
+				return nil, err
+			}
+
+			if !attachment.Accepted {
+				continue
+			}
+
+			accepted = true
+
+			for _, listenerName := range attachment.Listeners {
+				listenerNames[listenerName] = struct{}{}
+			}
+		}
+
+		if !accepted {
+			continue
+		}
+
+		if len(gateway.Spec.Listeners) == 0 {
+			programmed = append(programmed, l34Route)
+
+			continue
+		}
+
+		listeners := []gatewayapiv1.Listener{}
+
+		for _, listener := range gateway.Spec.Listeners {
+			if _, exists := listenerNames[listener.Name]; exists {
+				listeners = append(listeners, listener)
+			}
+		}
+
+		programmed = append(programmed, RestrictToListeners(l34Route, listeners)...)
+	}
+
+	return programmed, nil
+}
+
+// RestrictToListeners returns copies of the route whose protocols and destination ports are
+// restricted to the protocols and the ports of the listeners matching the route, so the
+// ports of the route outside the listeners are not programmed. The protocols of the route
+// sharing the same listener ports are kept in the same copy, a copy is made for each set
+// of ports (e.g. a TCP listener on port 4000 and a UDP listener on port 5000) and is named
+// after the route and its protocols (e.g. <route>-udp) to get distinct flows.
+func RestrictToListeners(route *v1alpha1.L34Route, listeners []gatewayapiv1.Listener) []*v1alpha1.L34Route {
+	protocolsPerPorts := map[string][]v1alpha1.TransportProtocol{}
+	portSets := []string{}
+
+	for _, protocol := range route.Spec.Protocols {
+		ports := []int{}
+
+		for _, listener := range listeners {
+			if strings.EqualFold(string(protocol), string(listener.Protocol)) &&
+				matchesListener(route, listener) && !slices.Contains(ports, int(listener.Port)) {
+				ports = append(ports, int(listener.Port))
+			}
+		}
+
+		if len(ports) == 0 {
+			continue
+		}
+
+		slices.Sort(ports)
+
+		portStrings := []string{}
+		for _, port := range ports {
+			portStrings = append(portStrings, strconv.Itoa(port))
+		}
+
+		portSet := strings.Join(portStrings, ",")
+		if _, exists := protocolsPerPorts[portSet]; !exists {
+			portSets = append(portSets, portSet)
+		}
+
+		protocolsPerPorts[portSet] = append(protocolsPerPorts[portSet], protocol)
+	}
+
+	restricted := []*v1alpha1.L34Route{}
+
+	for _, portSet := range portSets {
+		l34Route := route.DeepCopy()
+		l34Route.Spec.Protocols = protocolsPerPorts[portSet]
+		l34Route.Spec.DestinationPorts = strings.Split(portSet, ",")
+
+		if len(portSets) > 1 {
+			suffix := []string{}
+			for _, protocol := range l34Route.Spec.Protocols {
+				suffix = append(suffix, strings.ToLower(string(protocol)))
+			}
+
+			l34Route.SetName(fmt.Sprintf("%s-%s", route.GetName(), strings.Join(suffix, "-")))
+		}
+
+		restricted = append(restricted, l34Route)
+	}
+
+	return restricted
+}
@@ -0,0 +1,483 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package l34route_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/api/v1alpha1"
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/l34route"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	gatewayapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+func newL34Route(namespace string) *v1alpha1.L34Route {
+	return &v1alpha1.L34Route{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "route",
+			Namespace: namespace,
+		},
+	}
+}
+
+func TestParentGateway(t *testing.T) {
+	otherNamespace := gatewayapiv1.Namespace("other")
+	otherKind := gatewayapiv1.Kind("Service")
+
+	tests := []struct {
+		name      string
+		parentRef gatewayapiv1.ParentReference
+		want      types.NamespacedName
+		wantOk    bool
+	}{
+		{
+			name:      "same namespace",
+			parentRef: gatewayapiv1.ParentReference{Name: "gateway"},
+			want:      types.NamespacedName{Name: "gateway", Namespace: "route-ns"},
+			wantOk:    true,
+		},
+		{
+			name:      "other namespace",
+			parentRef: gatewayapiv1.ParentReference{Name: "gateway", Namespace: &otherNamespace},
+			want:      types.NamespacedName{Name: "gateway", Namespace: "other"},
+			wantOk:    true,
+		},
+		{
+			name:      "not a gateway",
+			parentRef: gatewayapiv1.ParentReference{Name: "gateway", Kind: &otherKind},
+			want:      types.NamespacedName{},
+			wantOk:    false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := l34route.ParentGateway(newL34Route("route-ns"), tt.parentRef)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("ParentGateway() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestBackendService(t *testing.T) {
+	otherNamespace := gatewayapiv1.Namespace("other")
+	otherGroup := gatewayapiv1.Group("example.com")
+
+	tests := []struct {
+		name       string
+		backendRef gatewayapiv1.BackendRef
+		want       types.NamespacedName
+		wantOk     bool
+	}{
+		{
+			name: "same namespace",
+			backendRef: gatewayapiv1.BackendRef{
+				BackendObjectReference: gatewayapiv1.BackendObjectReference{Name: "service"},
+			},
+			want:   types.NamespacedName{Name: "service", Namespace: "route-ns"},
+			wantOk: true,
+		},
+		{
+			name: "other namespace",
+			backendRef: gatewayapiv1.BackendRef{
+				BackendObjectReference: gatewayapiv1.BackendObjectReference{Name: "service", Namespace: &otherNamespace},
+			},
+			want:   types.NamespacedName{Name: "service", Namespace: "other"},
+			wantOk: true,
+		},
+		{
+			name: "not a service",
+			backendRef: gatewayapiv1.BackendRef{
+				BackendObjectReference: gatewayapiv1.BackendObjectReference{Name: "service", Group: &otherGroup},
+			},
+			want:   types.NamespacedName{},
+			wantOk: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := l34route.BackendService(newL34Route("route-ns"), tt.backendRef)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("BackendService() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestAttach(t *testing.T) {
+	fromAll := gatewayapiv1.NamespacesFromAll
+	fromSame := gatewayapiv1.NamespacesFromSame
+	l34RouteGroup := gatewayapiv1.Group(v1alpha1.GroupName)
+	httpRouteGroup := gatewayapiv1.Group(gatewayapiv1.GroupName)
+	sectionB := gatewayapiv1.SectionName("b")
+	sectionUnknown := gatewayapiv1.SectionName("unknown")
+	port5000 := gatewayapiv1.PortNumber(5000)
+
+	tcp4000 := gatewayapiv1.Listener{
+		Name:     "a",
+		Port:     4000,
+		Protocol: gatewayapiv1.TCPProtocolType,
+		AllowedRoutes: &gatewayapiv1.AllowedRoutes{
+			Namespaces: &gatewayapiv1.RouteNamespaces{From: &fromAll},
+		},
+	}
+	udp5000 := gatewayapiv1.Listener{
+		Name:     "b",
+		Port:     5000,
+		Protocol: gatewayapiv1.UDPProtocolType,
+		AllowedRoutes: &gatewayapiv1.AllowedRoutes{
+			Namespaces: &gatewayapiv1.RouteNamespaces{From: &fromAll},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		routeNamespace string
+		protocols      []v1alpha1.TransportProtocol
+		ports          []string
+		parentRef      gatewayapiv1.ParentReference
+		listeners      []gatewayapiv1.Listener
+		want           l34route.Attachment
+	}{
+		{
+			name:           "no listener same namespace",
+			routeNamespace: "gateway-ns",
+			want: l34route.Attachment{
+				Accepted: true,
+				Reason:   gatewayapiv1.RouteReasonAccepted,
+			},
+		},
+		{
+			name:           "no listener other namespace",
+			routeNamespace: "route-ns",
+			want: l34route.Attachment{
+				Accepted: false,
+				Reason:   gatewayapiv1.RouteReasonNotAllowedByListeners,
+			},
+		},
+		{
+			name:           "all namespaces",
+			routeNamespace: "route-ns",
+			protocols:      []v1alpha1.TransportProtocol{v1alpha1.TCP},
+			ports:          []string{"4000"},
+			listeners:      []gatewayapiv1.Listener{tcp4000},
+			want: l34route.Attachment{
+				Accepted:  true,
+				Reason:    gatewayapiv1.RouteReasonAccepted,
+				Listeners: []gatewayapiv1.SectionName{"a"},
+			},
+		},
+		{
+			name:           "same namespace only",
+			routeNamespace: "route-ns",
+			protocols:      []v1alpha1.TransportProtocol{v1alpha1.TCP},
+			listeners: []gatewayapiv1.Listener{
+				{
+					Name:     "a",
+					Port:     4000,
+					Protocol: gatewayapiv1.TCPProtocolType,
+					AllowedRoutes: &gatewayapiv1.AllowedRoutes{
+						Namespaces: &gatewayapiv1.RouteNamespaces{From: &fromSame},
+					},
+				},
+			},
+			want: l34route.Attachment{
+				Accepted: false,
+				Reason:   gatewayapiv1.RouteReasonNotAllowedByListeners,
+			},
+		},
+		{
+			name:           "kind allowed",
+			routeNamespace: "route-ns",
+			protocols:      []v1alpha1.TransportProtocol{v1alpha1.TCP},
+			listeners: []gatewayapiv1.Listener{
+				{
+					Name:     "a",
+					Port:     4000,
+					Protocol: gatewayapiv1.TCPProtocolType,
+					AllowedRoutes: &gatewayapiv1.AllowedRoutes{
+						Namespaces: &gatewayapiv1.RouteNamespaces{From: &fromAll},
+						Kinds:      []gatewayapiv1.RouteGroupKind{{Group: &l34RouteGroup, Kind: "L34Route"}},
+					},
+				},
+			},
+			want: l34route.Attachment{
+				Accepted:  true,
+				Reason:    gatewayapiv1.RouteReasonAccepted,
+				Listeners: []gatewayapiv1.SectionName{"a"},
+			},
+		},
+		{
+			name:           "kind not allowed",
+			routeNamespace: "route-ns",
+			protocols:      []v1alpha1.TransportProtocol{v1alpha1.TCP},
+			listeners: []gatewayapiv1.Listener{
+				{
+					Name:     "a",
+					Port:     4000,
+					Protocol: gatewayapiv1.TCPProtocolType,
+					AllowedRoutes: &gatewayapiv1.AllowedRoutes{
+						Namespaces: &gatewayapiv1.RouteNamespaces{From: &fromAll},
+						Kinds:      []gatewayapiv1.RouteGroupKind{{Group: &httpRouteGroup, Kind: "HTTPRoute"}},
+					},
+				},
+			},
+			want: l34route.Attachment{
+				Accepted: false,
+				Reason:   gatewayapiv1.RouteReasonNotAllowedByListeners,
+			},
+		},
+		{
+			name:           "kind without group not allowed",
+			routeNamespace: "route-ns",
+			protocols:      []v1alpha1.TransportProtocol{v1alpha1.TCP},
+			listeners: []gatewayapiv1.Listener{
+				{
+					Name:     "a",
+					Port:     4000,
+					Protocol: gatewayapiv1.TCPProtocolType,
+					AllowedRoutes: &gatewayapiv1.AllowedRoutes{
+						Namespaces: &gatewayapiv1.RouteNamespaces{From: &fromAll},
+						Kinds:      []gatewayapiv1.RouteGroupKind{{Kind: "L34Route"}},
+					},
+				},
+			},
+			want: l34route.Attachment{
+				Accepted: false,
+				Reason:   gatewayapiv1.RouteReasonNotAllowedByListeners,
+			},
+		},
+		{
+			name:           "all listeners matching",
+			routeNamespace: "gateway-ns",
+			protocols:      []v1alpha1.TransportProtocol{v1alpha1.TCP, v1alpha1.UDP},
+			ports:          []string{"any"},
+			listeners:      []gatewayapiv1.Listener{tcp4000, udp5000},
+			want: l34route.Attachment{
+				Accepted:  true,
+				Reason:    gatewayapiv1.RouteReasonAccepted,
+				Listeners: []gatewayapiv1.SectionName{"a", "b"},
+			},
+		},
+		{
+			name:           "section name",
+			routeNamespace: "gateway-ns",
+			protocols:      []v1alpha1.TransportProtocol{v1alpha1.TCP, v1alpha1.UDP},
+			ports:          []string{"3000-6000"},
+			parentRef:      gatewayapiv1.ParentReference{Name: "gateway", SectionName: &sectionB},
+			listeners:      []gatewayapiv1.Listener{tcp4000, udp5000},
+			want: l34route.Attachment{
+				Accepted:  true,
+				Reason:    gatewayapiv1.RouteReasonAccepted,
+				Listeners: []gatewayapiv1.SectionName{"b"},
+			},
+		},
+		{
+			name:           "no destination ports",
+			routeNamespace: "gateway-ns",
+			protocols:      []v1alpha1.TransportProtocol{v1alpha1.UDP},
+			listeners:      []gatewayapiv1.Listener{tcp4000, udp5000},
+			want: l34route.Attachment{
+				Accepted:  true,
+				Reason:    gatewayapiv1.RouteReasonAccepted,
+				Listeners: []gatewayapiv1.SectionName{"b"},
+			},
+		},
+		{
+			name:           "destination port range",
+			routeNamespace: "gateway-ns",
+			protocols:      []v1alpha1.TransportProtocol{v1alpha1.TCP},
+			ports:          []string{"3000-4500"},
+			listeners:      []gatewayapiv1.Listener{tcp4000, udp5000},
+			want: l34route.Attachment{
+				Accepted:  true,
+				Reason:    gatewayapiv1.RouteReasonAccepted,
+				Listeners: []gatewayapiv1.SectionName{"a"},
+			},
+		},
+		{
+			name:           "multi-protocol route with a single protocol listener",
+			routeNamespace: "gateway-ns",
+			protocols:      []v1alpha1.TransportProtocol{v1alpha1.TCP, v1alpha1.UDP, v1alpha1.SCTP},
+			ports:          []string{"4000"},
+			listeners:      []gatewayapiv1.Listener{tcp4000},
+			want: l34route.Attachment{
+				Accepted:  true,
+				Reason:    gatewayapiv1.RouteReasonAccepted,
+				Listeners: []gatewayapiv1.SectionName{"a"},
+			},
+		},
+		{
+			name:           "unknown section name",
+			routeNamespace: "gateway-ns",
+			protocols:      []v1alpha1.TransportProtocol{v1alpha1.TCP},
+			parentRef:      gatewayapiv1.ParentReference{Name: "gateway", SectionName: &sectionUnknown},
+			listeners:      []gatewayapiv1.Listener{tcp4000, udp5000},
+			want: l34route.Attachment{
+				Accepted: false,
+				Reason:   gatewayapiv1.RouteReasonNoMatchingParent,
+			},
+		},
+		{
+			name:           "port with protocol mismatch",
+			routeNamespace: "gateway-ns",
+			protocols:      []v1alpha1.TransportProtocol{v1alpha1.TCP},
+			parentRef:      gatewayapiv1.ParentReference{Name: "gateway", Port: &port5000},
+			listeners:      []gatewayapiv1.Listener{tcp4000, udp5000},
+			want: l34route.Attachment{
+				Accepted: false,
+				Reason:   gatewayapiv1.RouteReasonUnsupportedValue,
+			},
+		},
+		{
+			name:           "destination port mismatch",
+			routeNamespace: "gateway-ns",
+			protocols:      []v1alpha1.TransportProtocol{v1alpha1.TCP},
+			ports:          []string{"4001-5000"},
+			listeners:      []gatewayapiv1.Listener{tcp4000},
+			want: l34route.Attachment{
+				Accepted: false,
+				Reason:   gatewayapiv1.RouteReasonUnsupportedValue,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gateway := &gatewayapiv1.Gateway{
+				ObjectMeta: metav1.ObjectMeta{Name: "gateway", Namespace: "gateway-ns"},
+				Spec:       gatewayapiv1.GatewaySpec{Listeners: tt.listeners},
+			}
+
+			route := newL34Route(tt.routeNamespace)
+			route.Spec.Protocols = tt.protocols
+			route.Spec.DestinationPorts = tt.ports
+
+			got, err := l34route.Attach(context.Background(), nil, gateway, route, tt.parentRef)
+			if err != nil {
+				t.Fatalf("Attach() error = %v", err)
+			}
+
+			if got.Accepted != tt.want.Accepted || got.Reason != tt.want.Reason ||
+				!reflect.DeepEqual(got.Listeners, tt.want.Listeners) {
+				t.Errorf("Attach() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRestrictToListeners(t *testing.T) {
+	tcp4000 := gatewayapiv1.Listener{Name: "a", Port: 4000, Protocol: gatewayapiv1.TCPProtocolType}
+	tcp4001 := gatewayapiv1.Listener{Name: "b", Port: 4001, Protocol: gatewayapiv1.TCPProtocolType}
+	udp4000 := gatewayapiv1.Listener{Name: "c", Port: 4000, Protocol: gatewayapiv1.UDPProtocolType}
+	udp5000 := gatewayapiv1.Listener{Name: "d", Port: 5000, Protocol: gatewayapiv1.UDPProtocolType}
+
+	type restricted struct {
+		name      string
+		protocols []v1alpha1.TransportProtocol
+		ports     []string
+	}
+
+	tests := []struct {
+		name      string
+		protocols []v1alpha1.TransportProtocol
+		ports     []string
+		listeners []gatewayapiv1.Listener
+		want      []restricted
+	}{
+		{
+			name:      "no destination ports",
+			protocols: []v1alpha1.TransportProtocol{v1alpha1.TCP},
+			listeners: []gatewayapiv1.Listener{tcp4001, tcp4000},
+			want: []restricted{
+				{name: "route", protocols: []v1alpha1.TransportProtocol{v1alpha1.TCP}, ports: []string{"4000", "4001"}},
+			},
+		},
+		{
+			name:      "destination port range",
+			protocols: []v1alpha1.TransportProtocol{v1alpha1.TCP},
+			ports:     []string{"3000-4000"},
+			listeners: []gatewayapiv1.Listener{tcp4000, tcp4001},
+			want: []restricted{
+				{name: "route", protocols: []v1alpha1.TransportProtocol{v1alpha1.TCP}, ports: []string{"4000"}},
+			},
+		},
+		{
+			name:      "multi-protocol route with a single protocol listener",
+			protocols: []v1alpha1.TransportProtocol{v1alpha1.TCP, v1alpha1.UDP},
+			ports:     []string{"any"},
+			listeners: []gatewayapiv1.Listener{tcp4000},
+			want: []restricted{
+				{name: "route", protocols: []v1alpha1.TransportProtocol{v1alpha1.TCP}, ports: []string{"4000"}},
+			},
+		},
+		{
+			name:      "protocols sharing the listener ports",
+			protocols: []v1alpha1.TransportProtocol{v1alpha1.TCP, v1alpha1.UDP},
+			listeners: []gatewayapiv1.Listener{tcp4000, udp4000},
+			want: []restricted{
+				{
+					name:      "route",
+					protocols: []v1alpha1.TransportProtocol{v1alpha1.TCP, v1alpha1.UDP},
+					ports:     []string{"4000"},
+				},
+			},
+		},
+		{
+			name:      "protocols with different listener ports",
+			protocols: []v1alpha1.TransportProtocol{v1alpha1.TCP, v1alpha1.UDP},
+			listeners: []gatewayapiv1.Listener{tcp4000, udp5000},
+			want: []restricted{
+				{name: "route-tcp", protocols: []v1alpha1.TransportProtocol{v1alpha1.TCP}, ports: []string{"4000"}},
+				{name: "route-udp", protocols: []v1alpha1.TransportProtocol{v1alpha1.UDP}, ports: []string{"5000"}},
+			},
+		},
+		{
+			name:      "no listener matching",
+			protocols: []v1alpha1.TransportProtocol{v1alpha1.TCP},
+			ports:     []string{"5000"},
+			listeners: []gatewayapiv1.Listener{tcp4000},
+			want:      []restricted{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route := newL34Route("gateway-ns")
+			route.Spec.Protocols = tt.protocols
+			route.Spec.DestinationPorts = tt.ports
+
+			got := []restricted{}
+			for _, l34Route := range l34route.RestrictToListeners(route, tt.listeners) {
+				got = append(got, restricted{
+					name:      l34Route.GetName(),
+					protocols: l34Route.Spec.Protocols,
+					ports:     l34Route.Spec.DestinationPorts,
+				})
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RestrictToListeners() = %+v, want %+v", got, tt.want)
+			}
+
+			if route.GetName() != "route" || !reflect.DeepEqual(route.Spec.Protocols, tt.protocols) {
+				t.Errorf("RestrictToListeners() modified the route: %+v", route)
+			}
+		})
+	}
+}
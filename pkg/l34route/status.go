@@ -0,0 +1,175 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package l34route
+
+import (
+	"context"
+	"reflect"
+	"slices"
+
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/api/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	gatewayapiv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+// ParentStatus returns the status of the route for the parent reference to a gateway:
+// the route is accepted if it is attached to the gateway (see Attach), and its references
+// are resolved if all backend references are services the route is permitted to reference.
+func ParentStatus(
+	ctx context.Context,
+	c client.Reader,
+	route *v1alpha1.L34Route,
+	parentRef gatewayapiv1.ParentReference,
+	controllerName gatewayapiv1.GatewayController,
+	attachment Attachment,
+) (gatewayapiv1.RouteParentStatus, error) {
+	parentStatus := gatewayapiv1.RouteParentStatus{
+		ParentRef:      parentRef,
+		ControllerName: controllerName,
+		Conditions:     []metav1.Condition{},
+	}
+
+	accepted := metav1.Condition{
+		Type:               string(gatewayapiv1.RouteConditionAccepted),
+		Status:             metav1.ConditionTrue,
+		Reason:             string(attachment.Reason),
+		Message:            attachment.Message,
+		ObservedGeneration: route.GetGeneration(),
+	}
+
+	if !attachment.Accepted {
+		accepted.Status = metav1.ConditionFalse
+	}
+
+	meta.SetStatusCondition(&parentStatus.Conditions, accepted)
+
+	resolvedRefs, err := resolvedRefsCondition(ctx, c, route)
+	if err != nil {
+		return parentStatus, err
+	}
+
+	meta.SetStatusCondition(&parentStatus.Conditions, resolvedRefs)
+
+	return parentStatus, nil
+}
+
+func resolvedRefsCondition(ctx context.Context, c client.Reader, route *v1alpha1.L34Route) (metav1.Condition, error) {
+	for _, backendRef := range route.Spec.BackendRefs {
+		service, ok := BackendService(route, backendRef)
+		if !ok {
+			return resolvedRefs(route, metav1.ConditionFalse, gatewayapiv1.RouteReasonInvalidKind,
+				"The backend "+string(backendRef.Name)+" is not a service"), nil
+		}
+
+		permitted, err := BackendPermitted(ctx, c, route, service)
+		if err != nil {
+			return metav1.Condition{}, err
+		}
+
+		if !permitted {
+			return resolvedRefs(route, metav1.ConditionFalse, gatewayapiv1.RouteReasonRefNotPermitted,
+				"No ReferenceGrant allows the reference to the service "+service.String()), nil
+		}
+
+		err = c.Get(ctx, service, &v1.Service{})
+		if err != nil {
+			if client.IgnoreNotFound(err) != nil {
+				return metav1.Condition{}, err //nolint:wrapcheck
+			}
+
+			return resolvedRefs(route, metav1.ConditionFalse, gatewayapiv1.RouteReasonBackendNotFound,
+				"The service "+service.String()+" does not exist"), nil
+		}
+	}
+
+	return resolvedRefs(route, metav1.ConditionTrue, gatewayapiv1.RouteReasonResolvedRefs,
+		"All references are resolved"), nil
+}
+
+func resolvedRefs(
+	route *v1alpha1.L34Route,
+	status metav1.ConditionStatus,
+	reason gatewayapiv1.RouteConditionReason,
+	message string,
+) metav1.Condition {
+	return metav1.Condition{
+		Type:               string(gatewayapiv1.RouteConditionResolvedRefs),
+		Status:             status,
+		Reason:             string(reason),
+		Message:            message,
+		ObservedGeneration: route.GetGeneration(),
+	}
+}
+
+// SetParentStatus sets the status of the route for a parent reference, replacing the
+// existing status written by the same controller for the same parent reference. The
+// last transition times of the existing conditions are kept if their status did not change.
+// True is returned if the status of the route changed.
+func SetParentStatus(route *v1alpha1.L34Route, parentStatus gatewayapiv1.RouteParentStatus) bool {
+	for i, existing := range route.Status.Parents {
+		if existing.ControllerName != parentStatus.ControllerName ||
+			!reflect.DeepEqual(existing.ParentRef, parentStatus.ParentRef) {
+			continue
+		}
+
+		conditions := existing.DeepCopy().Conditions
+		for _, condition := range parentStatus.Conditions {
+			meta.SetStatusCondition(&conditions, condition)
+		}
+
+		if reflect.DeepEqual(conditions, existing.Conditions) {
+			return false
+		}
+
+		route.Status.Parents[i].Conditions = conditions
+
+		return true
+	}
+
+	route.Status.Parents = append(route.Status.Parents, parentStatus)
+
+	return true
+}
+
+// RemoveStaleParentStatuses removes the statuses written by the controller for the parent
+// references which are no longer in the spec of the route. True is returned if the status
+// of the route changed.
+func RemoveStaleParentStatuses(route *v1alpha1.L34Route, controllerName gatewayapiv1.GatewayController) bool {
+	parents := []gatewayapiv1.RouteParentStatus{}
+
+	for _, parentStatus := range route.Status.Parents {
+		if parentStatus.ControllerName == controllerName &&
+			!slices.ContainsFunc(route.Spec.ParentRefs, func(parentRef gatewayapiv1.ParentReference) bool {
+				return reflect.DeepEqual(parentRef, parentStatus.ParentRef)
+			}) {
+			continue
+		}
+
+		parents = append(parents, parentStatus)
+	}
+
+	if len(parents) == len(route.Status.Parents) {
+		return false
+	}
+
+	route.Status.Parents = parents
+
+	return true
+}
@@ -18,6 +18,9 @@ package networking
 
 import (
 	"net"
+
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/api/v1alpha1"
+	gatewayapiv1 "sigs.k8s.io/gateway-api/apis/v1"
 )
 
 // BroadcastFromIPNet returns the broadcast address from an IPNet.
@@ -46,3 +49,60 @@ func NextIP(ip net.IP) net.IP {
 
 	return next
 }
+
+// GatewayAddress returns the Gateway status address of a CIDR: the IP with the IPAddress
+// type for a single IP (/32 or /128), otherwise the prefix with the CIDRAddressType.
+// false is returned if the CIDR is invalid.
+func GatewayAddress(cidr string) (gatewayapiv1.GatewayStatusAddress, bool) {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return gatewayapiv1.GatewayStatusAddress{}, false
+	}
+
+	addressType := gatewayapiv1.IPAddressType
+	value := ipNet.String()
+
+	if ones, bits := ipNet.Mask.Size(); ones == bits {
+		value = ipNet.IP.String()
+	} else {
+		addressType = v1alpha1.CIDRAddressType
+	}
+
+	return gatewayapiv1.GatewayStatusAddress{
+		Type:  &addressType,
+		Value: value,
+	}, true
+}
+
+// GatewayAddressCIDR returns the CIDR of a Gateway status address: /32 or /128 for an
+// address of the IPAddress type, the prefix for an address of the CIDRAddressType.
+// false is returned if the address is invalid or of another type.
+func GatewayAddressCIDR(address gatewayapiv1.GatewayStatusAddress) (string, bool) {
+	addressType := gatewayapiv1.IPAddressType
+	if address.Type != nil {
+		addressType = *address.Type
+	}
+
+	switch addressType {
+	case gatewayapiv1.IPAddressType:
+		ip := net.ParseIP(address.Value)
+		if ip == nil {
+			return "", false
+		}
+
+		if ip.To4() != nil {
+			return ip.String() + "/32", true
+		}
+
+		return ip.String() + "/128", true
+	case v1alpha1.CIDRAddressType:
+		_, ipNet, err := net.ParseCIDR(address.Value)
+		if err != nil {
+			return "", false
+		}
+
+		return ipNet.String(), true
+	}
+
+	return "", false
+}
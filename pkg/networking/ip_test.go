@@ -21,8 +21,10 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/api/v1alpha1"
 	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/networking"
 	"go.uber.org/goleak"
+	gatewayapiv1 "sigs.k8s.io/gateway-api/apis/v1"
 )
 
 func TestBroadcastFromIPNet(t *testing.T) {
@@ -184,3 +186,104 @@ func TestNextIP(t *testing.T) {
 		})
 	}
 }
+
+func TestGatewayAddress(t *testing.T) {
+	t.Cleanup(func() { goleak.VerifyNone(t) })
+
+	tests := []struct {
+		name        string
+		cidr        string
+		wantAddress gatewayapiv1.GatewayStatusAddress
+		wantCIDR    string
+		wantOk      bool
+	}{
+		{
+			name:        "ipv4: 40.0.0.1/32",
+			cidr:        "40.0.0.1/32",
+			wantAddress: gatewayapiv1.GatewayStatusAddress{Type: ptrTo(gatewayapiv1.IPAddressType), Value: "40.0.0.1"},
+			wantCIDR:    "40.0.0.1/32",
+			wantOk:      true,
+		},
+		{
+			name:        "ipv4: 40.0.0.5/24",
+			cidr:        "40.0.0.5/24",
+			wantAddress: gatewayapiv1.GatewayStatusAddress{Type: ptrTo(v1alpha1.CIDRAddressType), Value: "40.0.0.0/24"},
+			wantCIDR:    "40.0.0.0/24",
+			wantOk:      true,
+		},
+		{
+			name:        "ipv6: 2000::1/128",
+			cidr:        "2000::1/128",
+			wantAddress: gatewayapiv1.GatewayStatusAddress{Type: ptrTo(gatewayapiv1.IPAddressType), Value: "2000::1"},
+			wantCIDR:    "2000::1/128",
+			wantOk:      true,
+		},
+		{
+			name:        "ipv6: 2000::/64",
+			cidr:        "2000::/64",
+			wantAddress: gatewayapiv1.GatewayStatusAddress{Type: ptrTo(v1alpha1.CIDRAddressType), Value: "2000::/64"},
+			wantCIDR:    "2000::/64",
+			wantOk:      true,
+		},
+		{
+			name:   "invalid",
+			cidr:   "40.0.0.1",
+			wantOk: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			address, ok := networking.GatewayAddress(tt.cidr)
+			if ok != tt.wantOk || !reflect.DeepEqual(address, tt.wantAddress) {
+				t.Fatalf("GatewayAddress() = %v, %v, want %v, %v", address, ok, tt.wantAddress, tt.wantOk)
+			}
+
+			if !ok {
+				return
+			}
+
+			if cidr, ok := networking.GatewayAddressCIDR(address); !ok || cidr != tt.wantCIDR {
+				t.Errorf("GatewayAddressCIDR() = %v, %v, want %v", cidr, ok, tt.wantCIDR)
+			}
+		})
+	}
+}
+
+func TestGatewayAddressCIDR(t *testing.T) {
+	t.Cleanup(func() { goleak.VerifyNone(t) })
+
+	tests := []struct {
+		name    string
+		address gatewayapiv1.GatewayStatusAddress
+		want    string
+		wantOk  bool
+	}{
+		{
+			name:    "no type",
+			address: gatewayapiv1.GatewayStatusAddress{Value: "40.0.0.1"},
+			want:    "40.0.0.1/32",
+			wantOk:  true,
+		},
+		{
+			name:    "invalid IP",
+			address: gatewayapiv1.GatewayStatusAddress{Type: ptrTo(gatewayapiv1.IPAddressType), Value: "40.0.0.0/24"},
+			wantOk:  false,
+		},
+		{
+			name:    "hostname",
+			address: gatewayapiv1.GatewayStatusAddress{Type: ptrTo(gatewayapiv1.HostnameAddressType), Value: "example.com"},
+			wantOk:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, ok := networking.GatewayAddressCIDR(tt.address); got != tt.want || ok != tt.wantOk {
+				t.Errorf("GatewayAddressCIDR() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func ptrTo[T any](a T) *T {
+	return &a
+}
@@ -0,0 +1,119 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package networking
+
+import (
+	"fmt"
+	"net"
+	"slices"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// TableRange is a range of kernel routing table IDs (also used as forwarding marks).
+type TableRange struct {
+	// First is the first table ID of the range.
+	First int
+	// Last is the last table ID of the range (included).
+	Last int
+}
+
+// Contains returns true if the table ID is in the range.
+func (tr TableRange) Contains(tableID int) bool {
+	return tableID >= tr.First && tableID <= tr.Last
+}
+
+// Overlaps returns true if the ranges have at least one table ID in common.
+func (tr TableRange) Overlaps(other TableRange) bool {
+	return tr.First <= other.Last && other.First <= tr.Last
+}
+
+func (tr TableRange) String() string {
+	return fmt.Sprintf("%d-%d", tr.First, tr.Last)
+}
+
+// RoutingConflicts returns a description of the policy rules and routes using a table (or a
+// forwarding mark) of the ranges or one of the rule priorities which are not owned according
+// to the functions in parameter. These rules and routes have been added by another component
+// (e.g. the host or a CNI) and would collide with the ones of the caller.
+func RoutingConflicts(
+	ranges []TableRange,
+	priorities []int,
+	ownedRule func(*netlink.Rule) bool,
+	ownedRoute func(*netlink.Route) bool,
+) ([]string, error) {
+	inRanges := func(tableID int) bool {
+		return slices.ContainsFunc(ranges, func(tr TableRange) bool {
+			return tr.Contains(tableID)
+		})
+	}
+
+	rules, err := netlink.RuleList(netlink.FAMILY_ALL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rules: %w", err)
+	}
+
+	conflicts := []string{}
+
+	for _, rule := range rules {
+		currentRule := rule
+
+		if !inRanges(rule.Table) && !inRanges(rule.Mark) && !slices.Contains(priorities, rule.Priority) {
+			continue
+		}
+
+		if ownedRule(&currentRule) {
+			continue
+		}
+
+		conflict := fmt.Sprintf("rule %d: from %s", rule.Priority, prefixString(rule.Src))
+		if rule.Mark > 0 {
+			conflict = fmt.Sprintf("%s fwmark %d", conflict, rule.Mark)
+		}
+
+		conflicts = append(conflicts, fmt.Sprintf("%s table %d", conflict, rule.Table))
+	}
+
+	routes, err := netlink.RouteListFiltered(netlink.FAMILY_ALL, &netlink.Route{
+		Table: unix.RT_TABLE_UNSPEC,
+	}, netlink.RT_FILTER_TABLE)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list routes: %w", err)
+	}
+
+	for _, route := range routes {
+		currentRoute := route
+
+		if !inRanges(route.Table) || ownedRoute(&currentRoute) {
+			continue
+		}
+
+		conflicts = append(conflicts, fmt.Sprintf("route %s table %d proto %d",
+			prefixString(route.Dst), route.Table, route.Protocol))
+	}
+
+	return conflicts, nil
+}
+
+func prefixString(prefix *net.IPNet) string {
+	if prefix == nil {
+		return "all"
+	}
+
+	return prefix.String()
+}
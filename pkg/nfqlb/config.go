@@ -28,7 +28,8 @@ type nfqlbConfig struct {
 	qlength        uint
 	fanout         bool
 	healInterval   time.Duration
-	startingOffset int
+	startingOffset uint32
+	lastOffset     uint32
 	nfqlbPath      string
 	logger         logr.Logger
 }
@@ -40,6 +41,7 @@ func newNFQLBConfig() *nfqlbConfig {
 		fanout:         false,
 		healInterval:   defaultHealInterval,
 		startingOffset: defaultStartingOffset,
+		lastOffset:     defaultLastOffset,
 		nfqlbPath:      nfqlbCmd,
 		logger:         log.Logger.WithValues("class", "nfqlb"),
 	}
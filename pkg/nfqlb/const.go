@@ -16,7 +16,10 @@ limitations under the License.
 
 package nfqlb
 
-import "time"
+import (
+	"math"
+	"time"
+)
 
 const (
 	ownfw          = 0
@@ -31,6 +34,7 @@ const (
 	defaultQueue          = "0:3"
 	defaultQLength        = 1024
 	defaultStartingOffset = 5000
+	defaultLastOffset     = math.MaxUint32
 	defaultHealInterval   = 10 * time.Second
 	defaultMaxTargets     = 100
 
@@ -47,6 +47,10 @@ func New(options ...Option) (*NFQueueLoadBalancer, error) {
 		opt(config)
 	}
 
+	if config.startingOffset == 0 || config.lastOffset < config.startingOffset {
+		return nil, fmt.Errorf("%w: %d-%d", errOffsetRange, config.startingOffset, config.lastOffset)
+	}
+
 	start, end, err := getQueue(config.queue)
 	if err != nil {
 		return nil, err
@@ -124,7 +128,7 @@ func (nfqlb *NFQueueLoadBalancer) heal(ctx context.Context) {
 			for _, service := range nfqlb.services {
 				service.mu.Lock()
 				for identifier, ips := range service.targets {
-					fwmark := identifier + service.offset
+					fwmark := service.fwMark(identifier)
 
 					for _, ip := range ips {
 						err := createPolicyRoute(fwmark, ip)
@@ -283,7 +287,7 @@ type Service struct {
 	*nfqlbServiceConfig
 	name                              string
 	targets                           map[int][]string // Key: identifier ; Value: IPs
-	offset                            int
+	offset                            uint32
 	mu                                sync.Mutex
 	updateNfQueueDestinationCIDRsFunc func(ctx context.Context) error
 	nfqlbPath                         string
@@ -309,7 +313,7 @@ func (nfqlb *NFQueueLoadBalancer) AddService(ctx context.Context,
 		opt(config)
 	}
 
-	offset, err := getOffset(nfqlb.startingOffset, nfqlb.services, config.maxTargets)
+	offset, err := getOffset(nfqlb.startingOffset, nfqlb.lastOffset, nfqlb.services, config.maxTargets)
 	if err != nil {
 		return nil, err
 	}
@@ -491,6 +495,11 @@ func (s *Service) DeleteFlow(ctx context.Context, flowToDelete Flow) error {
 	return nil
 }
 
+// fwMark returns the forwarding mark (and kernel table) of the target identifier.
+func (s *Service) fwMark(identifier int) uint32 {
+	return s.offset + uint32(identifier) //nolint:gosec
+}
+
 // AddTarget adds a target identifier to the nfqlb service
 // and configures the policy route associated.
 func (s *Service) AddTarget(ctx context.Context, ips []string, identifier int) error {
@@ -511,7 +520,7 @@ func (s *Service) AddTarget(ctx context.Context, ips []string, identifier int) e
 		"activate",
 		fmt.Sprintf("--index=%d", identifier),
 		fmt.Sprintf("--shm=%s", s.name),
-		strconv.Itoa(identifier+s.offset),
+		strconv.FormatUint(uint64(s.fwMark(identifier)), 10),
 	).CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("failed activating nfqlb target ; %w; %s", err, stdoutStderr)
@@ -519,7 +528,7 @@ func (s *Service) AddTarget(ctx context.Context, ips []string, identifier int) e
 
 	s.targets[identifier] = ips
 
-	fwmark := identifier + s.offset
+	fwmark := s.fwMark(identifier)
 
 	for _, ip := range ips {
 		err = createPolicyRoute(fwmark, ip)
@@ -569,7 +578,7 @@ func (s *Service) deleteTargetNoLock(ctx context.Context, ips []string, identifi
 	}
 
 	for _, ip := range ips {
-		_ = deletePolicyRoute(identifier+s.offset, ip)
+		_ = deletePolicyRoute(s.fwMark(identifier), ip)
 	}
 
 	log.FromContextOrGlobal(ctx).Info("nfqlb: target deleted", "service", s.name, "ips", ips, "identifier", identifier)
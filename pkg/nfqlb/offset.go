@@ -16,30 +16,31 @@ limitations under the License.
 
 package nfqlb
 
-import (
-	"errors"
-	"math"
-)
+import "errors"
 
-var errIdentifierOffset = errors.New("unable to generate identifier offset")
+var (
+	errIdentifierOffset = errors.New("unable to generate identifier offset")
+	errOffsetRange      = errors.New("the last offset must be greater than the starting offset")
+)
 
-func getOffset(startingOffset int, services map[string]*Service, maxTarget int) (int, error) {
-	offset := startingOffset
+func getOffset(startingOffset uint32, lastOffset uint32, services map[string]*Service, maxTarget int) (uint32, error) {
+	// computed over 64 bits so the ranges ending at the last forwarding mark do not overflow.
+	offset := uint64(startingOffset)
 
 search:
 	for {
-		if offset >= (math.MaxInt - maxTarget + 1) {
+		if offset+uint64(maxTarget)-1 > uint64(lastOffset) {
 			return 0, errIdentifierOffset
 		}
 
 		for _, service := range services {
-			serviceStart := service.offset
-			serviceEnd := serviceStart + service.maxTargets - 1
+			serviceStart := uint64(service.offset)
+			serviceEnd := serviceStart + uint64(service.maxTargets) - 1
 			currentSearchStart := offset
-			currentSearchEnd := offset + maxTarget - 1
+			currentSearchEnd := offset + uint64(maxTarget) - 1
 
 			if currentSearchStart <= serviceEnd && currentSearchEnd >= serviceStart {
-				offset = serviceStart + service.maxTargets
+				offset = serviceStart + uint64(service.maxTargets)
 
 				continue search
 			}
@@ -48,5 +49,5 @@ search:
 		break
 	}
 
-	return offset, nil
+	return uint32(offset), nil
 }
@@ -42,12 +42,21 @@ func WithFanout(fanout bool) Option {
 
 // WithStartingOffset sets the starting offset for the fowarding mark
 // to avoid collisions with existing routing tables.
-func WithStartingOffset(startingOffset int) Option {
+func WithStartingOffset(startingOffset uint32) Option {
 	return func(c *nfqlbConfig) {
 		c.startingOffset = startingOffset
 	}
 }
 
+// WithLastOffset sets the last fowarding mark (and routing table) which can
+// be used, so the range from the starting offset does not collide with
+// existing routing tables.
+func WithLastOffset(lastOffset uint32) Option {
+	return func(c *nfqlbConfig) {
+		c.lastOffset = lastOffset
+	}
+}
+
 // WithNFQLBPath sets the path to the nfqlb binary.
 func WithNFQLBPath(nfqlbPath string) Option {
 	return func(c *nfqlbConfig) {
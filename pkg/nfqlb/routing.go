@@ -19,17 +19,40 @@ package nfqlb
 import (
 	"errors"
 	"fmt"
+	"math"
 	"net"
 
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/networking"
 	"github.com/vishvananda/netlink"
 )
 
 var errInvalidIP = errors.New("the ip address is invalid")
 
+// Conflicts returns the policy rules and routes using the forwarding marks (and routing tables)
+// from the starting offset to the last offset which have not been added by nfqlb (e.g. added
+// by the router or by a CNI).
+func (nfqlb *NFQueueLoadBalancer) Conflicts() ([]string, error) {
+	//nolint:wrapcheck
+	return networking.RoutingConflicts(
+		// the table IDs are int in netlink, the range is limited to the ones it can report.
+		[]networking.TableRange{{
+			First: int(min(uint64(nfqlb.startingOffset), math.MaxInt)), //nolint:gosec
+			Last:  int(min(uint64(nfqlb.lastOffset), math.MaxInt)),     //nolint:gosec
+		}},
+		nil,
+		func(rule *netlink.Rule) bool {
+			return rule.Mark == rule.Table && rule.Src == nil && rule.Dst == nil
+		},
+		func(route *netlink.Route) bool {
+			return route.Dst == nil && route.Gw != nil
+		},
+	)
+}
+
 // createPolicyRoute creates a new policy route based on the fowarding mark.
 // If the policy route if already existing and correspond to the parameters,
 // nothing will happen, otherwise the previous one will be deleted.
-func createPolicyRoute(fwMark int, ip string) error {
+func createPolicyRoute(fwMark uint32, ip string) error {
 	ipAddr := net.ParseIP(ip)
 	if ipAddr == nil {
 		return errInvalidIP
@@ -55,7 +78,7 @@ func createPolicyRoute(fwMark int, ip string) error {
 	return nil
 }
 
-func deletePolicyRoute(fwMark int, ip string) error {
+func deletePolicyRoute(fwMark uint32, ip string) error {
 	ipAddr := net.ParseIP(ip)
 	if ipAddr == nil {
 		return errInvalidIP
@@ -75,7 +98,7 @@ func deletePolicyRoute(fwMark int, ip string) error {
 }
 
 // todo: valid rule
-func validPolicyRoute(fwMark int, ip net.IP) bool {
+func validPolicyRoute(fwMark uint32, ip net.IP) bool {
 	family := netlink.FAMILY_V6
 
 	if ip.To4() != nil {
@@ -94,17 +117,19 @@ func validPolicyRoute(fwMark int, ip net.IP) bool {
 	return true
 }
 
-func getRoute(tableID int, ip net.IP) *netlink.Route {
+// getRoute returns the route via the ip in the table. netlink handles the table IDs (and the
+// forwarding marks) as int, the uint32 value is kept bit for bit on 32-bit architectures.
+func getRoute(tableID uint32, ip net.IP) *netlink.Route {
 	return &netlink.Route{
 		Gw:    ip,
-		Table: tableID,
+		Table: int(tableID), //nolint:gosec
 	}
 }
 
-func getRule(fwMark int, ip net.IP) *netlink.Rule {
+func getRule(fwMark uint32, ip net.IP) *netlink.Rule {
 	rule := netlink.NewRule()
-	rule.Table = fwMark
-	rule.Mark = fwMark
+	rule.Table = int(fwMark) //nolint:gosec
+	rule.Mark = int(fwMark)  //nolint:gosec
 	rule.Family = netlink.FAMILY_V6
 
 	if ip.To4() != nil {
@@ -0,0 +1,208 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package readiness
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	directoryPermission = 0o755
+	filePermission      = 0o644
+)
+
+// file is the content of the readiness file.
+type file struct {
+	// VIPs (CIDRs) for which the data plane is ready: a flow is programmed
+	// and at least one target is active.
+	VIPs []string `json:"vips"`
+}
+
+// Write atomically writes the VIPs ready to be announced in the readiness file.
+// The file is shared between the stateless-load-balancer and the router
+// containers of a load-balancer pod.
+func Write(path string, vips []string) error {
+	content := &file{
+		VIPs: dedup(vips),
+	}
+
+	data, err := json.Marshal(content)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the readiness file: %w", err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), directoryPermission)
+	if err != nil {
+		return fmt.Errorf("failed to create the readiness directory: %w", err)
+	}
+
+	// write into a temporary file and rename it so the reader never gets a partial file.
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create the temporary readiness file: %w", err)
+	}
+
+	defer os.Remove(tmpFile.Name())
+
+	_, err = tmpFile.Write(data)
+	if err != nil {
+		_ = tmpFile.Close()
+
+		return fmt.Errorf("failed to write the temporary readiness file: %w", err)
+	}
+
+	err = tmpFile.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close the temporary readiness file: %w", err)
+	}
+
+	err = os.Chmod(tmpFile.Name(), filePermission)
+	if err != nil {
+		return fmt.Errorf("failed to chmod the temporary readiness file: %w", err)
+	}
+
+	err = os.Rename(tmpFile.Name(), path)
+	if err != nil {
+		return fmt.Errorf("failed to rename the temporary readiness file: %w", err)
+	}
+
+	return nil
+}
+
+// Read returns the VIPs ready to be announced from the readiness file.
+// No VIP is ready if the file does not exist.
+func Read(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []string{}, nil
+		}
+
+		return nil, fmt.Errorf("failed to read the readiness file: %w", err)
+	}
+
+	content := &file{}
+
+	err = json.Unmarshal(data, content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the readiness file: %w", err)
+	}
+
+	return dedup(content.VIPs), nil
+}
+
+// Filter returns the VIPs contained in one of the ready CIDRs.
+func Filter(vips []string, ready []string) []string {
+	readyNets := []*net.IPNet{}
+
+	for _, cidr := range ready {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			continue
+		}
+
+		readyNets = append(readyNets, ipNet)
+	}
+
+	res := []string{}
+
+	for _, vip := range vips {
+		ip, vipNet, err := net.ParseCIDR(vip)
+		if err != nil {
+			continue
+		}
+
+		vipOnes, _ := vipNet.Mask.Size()
+
+		for _, readyNet := range readyNets {
+			readyOnes, _ := readyNet.Mask.Size()
+
+			if readyNet.Contains(ip) && readyOnes <= vipOnes && (ip.To4() == nil) == (readyNet.IP.To4() == nil) {
+				res = append(res, vip)
+
+				break
+			}
+		}
+	}
+
+	return res
+}
+
+// Watch polls the readiness file every interval and sends a notification on the
+// returned channel each time its content changes. The channel is closed once the
+// context is done.
+func Watch(ctx context.Context, path string, interval time.Duration) <-chan struct{} {
+	notifications := make(chan struct{}, 1)
+	previous, _ := os.ReadFile(path)
+
+	go func() {
+		defer close(notifications)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			current, _ := os.ReadFile(path)
+			if bytes.Equal(previous, current) {
+				continue
+			}
+
+			previous = current
+
+			select {
+			case notifications <- struct{}{}:
+			default: // a notification is already pending.
+			}
+		}
+	}()
+
+	return notifications
+}
+
+func dedup(vips []string) []string {
+	res := []string{}
+	exists := map[string]struct{}{}
+
+	for _, vip := range vips {
+		if _, ok := exists[vip]; ok {
+			continue
+		}
+
+		exists[vip] = struct{}{}
+
+		res = append(res, vip)
+	}
+
+	sort.Strings(res)
+
+	return res
+}
@@ -0,0 +1,137 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package readiness_test
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/readiness"
+)
+
+func TestWriteRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "readiness", "vips")
+
+	vips, err := readiness.Read(path)
+	if err != nil || len(vips) != 0 {
+		t.Fatalf("Read() without file = %v, %v, want no VIP", vips, err)
+	}
+
+	err = readiness.Write(path, []string{"2000::1/128", "20.0.0.1/32", "20.0.0.1/32"})
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	vips, err = readiness.Read(path)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+
+	want := []string{"20.0.0.1/32", "2000::1/128"}
+	if !reflect.DeepEqual(vips, want) {
+		t.Errorf("Read() = %v, want %v", vips, want)
+	}
+
+	err = readiness.Write(path, nil)
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	vips, err = readiness.Read(path)
+	if err != nil || len(vips) != 0 {
+		t.Errorf("Read() = %v, %v, want no VIP", vips, err)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	tests := []struct {
+		name  string
+		vips  []string
+		ready []string
+		want  []string
+	}{
+		{
+			name:  "nothing ready",
+			vips:  []string{"20.0.0.1/32", "2000::1/128"},
+			ready: []string{},
+			want:  []string{},
+		},
+		{
+			name:  "exact match",
+			vips:  []string{"20.0.0.1/32", "20.0.0.2/32", "2000::1/128"},
+			ready: []string{"20.0.0.1/32", "2000::1/128"},
+			want:  []string{"20.0.0.1/32", "2000::1/128"},
+		},
+		{
+			name:  "contained in a ready CIDR",
+			vips:  []string{"20.0.0.1/32", "20.0.1.1/32"},
+			ready: []string{"20.0.0.0/24"},
+			want:  []string{"20.0.0.1/32"},
+		},
+		{
+			name:  "larger than the ready CIDR",
+			vips:  []string{"20.0.0.0/24"},
+			ready: []string{"20.0.0.1/32"},
+			want:  []string{},
+		},
+		{
+			name:  "ipv4 not matched by ipv6 default",
+			vips:  []string{"20.0.0.1/32"},
+			ready: []string{"::/0"},
+			want:  []string{},
+		},
+		{
+			name:  "invalid",
+			vips:  []string{"20.0.0.1", "20.0.0.2/32"},
+			ready: []string{"abc", "20.0.0.0/24"},
+			want:  []string{"20.0.0.2/32"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := readiness.Filter(tt.vips, tt.ready); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Filter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vips")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	notifications := readiness.Watch(ctx, path, 10*time.Millisecond)
+
+	err := readiness.Write(path, []string{"20.0.0.1/32"})
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	select {
+	case <-notifications:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Watch() no notification after the readiness file changed")
+	}
+
+	cancel()
+
+	for range notifications { //nolint:revive
+	}
+}
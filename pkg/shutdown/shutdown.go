@@ -0,0 +1,154 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+)
+
+const directoryPermission = 0o755
+
+// drainedMessage is sent to the clients once the traffic has been drained.
+const drainedMessage = "drained\n"
+
+var errNotDrained = errors.New("the connection has been closed before the traffic was drained")
+
+// Server coordinates the shutdown of the containers of a load-balancer pod. It is run by
+// the router: the clients (e.g. the stateless-load-balancer) connect to its local socket
+// and wait until the router has drained the traffic (the VIPs have been withdrawn) before
+// removing their data plane.
+type Server struct {
+	// SocketPath is the path of the unix socket shared by the containers.
+	SocketPath string
+
+	drained     chan struct{}
+	drainedOnce sync.Once
+}
+
+// NewServer is the shutdown server constructor.
+func NewServer(socketPath string) *Server {
+	return &Server{
+		SocketPath: socketPath,
+		drained:    make(chan struct{}),
+	}
+}
+
+// Run listens on the socket until the context is cancelled. Each client is notified
+// once the traffic has been drained (see Drained).
+func (s *Server) Run(ctx context.Context) error {
+	err := os.MkdirAll(filepath.Dir(s.SocketPath), directoryPermission)
+	if err != nil {
+		return fmt.Errorf("failed to create the shutdown socket directory: %w", err)
+	}
+
+	// the socket might remain from a previous run of the container.
+	_ = os.Remove(s.SocketPath)
+
+	listener, err := (&net.ListenConfig{}).Listen(ctx, "unix", s.SocketPath)
+	if err != nil {
+		return fmt.Errorf("failed to listen on the shutdown socket: %w", err)
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		_ = listener.Close()
+	})
+	defer stop()
+
+	var wg sync.WaitGroup
+
+	defer wg.Wait()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return fmt.Errorf("failed to accept a connection on the shutdown socket: %w", err)
+		}
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			defer conn.Close()
+
+			select {
+			case <-s.drained:
+			case <-ctx.Done():
+			}
+
+			// the server is usually stopped right after the drain, the clients are
+			// notified anyway.
+			select {
+			case <-s.drained:
+				_, _ = conn.Write([]byte(drainedMessage))
+			default:
+			}
+		}()
+	}
+}
+
+// Drained notifies the clients that the traffic has been drained.
+func (s *Server) Drained() {
+	s.drainedOnce.Do(func() {
+		close(s.drained)
+	})
+}
+
+// Wait connects to the shutdown socket and waits until the traffic has been drained or
+// the context is cancelled. It returns immediately if no server is listening on the
+// socket (e.g. the router is not running), since nothing attracts the traffic anymore.
+func Wait(ctx context.Context, socketPath string) error {
+	conn, err := (&net.Dialer{}).DialContext(ctx, "unix", socketPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) || errors.Is(err, syscall.ECONNREFUSED) {
+			return nil
+		}
+
+		return fmt.Errorf("failed to connect to the shutdown socket: %w", err)
+	}
+
+	defer conn.Close()
+
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.Close()
+	})
+	defer stop()
+
+	message := make([]byte, len(drainedMessage))
+
+	_, err = io.ReadFull(conn, message)
+	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("failed to wait for the traffic to be drained: %w", context.Cause(ctx))
+		}
+
+		return errNotDrained
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+/*
+Copyright (c) 2024 OpenInfra Foundation Europe
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shutdown_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lioneljouin/l-3-4-gateway-api-poc/pkg/shutdown"
+)
+
+func TestWait(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "shutdown", "shutdown.sock")
+
+	// no server listening: nothing to wait for.
+	if err := shutdown.Wait(context.Background(), socketPath); err != nil {
+		t.Fatalf("Wait() without server error = %v", err)
+	}
+
+	server := shutdown.NewServer(socketPath)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+
+	go func() {
+		done <- server.Run(ctx)
+	}()
+
+	for range 50 {
+		if _, err := os.Stat(socketPath); err == nil {
+			break
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	waited := make(chan error)
+
+	go func() {
+		waited <- shutdown.Wait(context.Background(), socketPath)
+	}()
+
+	select {
+	case err := <-waited:
+		t.Fatalf("Wait() = %v before the traffic has been drained", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	server.Drained()
+
+	if err := <-waited; err != nil {
+		t.Errorf("Wait() error = %v", err)
+	}
+
+	// the clients connecting after the drain are notified immediately.
+	if err := shutdown.Wait(context.Background(), socketPath); err != nil {
+		t.Errorf("Wait() after the drain error = %v", err)
+	}
+
+	cancel()
+
+	if err := <-done; err != nil {
+		t.Errorf("Server.Run() error = %v", err)
+	}
+
+	// the server has stopped: nothing to wait for.
+	if err := shutdown.Wait(context.Background(), socketPath); err != nil {
+		t.Errorf("Wait() after the server stopped error = %v", err)
+	}
+}
+
+func TestWaitTimeout(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "shutdown.sock")
+
+	server := shutdown.NewServer(socketPath)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	t.Cleanup(cancel)
+
+	go func() {
+		_ = server.Run(ctx)
+	}()
+
+	for range 50 {
+		if _, err := os.Stat(socketPath); err == nil {
+			break
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer waitCancel()
+
+	if err := shutdown.Wait(waitCtx, socketPath); err == nil {
+		t.Errorf("Wait() error = nil, want a timeout")
+	}
+}
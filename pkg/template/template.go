@@ -22,13 +22,17 @@ import (
 
 	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/apimachinery/pkg/util/yaml"
+	appsv1ac "k8s.io/client-go/applyconfigurations/apps/v1"
 )
 
 const (
 	decoderBufferSize = 4096
 )
 
-func Read[T *appsv1.Deployment | *appsv1.DaemonSet](file string, res T) error { //nolint:ireturn
+func Read[T *appsv1.Deployment | *appsv1.DaemonSet | *appsv1ac.DeploymentApplyConfiguration](
+	file string,
+	res T,
+) error { //nolint:ireturn
 	data, err := os.Open(file)
 	if err != nil {
 		return fmt.Errorf("fail to open file: %w", err)